@@ -3,20 +3,29 @@ package main
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"io/fs"
 	"kalycs/db"
 	"kalycs/internal/classifier"
+	"kalycs/internal/database"
+	"kalycs/internal/dedupe"
 	"kalycs/internal/logging"
 	"kalycs/internal/store"
 	"kalycs/internal/utils"
 	"kalycs/internal/watcher"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
 type App struct {
 	ctx        context.Context
-	watcher    watcher.Watcher
+	watchers   *watcher.Manager
+	hasher     *dedupe.Hasher
 	db         *sql.DB
 	store      *store.Store
 	classifier *classifier.Classifier
@@ -49,17 +58,83 @@ func (a *App) startup(ctx context.Context) {
 		logging.L().Fatalw("Failed to load rules", "error", err)
 	}
 
-	downloadsDir, err := utils.GetDownloadsDirectory()
+	if err := a.store.Stats.PruneDaily(a.ctx, time.Now()); err != nil {
+		logging.L().Warnw("Failed to prune rule statistics", "error", err)
+	}
+
+	a.hasher = dedupe.NewHasher(ctx, a.store, dedupe.DefaultWorkers)
+	a.classifier.SetHashQueue(a.hasher)
+	a.hasher.Start()
+
+	if err := a.ensureDefaultWatchRoot(a.ctx); err != nil {
+		logging.L().Warnw("Failed to add default watch root", "error", err)
+	}
+
+	a.watchers = watcher.NewManager(ctx, a.classifier)
+	if err := a.syncWatchers(a.ctx); err != nil {
+		logging.L().Errorw("Failed to start some watchers", "error", err)
+	}
+
+	// Catch up on files that changed while Kalycs was closed without blocking the UI
+	go a.reconcile(a.ctx)
+}
+
+// ReconcileProgressEvent is emitted to the frontend with a watcher.ReconcileProgress payload
+const ReconcileProgressEvent = "reconcile:progress"
+
+// reconcile scans the watch roots for changes missed while Kalycs was not
+// running. Files classified by the scan share a batch so they can be undone together.
+func (a *App) reconcile(ctx context.Context) {
+	roots, err := a.store.Watch.GetAll(ctx)
+	if err != nil {
+		logging.L().Errorw("Failed to load watch roots for reconciliation", "error", err)
+		return
+	}
+
+	ctx = classifier.WithBatchID(ctx, database.GenerateID())
+	err = watcher.Reconcile(ctx, a.classifier, roots, func(p watcher.ReconcileProgress) {
+		runtime.EventsEmit(a.ctx, ReconcileProgressEvent, p)
+	})
+	if err != nil {
+		logging.L().Errorw("Reconciliation finished with errors", "error", err)
+	}
+}
+
+// ensureDefaultWatchRoot watches the downloads directory when no roots are configured yet
+func (a *App) ensureDefaultWatchRoot(ctx context.Context) error {
+	roots, err := a.store.Watch.GetAll(ctx)
 	if err != nil {
-		logging.L().Fatalw("Failed to get downloads directory", "error", err)
+		return err
+	}
+	if len(roots) > 0 {
+		return nil
 	}
 
-	w, err := watcher.NewWatcher(ctx, downloadsDir, a.classifier)
+	downloadsDir, err := a.resolveDownloadsDirectory(ctx)
 	if err != nil {
-		logging.L().Fatalw("Failed to create watcher", "error", err)
+		return err
 	}
-	a.watcher = *w
-	a.watcher.Start()
+
+	root := &db.WatchRoot{Path: downloadsDir, Enabled: true}
+	return a.store.Watch.Create(ctx, root)
+}
+
+// resolveDownloadsDirectory applies the user's stored override, if any, to downloads-directory discovery
+func (a *App) resolveDownloadsDirectory(ctx context.Context) (string, error) {
+	override, _, err := a.store.Setting.Get(ctx, store.SettingDownloadsDirectory)
+	if err != nil {
+		return "", err
+	}
+	return utils.ResolveDownloadsDirectory(override)
+}
+
+// syncWatchers starts and stops watchers to match the stored watch roots
+func (a *App) syncWatchers(ctx context.Context) error {
+	roots, err := a.store.Watch.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	return a.watchers.Sync(roots)
 }
 
 // domReady is called after the front-end has been loaded
@@ -70,12 +145,18 @@ func (a *App) startup(ctx context.Context) {
 func (a *App) shutdown(ctx context.Context) {
 	a.ctx = ctx
 	logging.L().Info("Application shutdown")
-	a.watcher.Stop()
+	a.watchers.StopAll()
+	a.hasher.Stop()
 }
 
 // ImportFolder walks a directory, classifying each file.
-func (a *App) ImportFolder(ctx context.Context, dir string) error {
-	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+// It returns the batch ID under which file actions were journaled, so the
+// whole import can be undone with UndoBatch.
+func (a *App) ImportFolder(ctx context.Context, dir string) (string, error) {
+	batchID := database.GenerateID()
+	ctx = classifier.WithRoot(classifier.WithBatchID(ctx, batchID), dir)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			logging.L().Errorw("error accessing path during import", "path", path, "error", err)
 			return err
@@ -97,6 +178,7 @@ func (a *App) ImportFolder(ctx context.Context, dir string) error {
 
 		return nil
 	})
+	return batchID, err
 }
 
 // ---------------- Project Methods ----------------
@@ -117,6 +199,15 @@ func (a *App) DeleteProject(ctx context.Context, id string) error {
 	return a.store.Project.Delete(ctx, id)
 }
 
+// MoveProject moves a project directly before or after targetID in the order
+// projects are evaluated, e.g. when it is dropped onto another project.
+func (a *App) MoveProject(ctx context.Context, id, targetID string, after bool) error {
+	if err := a.store.Project.Move(ctx, id, targetID, after); err != nil {
+		return err
+	}
+	return a.rulesChanged(ctx)
+}
+
 // ---------------- Rule Methods ----------------
 
 func (a *App) ListRules(ctx context.Context, projectID string) ([]db.Rule, error) {
@@ -128,7 +219,7 @@ func (a *App) CreateRule(ctx context.Context, r db.Rule) error {
 	if err != nil {
 		return err
 	}
-	return a.classifier.Reload(ctx)
+	return a.rulesChanged(ctx)
 }
 
 func (a *App) UpdateRule(ctx context.Context, r db.Rule) error {
@@ -136,7 +227,7 @@ func (a *App) UpdateRule(ctx context.Context, r db.Rule) error {
 	if err != nil {
 		return err
 	}
-	return a.classifier.Reload(ctx)
+	return a.rulesChanged(ctx)
 }
 
 func (a *App) DeleteRule(ctx context.Context, id string) error {
@@ -144,5 +235,231 @@ func (a *App) DeleteRule(ctx context.Context, id string) error {
 	if err != nil {
 		return err
 	}
-	return a.classifier.Reload(ctx)
+	return a.rulesChanged(ctx)
+}
+
+// PreviewRule reports which indexed files an unsaved rule would capture and
+// which of them it would take from other projects, using current priorities.
+func (a *App) PreviewRule(ctx context.Context, r db.Rule) (classifier.PreviewResult, error) {
+	return a.classifier.Preview(ctx, r)
+}
+
+// PreviewRuleInDirectory is PreviewRule for the files directly inside dir,
+// including files that have not been indexed yet.
+func (a *App) PreviewRuleInDirectory(ctx context.Context, r db.Rule, dir string) (classifier.PreviewResult, error) {
+	return a.classifier.PreviewDir(ctx, r, dir)
+}
+
+// MoveRule moves a rule directly before or after another rule of the same
+// project, e.g. when it is dropped onto it.
+func (a *App) MoveRule(ctx context.Context, id, targetID string, after bool) error {
+	if err := a.store.Rule.Move(ctx, id, targetID, after); err != nil {
+		return err
+	}
+	return a.rulesChanged(ctx)
+}
+
+// ReclassifiedEvent is emitted to the frontend with the classifier.ReclassifyResult
+// of a reclassification started automatically after rules changed
+const ReclassifiedEvent = "reclassify:done"
+
+// rulesChanged reloads the rules and, unless turned off, reclassifies the
+// indexed files in the background so they follow the new rules
+func (a *App) rulesChanged(ctx context.Context) error {
+	if err := a.classifier.Reload(ctx); err != nil {
+		return err
+	}
+	enabled, err := a.GetAutoReclassify(ctx)
+	if err != nil || !enabled {
+		return err
+	}
+
+	go func() {
+		result, err := a.classifier.Reclassify(a.ctx, classifier.ReclassifyScope{Kind: classifier.ScopeAll}, false)
+		if err != nil {
+			logging.L().Errorw("Automatic reclassification failed", "error", err)
+			return
+		}
+		runtime.EventsEmit(a.ctx, ReclassifiedEvent, result)
+	}()
+	return nil
+}
+
+// ReclassifyFiles runs the current rules against indexed files in scope and
+// reports which files changed project; with dryRun nothing is changed
+func (a *App) ReclassifyFiles(ctx context.Context, scope classifier.ReclassifyScope, dryRun bool) (classifier.ReclassifyResult, error) {
+	return a.classifier.Reclassify(ctx, scope, dryRun)
+}
+
+// ---------------- Watch Root Methods ----------------
+
+func (a *App) ListWatchRoots(ctx context.Context) ([]db.WatchRoot, error) {
+	return a.store.Watch.GetAll(ctx)
+}
+
+func (a *App) AddWatchRoot(ctx context.Context, root db.WatchRoot) error {
+	if err := a.store.Watch.Create(ctx, &root); err != nil {
+		return err
+	}
+	return a.syncWatchers(ctx)
+}
+
+func (a *App) UpdateWatchRoot(ctx context.Context, root db.WatchRoot) error {
+	if err := a.store.Watch.Update(ctx, &root); err != nil {
+		return err
+	}
+	return a.syncWatchers(ctx)
+}
+
+func (a *App) RemoveWatchRoot(ctx context.Context, id string) error {
+	if err := a.store.Watch.Delete(ctx, id); err != nil {
+		return err
+	}
+	return a.syncWatchers(ctx)
+}
+
+// ---------------- Settings Methods ----------------
+
+// GetDownloadsDirectory returns the downloads directory in effect, including any override
+func (a *App) GetDownloadsDirectory(ctx context.Context) (string, error) {
+	return a.resolveDownloadsDirectory(ctx)
+}
+
+// SetDownloadsDirectory stores an override for the downloads directory; an empty
+// path restores automatic discovery. A watch root on the previous downloads
+// directory is moved to the new one.
+func (a *App) SetDownloadsDirectory(ctx context.Context, path string) error {
+	previous, err := a.resolveDownloadsDirectory(ctx)
+	if err != nil {
+		logging.L().Warnw("Failed to resolve previous downloads directory", "error", err)
+	}
+
+	path = strings.TrimSpace(path)
+	if path == "" {
+		err = a.store.Setting.Delete(ctx, store.SettingDownloadsDirectory)
+	} else {
+		info, statErr := os.Stat(path)
+		if statErr != nil {
+			return fmt.Errorf("downloads directory is not accessible: %w", statErr)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("downloads directory '%s' is not a directory", path)
+		}
+		if _, resolveErr := utils.ResolveDownloadsDirectory(path); resolveErr != nil {
+			return resolveErr
+		}
+		err = a.store.Setting.Set(ctx, store.SettingDownloadsDirectory, path)
+	}
+	if err != nil {
+		return err
+	}
+
+	current, err := a.resolveDownloadsDirectory(ctx)
+	if err != nil || previous == "" || current == previous {
+		return err
+	}
+
+	roots, err := a.store.Watch.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range roots {
+		if r.Path == previous {
+			r.Path = current
+			if err := a.store.Watch.Update(ctx, &r); err != nil {
+				return err
+			}
+			return a.syncWatchers(ctx)
+		}
+	}
+	return nil
+}
+
+// GetAutoReclassify reports whether indexed files are reclassified after rules change
+func (a *App) GetAutoReclassify(ctx context.Context) (bool, error) {
+	value, _, err := a.store.Setting.Get(ctx, store.SettingAutoReclassify)
+	if err != nil {
+		return false, err
+	}
+	return value != "false", nil
+}
+
+// SetAutoReclassify turns automatic reclassification after rule changes on or off
+func (a *App) SetAutoReclassify(ctx context.Context, enabled bool) error {
+	return a.store.Setting.Set(ctx, store.SettingAutoReclassify, fmt.Sprint(enabled))
+}
+
+// ---------------- Undo Methods ----------------
+
+func (a *App) ListFileActions(ctx context.Context, limit int) ([]db.FileAction, error) {
+	return a.store.Action.ListRecent(ctx, limit)
+}
+
+func (a *App) UndoAction(ctx context.Context, actionID string) (classifier.UndoResult, error) {
+	return a.classifier.UndoAction(ctx, actionID)
+}
+
+func (a *App) UndoBatch(ctx context.Context, batchID string) (classifier.UndoResult, error) {
+	return a.classifier.UndoBatch(ctx, batchID)
+}
+
+func (a *App) UndoSince(ctx context.Context, since time.Time) (classifier.UndoResult, error) {
+	return a.classifier.UndoSince(ctx, since)
+}
+
+// ---------------- File Methods ----------------
+
+// ListProjectFiles returns the files assigned to a project. Each file tells
+// how it got there: the rule that matched it, the incoming default, or the user.
+func (a *App) ListProjectFiles(ctx context.Context, projectID string) ([]db.File, error) {
+	return a.store.File.ByProject(ctx, projectID)
+}
+
+// ExplainFile answers "why is this file in this project?"
+func (a *App) ExplainFile(ctx context.Context, fileID string) (classifier.Explanation, error) {
+	return a.classifier.Explain(ctx, fileID)
+}
+
+// AssignFile moves a file to a project by hand and pins it there
+func (a *App) AssignFile(ctx context.Context, fileID, projectID string) error {
+	return a.classifier.Assign(ctx, fileID, projectID)
+}
+
+// UnpinFile lets the rules decide a manually assigned file's project again
+func (a *App) UnpinFile(ctx context.Context, fileID string) (*db.File, error) {
+	return a.classifier.Unpin(ctx, fileID)
+}
+
+// ---------------- Rule Report Methods ----------------
+
+// GetRuleReport returns how often each rule matched and flags rules that never
+// matched, can never match because earlier rules cover them, or overlap with
+// rules of another project
+func (a *App) GetRuleReport(ctx context.Context) (classifier.RuleReport, error) {
+	return a.classifier.Report(ctx)
+}
+
+// ---------------- Missing File Methods ----------------
+
+// ListMissingFiles returns tracked files that have disappeared from disk
+func (a *App) ListMissingFiles(ctx context.Context) ([]db.File, error) {
+	return a.store.File.ListMissing(ctx)
+}
+
+// ForgetFile removes a file's row, e.g. once the user has dismissed it from the missing files view
+func (a *App) ForgetFile(ctx context.Context, fileID string) error {
+	return a.store.File.Delete(ctx, fileID)
+}
+
+// ---------------- Duplicate Methods ----------------
+
+// ListDuplicateGroups returns groups of files with identical content across all projects
+func (a *App) ListDuplicateGroups(ctx context.Context) ([]dedupe.Group, error) {
+	return dedupe.FindGroups(ctx, a.store)
+}
+
+// ResolveDuplicates keeps one file of a duplicate group and trashes or
+// hardlinks the rest; mode is "trash" or "hardlink"
+func (a *App) ResolveDuplicates(ctx context.Context, hash, keepFileID, mode string) (dedupe.ResolveResult, error) {
+	return dedupe.Resolve(ctx, a.store, hash, keepFileID, mode)
 }
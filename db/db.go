@@ -1,12 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"kalycs/internal/logging"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -17,38 +19,99 @@ var db *sql.DB
 
 // Project represents the project schema
 type Project struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	IsActive    bool      `json:"is_active"`
-	IsFavourite bool      `json:"is_favourite"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	IsActive       bool      `json:"is_active"`
+	IsFavourite    bool      `json:"is_favourite"`
+	DestinationDir string    `json:"destination_dir"` // where classified files are placed
+	Action         string    `json:"action"`          // none, move, copy, hardlink, symlink
+	Priority       int       `json:"priority"`        // lower values are evaluated first
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // Rule represents the rules schema
 type Rule struct {
-	ID            string    `json:"id"`
-	Name          string    `json:"name"`
-	ProjectID     string    `json:"project_id"`
-	Rule          string    `json:"rule"`  // starts_with, contains, ends_with, extension, regex
-	Texts         string    `json:"texts"` // JSON array as string
-	CaseSensitive bool      `json:"case_sensitive"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	ProjectID      string    `json:"project_id"`
+	Rule           string    `json:"rule"`       // starts_with, contains, ends_with, extension, regex, glob, path, parent, mime, content, content_regex, metadata, source_domain, size, age, modified, composite
+	Texts          string    `json:"texts"`      // JSON array as string
+	Conditions     string    `json:"conditions"` // JSON condition tree; see package condition
+	CaseSensitive  bool      `json:"case_sensitive"`
+	Priority       int       `json:"priority"`        // order within the project; lower values are evaluated first
+	RenameTemplate string    `json:"rename_template"` // new name for matched files, e.g. "{date}_{name}{ext}"; see package rename
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // File represents the file schema
 type File struct {
+	ID               string         `json:"id"`
+	Path             string         `json:"path"`
+	Name             string         `json:"name"`
+	Ext              string         `json:"ext"`
+	Size             int64          `json:"size"`
+	Mtime            time.Time      `json:"mtime"`
+	ProjectID        sql.NullString `json:"project_id"`
+	AssignmentSource string         `json:"assignment_source"` // rule, incoming or manual; see AssignedByRule
+	RuleID           sql.NullString `json:"rule_id"`           // rule that chose the project, for rule assignments
+	MatchKind        string         `json:"match_kind"`        // kind of the rule that matched, e.g. extension
+	MatchText        string         `json:"match_text"`        // rule text that matched the file
+	Mime             string         `json:"mime"`              // content type sniffed from the leading bytes; empty until detected
+	OriginURL        string         `json:"origin_url"`        // URL the file was downloaded from, as recorded by the browser
+	ReferrerURL      string         `json:"referrer_url"`      // page that linked to the download
+	Hash             sql.NullString `json:"hash"`              // hex SHA-256 of the content, computed in the background
+	DeletedAt        sql.NullTime   `json:"deleted_at"`        // set when the file disappeared from disk
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
+
+// How a file's project was chosen
+const (
+	AssignedByRule   = "rule"     // a rule matched the file
+	AssignedIncoming = "incoming" // no rule matched, so the file went to the default project
+	AssignedManually = "manual"   // the user chose the project; reclassification leaves it alone
+)
+
+// FileAction represents a journal entry for a file action performed during classification
+type FileAction struct {
 	ID        string         `json:"id"`
-	Path      string         `json:"path"`
-	Name      string         `json:"name"`
-	Ext       string         `json:"ext"`
-	Size      int64          `json:"size"`
-	Mtime     time.Time      `json:"mtime"`
-	ProjectID sql.NullString `json:"project_id"`
+	FileID    string         `json:"file_id"`
+	Action    string         `json:"action"` // move, copy, hardlink, symlink, rename
+	OldPath   string         `json:"old_path"`
+	NewPath   string         `json:"new_path"`
+	Size      int64          `json:"size"`  // size of the result when the action was performed
+	Mtime     sql.NullTime   `json:"mtime"` // modification time of the result then; undo keeps a copy changed since
+	RuleID    sql.NullString `json:"rule_id"`
+	BatchID   sql.NullString `json:"batch_id"`
 	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
+	UndoneAt  sql.NullTime   `json:"undone_at"`
+}
+
+// RuleStats counts how often a rule captured a file
+type RuleStats struct {
+	RuleID        string       `json:"rule_id"`
+	TotalMatches  int64        `json:"total_matches"`
+	LastMatchedAt sql.NullTime `json:"last_matched_at"`
+	RecentMatches int64        `json:"recent_matches"` // matches in the last RecentMatchDays days
+}
+
+// RecentMatchDays is the window of RuleStats.RecentMatches
+const RecentMatchDays = 30
+
+// WatchRoot represents a directory watched for new files
+type WatchRoot struct {
+	ID             string    `json:"id"`
+	Path           string    `json:"path"`
+	Enabled        bool      `json:"enabled"`
+	Recursive      bool      `json:"recursive"`
+	MaxDepth       int       `json:"max_depth"`       // 0 means unlimited when recursive
+	QuietPeriodMs  int       `json:"quiet_period_ms"` // how long files must stay unchanged before classifying; 0 uses the default
+	IgnorePatterns string    `json:"ignore_patterns"` // JSON array of glob patterns as string
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // getAppDataDirectory returns the appropriate application data directory for the current OS
@@ -131,6 +194,35 @@ func InitializeDatabase() error {
 	return nil
 }
 
+// The rules table, its index and trigger are declared at package level so
+// rebuildTableIfOutdated can recreate them.
+const (
+	ruleTable = `
+	CREATE TABLE IF NOT EXISTS rules (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL CHECK(length(name) <= 25),
+		project_id TEXT NOT NULL,
+		rule TEXT NOT NULL ` + ruleKindCheck + `,
+		texts TEXT NOT NULL,
+		conditions TEXT NOT NULL DEFAULT '',
+		case_sensitive BOOLEAN NOT NULL DEFAULT 0,
+		priority INTEGER NOT NULL DEFAULT 0,
+		rename_template TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+	);`
+
+	ruleProjectIndex = `CREATE INDEX IF NOT EXISTS idx_rules_project_id ON rules(project_id);`
+
+	ruleTrigger = `
+	CREATE TRIGGER IF NOT EXISTS update_rules_updated_at 
+	AFTER UPDATE ON rules
+	BEGIN
+		UPDATE rules SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+	END;`
+)
+
 // createTables creates the required database tables
 func createTables() error {
 	projectTable := `
@@ -140,23 +232,13 @@ func createTables() error {
 		description TEXT CHECK(length(description) <= 200),
 		is_active BOOLEAN NOT NULL DEFAULT 1,
 		is_favourite BOOLEAN NOT NULL DEFAULT 0,
+		destination_dir TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL DEFAULT 'none' CHECK(action IN ('none', 'move', 'copy', 'hardlink', 'symlink')),
+		priority INTEGER NOT NULL DEFAULT 0,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 	);`
 
-	ruleTable := `
-	CREATE TABLE IF NOT EXISTS rules (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL CHECK(length(name) <= 25),
-		project_id TEXT NOT NULL,
-		rule TEXT NOT NULL CHECK(rule IN ('starts_with', 'contains', 'ends_with', 'extension', 'regex')),
-		texts TEXT NOT NULL,
-		case_sensitive BOOLEAN NOT NULL DEFAULT 0,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
-	);`
-
 	fileTable := `
 	CREATE TABLE IF NOT EXISTS files (
 		id          TEXT PRIMARY KEY,
@@ -166,14 +248,85 @@ func createTables() error {
 		size        INTEGER,
 		mtime       DATETIME,
 		project_id  TEXT,
+		assignment_source TEXT NOT NULL DEFAULT 'rule' CHECK(assignment_source IN ('rule', 'incoming', 'manual')),
+		rule_id     TEXT REFERENCES rules(id) ON DELETE SET NULL,
+		match_kind  TEXT NOT NULL DEFAULT '',
+		match_text  TEXT NOT NULL DEFAULT '',
+		mime        TEXT NOT NULL DEFAULT '',
+		origin_url  TEXT NOT NULL DEFAULT '',
+		referrer_url TEXT NOT NULL DEFAULT '',
+		hash        TEXT,
+		deleted_at  DATETIME,
 		created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE SET NULL
 	);`
 
+	fileActionTable := `
+	CREATE TABLE IF NOT EXISTS file_actions (
+		id          TEXT PRIMARY KEY,
+		file_id     TEXT NOT NULL,
+		action      TEXT NOT NULL,
+		old_path    TEXT NOT NULL,
+		new_path    TEXT NOT NULL,
+		size        INTEGER NOT NULL DEFAULT 0,
+		mtime       DATETIME,
+		rule_id     TEXT,
+		batch_id    TEXT,
+		created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		undone_at   DATETIME
+	);`
+
+	watchRootTable := `
+	CREATE TABLE IF NOT EXISTS watch_roots (
+		id              TEXT PRIMARY KEY,
+		path            TEXT NOT NULL UNIQUE,
+		enabled         BOOLEAN NOT NULL DEFAULT 1,
+		recursive       BOOLEAN NOT NULL DEFAULT 0,
+		max_depth       INTEGER NOT NULL DEFAULT 0 CHECK(max_depth >= 0),
+		quiet_period_ms INTEGER NOT NULL DEFAULT 0 CHECK(quiet_period_ms >= 0),
+		ignore_patterns TEXT NOT NULL DEFAULT '[]',
+		created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	ruleStatsTable := `
+	CREATE TABLE IF NOT EXISTS rule_stats (
+		rule_id         TEXT PRIMARY KEY REFERENCES rules(id) ON DELETE CASCADE,
+		total_matches   INTEGER NOT NULL DEFAULT 0,
+		last_matched_at DATETIME
+	);`
+
+	// One row per rule and local day with matches, for the recent match count
+	ruleDailyHitsTable := `
+	CREATE TABLE IF NOT EXISTS rule_daily_hits (
+		rule_id TEXT NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+		day     TEXT NOT NULL,
+		hits    INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (rule_id, day)
+	);`
+
+	// Fields read from a file's embedded metadata, such as EXIF or PDF document info
+	fileMetadataTable := `
+	CREATE TABLE IF NOT EXISTS file_metadata (
+		file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+		key     TEXT NOT NULL,
+		value   TEXT NOT NULL,
+		PRIMARY KEY (file_id, key)
+	);`
+
+	settingTable := `
+	CREATE TABLE IF NOT EXISTS settings (
+		key         TEXT PRIMARY KEY,
+		value       TEXT NOT NULL,
+		updated_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
 	// Create indexes
 	projectNameIndex := `CREATE INDEX IF NOT EXISTS idx_projects_name ON projects(name);`
-	ruleProjectIndex := `CREATE INDEX IF NOT EXISTS idx_rules_project_id ON rules(project_id);`
+	fileActionBatchIndex := `CREATE INDEX IF NOT EXISTS idx_file_actions_batch_id ON file_actions(batch_id);`
+	fileActionCreatedIndex := `CREATE INDEX IF NOT EXISTS idx_file_actions_created_at ON file_actions(created_at);`
+	fileActionFileIndex := `CREATE INDEX IF NOT EXISTS idx_file_actions_file_id ON file_actions(file_id);`
 
 	// Create trigger for updated_at
 	projectTrigger := `
@@ -183,13 +336,6 @@ func createTables() error {
 		UPDATE projects SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
 	END;`
 
-	ruleTrigger := `
-	CREATE TRIGGER IF NOT EXISTS update_rules_updated_at 
-	AFTER UPDATE ON rules
-	BEGIN
-		UPDATE rules SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
-	END;`
-
 	fileTrigger := `
 	CREATE TRIGGER IF NOT EXISTS trg_files_updated_at
 	AFTER UPDATE ON files
@@ -197,8 +343,17 @@ func createTables() error {
 		UPDATE files SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
 	END;`
 
+	watchRootTrigger := `
+	CREATE TRIGGER IF NOT EXISTS trg_watch_roots_updated_at
+	AFTER UPDATE ON watch_roots
+	BEGIN
+		UPDATE watch_roots SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+	END;`
+
 	statements := []string{
-		projectTable, ruleTable, fileTable, projectNameIndex, ruleProjectIndex, projectTrigger, ruleTrigger, fileTrigger,
+		projectTable, ruleTable, fileTable, fileActionTable, watchRootTable, ruleStatsTable, ruleDailyHitsTable, fileMetadataTable, settingTable,
+		projectNameIndex, ruleProjectIndex, fileActionBatchIndex, fileActionCreatedIndex, fileActionFileIndex,
+		projectTrigger, ruleTrigger, fileTrigger, watchRootTrigger,
 	}
 
 	for _, stmt := range statements {
@@ -207,9 +362,276 @@ func createTables() error {
 		}
 	}
 
+	if err := migrateTables(); err != nil {
+		return fmt.Errorf("failed to migrate tables: %w", err)
+	}
+
+	return nil
+}
+
+// ruleKindCheck constrains rules.rule to the supported rule kinds; keep in sync
+// with validation.ValidRuleTypes. Older databases whose rules table was created
+// with a different list are rebuilt by migrateTables.
+const ruleKindCheck = `CHECK(rule IN ('starts_with', 'contains', 'ends_with', 'extension', 'regex', 'glob', 'path', 'parent', 'mime', 'content', 'content_regex', 'metadata', 'source_domain', 'size', 'age', 'modified', 'composite'))`
+
+// migrateTables brings tables created by older versions up to date.
+// CREATE TABLE IF NOT EXISTS leaves existing tables untouched, so columns
+// added after the initial schema are added here.
+func migrateTables() error {
+	if err := rebuildTableIfOutdated("rules", ruleTable, ruleKindCheck, ruleProjectIndex, ruleTrigger); err != nil {
+		return err
+	}
+
+	// backfill, when set, runs once right after the column is added
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+		backfill   string
+	}{
+		{"projects", "destination_dir", "TEXT NOT NULL DEFAULT ''", ""},
+		{"projects", "action", "TEXT NOT NULL DEFAULT 'none' CHECK(action IN ('none', 'move', 'copy', 'hardlink', 'symlink'))", ""},
+		{"watch_roots", "max_depth", "INTEGER NOT NULL DEFAULT 0 CHECK(max_depth >= 0)", ""},
+		{"files", "deleted_at", "DATETIME", ""},
+		{"files", "hash", "TEXT", ""},
+		{"rules", "conditions", "TEXT NOT NULL DEFAULT ''", ""},
+		{"projects", "priority", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"rules", "priority", "INTEGER NOT NULL DEFAULT 0", ""},
+		// Files in the default project were put there because no rule matched
+		{"files", "assignment_source", "TEXT NOT NULL DEFAULT 'rule' CHECK(assignment_source IN ('rule', 'incoming', 'manual'))",
+			`UPDATE files SET assignment_source = 'incoming' WHERE project_id IN (SELECT id FROM projects WHERE name = 'Incoming')`},
+		{"files", "rule_id", "TEXT REFERENCES rules(id) ON DELETE SET NULL", ""},
+		{"files", "match_kind", "TEXT NOT NULL DEFAULT ''", ""},
+		{"files", "match_text", "TEXT NOT NULL DEFAULT ''", ""},
+		{"files", "mime", "TEXT NOT NULL DEFAULT ''", ""},
+		{"files", "origin_url", "TEXT NOT NULL DEFAULT ''", ""},
+		{"files", "referrer_url", "TEXT NOT NULL DEFAULT ''", ""},
+		{"rules", "rename_template", "TEXT NOT NULL DEFAULT ''", ""},
+		{"file_actions", "size", "INTEGER NOT NULL DEFAULT 0", ""},
+		{"file_actions", "mtime", "DATETIME", ""},
+		{"watch_roots", "quiet_period_ms", "INTEGER NOT NULL DEFAULT 0 CHECK(quiet_period_ms >= 0)", ""},
+	}
+
+	for _, c := range columns {
+		added, err := addColumnIfMissing(c.table, c.column, c.definition)
+		if err != nil {
+			return err
+		}
+		if added && c.backfill != "" {
+			if _, err := db.Exec(c.backfill); err != nil {
+				return fmt.Errorf("failed to backfill %s.%s: %w", c.table, c.column, err)
+			}
+		}
+	}
+
+	// Rules created before condition trees get the equivalent single match
+	_, err := db.Exec(`
+	UPDATE rules SET conditions = json_object(
+		'op', 'match',
+		'kind', rule,
+		'texts', json(texts),
+		'case_sensitive', json(CASE WHEN case_sensitive THEN 'true' ELSE 'false' END)
+	)
+	WHERE conditions = '' AND rule != 'composite' AND json_valid(texts)`)
+	if err != nil {
+		return fmt.Errorf("failed to backfill rule conditions: %w", err)
+	}
+
+	// Projects and rules from before explicit ordering all have priority 0.
+	// New rows never share a priority, so a group of several zeros is numbered
+	// once: rules in creation order, projects newest first as they were listed.
+	priorities := []string{`
+	UPDATE rules SET priority = (
+		SELECT COUNT(*) FROM rules r2
+		WHERE r2.project_id = rules.project_id
+		  AND (r2.created_at < rules.created_at OR (r2.created_at = rules.created_at AND r2.id < rules.id))
+	)
+	WHERE project_id IN (
+		SELECT project_id FROM rules GROUP BY project_id
+		HAVING COUNT(*) > 1 AND MIN(priority) = 0 AND MAX(priority) = 0
+	)`, `
+	UPDATE projects SET priority = (
+		SELECT COUNT(*) FROM projects p2
+		WHERE p2.created_at > projects.created_at OR (p2.created_at = projects.created_at AND p2.id < projects.id)
+	)
+	WHERE (SELECT COUNT(*) > 1 AND MIN(priority) = 0 AND MAX(priority) = 0 FROM projects)`,
+	}
+	for _, stmt := range priorities {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to backfill priorities: %w", err)
+		}
+	}
+
+	// Indexes on migrated columns can only be created once the columns exist
+	indexes := []string{
+		`CREATE INDEX IF NOT EXISTS idx_files_deleted_at ON files(deleted_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_files_hash ON files(hash);`,
+		`CREATE INDEX IF NOT EXISTS idx_files_rule_id ON files(rule_id);`,
+	}
+	for _, stmt := range indexes {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rebuildTableIfOutdated recreates table from definition when the stored
+// schema lacks marker, e.g. a CHECK constraint that ALTER TABLE cannot change.
+// Columns present in both versions are copied, and extras (the table's indexes
+// and triggers, which are dropped with it) are created again.
+func rebuildTableIfOutdated(table, definition, marker string, extras ...string) error {
+	var stored string
+	err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&stored)
+	if err != nil {
+		return fmt.Errorf("failed to read schema of %s: %w", table, err)
+	}
+	if strings.Contains(stored, marker) {
+		return nil
+	}
+	logging.L().Infow("Rebuilding table with updated schema", "table", table)
+
+	oldColumns, err := tableColumns(table)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Foreign keys must be off while the table is swapped, and the pragma has
+	// no effect inside a transaction, so it is set on this connection first.
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tmp := table + "_new"
+	create := strings.Replace(definition, "CREATE TABLE IF NOT EXISTS "+table, "CREATE TABLE "+tmp, 1)
+	if _, err := tx.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+
+	newColumns, err := txTableColumns(ctx, tx, tmp)
+	if err != nil {
+		return err
+	}
+	var common []string
+	for _, c := range oldColumns {
+		if _, ok := newColumns[c]; ok {
+			common = append(common, c)
+		}
+	}
+	cols := strings.Join(common, ", ")
+
+	stmts := []string{
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", tmp, cols, cols, table),
+		fmt.Sprintf("DROP TABLE %s", table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmp, table),
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to rebuild %s: %w", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, stmt := range extras {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to recreate index or trigger on %s: %w", table, err)
+		}
+	}
 	return nil
 }
 
+// tableColumns returns the column names of table in order
+func tableColumns(table string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+func txTableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]struct{}, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]struct{})
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = struct{}{}
+	}
+	return columns, rows.Err()
+}
+
+// addColumnIfMissing adds a column to a table unless it already exists and
+// reports whether it was added
+func addColumnIfMissing(table, column, definition string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   bool
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan column of %s: %w", table, err)
+		}
+		if name == column {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	rows.Close()
+
+	stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := db.Exec(stmt); err != nil {
+		return false, fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	logging.L().Infow("Database column added", "table", table, "column", column)
+	return true, nil
+}
+
 // CloseDatabase closes the database connection
 func CloseDatabase() error {
 	if db != nil {
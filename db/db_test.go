@@ -1,6 +1,7 @@
 package db
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -189,3 +190,239 @@ func TestDatabaseState(t *testing.T) {
 		t.Fatalf("GetDB() should not be nil after initialization")
 	}
 }
+
+// TestInitializeDatabaseMigratesOldSchema verifies that columns added after the
+// initial schema are added to databases created by older versions.
+func TestInitializeDatabaseMigratesOldSchema(t *testing.T) {
+	prepareTestEnv(t)
+
+	appDir, err := getAppDataDirectory()
+	if err != nil {
+		t.Fatalf("getAppDataDirectory() error = %v", err)
+	}
+
+	old, err := sql.Open("sqlite3", filepath.Join(appDir, "kalycs.db"))
+	if err != nil {
+		t.Fatalf("failed to open old database: %v", err)
+	}
+	_, err = old.Exec(`CREATE TABLE projects (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT,
+		is_active BOOLEAN NOT NULL DEFAULT 1,
+		is_favourite BOOLEAN NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		t.Fatalf("failed to create old projects table: %v", err)
+	}
+	if _, err := old.Exec(`INSERT INTO projects (id, name) VALUES ('p1', 'Old')`); err != nil {
+		t.Fatalf("failed to insert old project: %v", err)
+	}
+	old.Close()
+
+	if err := InitializeDatabase(); err != nil {
+		t.Fatalf("InitializeDatabase() error = %v", err)
+	}
+	defer CloseDatabase()
+
+	var action, destination string
+	err = GetDB().QueryRow(`SELECT action, destination_dir FROM projects WHERE id = 'p1'`).Scan(&action, &destination)
+	if err != nil {
+		t.Fatalf("migrated columns missing: %v", err)
+	}
+	if action != "none" || destination != "" {
+		t.Errorf("migrated defaults = (%q, %q), want (\"none\", \"\")", action, destination)
+	}
+}
+
+func TestInitializeDatabaseRebuildsOutdatedRules(t *testing.T) {
+	prepareTestEnv(t)
+
+	appDir, err := getAppDataDirectory()
+	if err != nil {
+		t.Fatalf("getAppDataDirectory() error = %v", err)
+	}
+
+	old, err := sql.Open("sqlite3", filepath.Join(appDir, "kalycs.db"))
+	if err != nil {
+		t.Fatalf("failed to open old database: %v", err)
+	}
+	stmts := []string{
+		`CREATE TABLE projects (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, description TEXT,
+			is_active BOOLEAN NOT NULL DEFAULT 1, is_favourite BOOLEAN NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE rules (id TEXT PRIMARY KEY, name TEXT NOT NULL, project_id TEXT NOT NULL,
+			rule TEXT NOT NULL CHECK(rule IN ('starts_with', 'contains', 'ends_with', 'extension', 'regex')),
+			texts TEXT NOT NULL, case_sensitive BOOLEAN NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE)`,
+		`INSERT INTO projects (id, name) VALUES ('p1', 'Old')`,
+		`INSERT INTO rules (id, name, project_id, rule, texts) VALUES ('r1', 'PDFs', 'p1', 'extension', '["pdf"]')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := old.Exec(stmt); err != nil {
+			t.Fatalf("failed to set up old schema: %v", err)
+		}
+	}
+	old.Close()
+
+	if err := InitializeDatabase(); err != nil {
+		t.Fatalf("InitializeDatabase() error = %v", err)
+	}
+	defer CloseDatabase()
+
+	var texts string
+	if err := GetDB().QueryRow(`SELECT texts FROM rules WHERE id = 'r1'`).Scan(&texts); err != nil {
+		t.Fatalf("existing rule lost during rebuild: %v", err)
+	}
+	if texts != `["pdf"]` {
+		t.Errorf("rule texts = %q, want [\"pdf\"]", texts)
+	}
+	var conditions string
+	if err := GetDB().QueryRow(`SELECT conditions FROM rules WHERE id = 'r1'`).Scan(&conditions); err != nil {
+		t.Fatalf("failed to read conditions: %v", err)
+	}
+	if want := `{"op":"match","kind":"extension","texts":["pdf"],"case_sensitive":false}`; conditions != want {
+		t.Errorf("backfilled conditions = %s, want %s", conditions, want)
+	}
+	if _, err := GetDB().Exec(`INSERT INTO rules (id, name, project_id, rule, texts) VALUES ('r2', 'Big', 'p1', 'size', '[">100MB"]')`); err != nil {
+		t.Errorf("new rule kind rejected after rebuild: %v", err)
+	}
+	if _, err := GetDB().Exec(`DELETE FROM projects WHERE id = 'p1'`); err != nil {
+		t.Fatalf("failed to delete project: %v", err)
+	}
+	var count int
+	GetDB().QueryRow(`SELECT COUNT(*) FROM rules`).Scan(&count)
+	if count != 0 {
+		t.Errorf("rules not cascaded after rebuild, %d left", count)
+	}
+
+	// A second start leaves the rebuilt table alone
+	if err := InitializeDatabase(); err != nil {
+		t.Fatalf("InitializeDatabase() second run error = %v", err)
+	}
+}
+
+// TestInitializeDatabaseBackfillsPriorities verifies that rules and projects from before
+// explicit ordering keep the order they were evaluated and listed in.
+func TestInitializeDatabaseBackfillsPriorities(t *testing.T) {
+	prepareTestEnv(t)
+
+	appDir, err := getAppDataDirectory()
+	if err != nil {
+		t.Fatalf("getAppDataDirectory() error = %v", err)
+	}
+
+	old, err := sql.Open("sqlite3", filepath.Join(appDir, "kalycs.db"))
+	if err != nil {
+		t.Fatalf("failed to open old database: %v", err)
+	}
+	stmts := []string{
+		`CREATE TABLE projects (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, description TEXT,
+			is_active BOOLEAN NOT NULL DEFAULT 1, is_favourite BOOLEAN NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE rules (id TEXT PRIMARY KEY, name TEXT NOT NULL, project_id TEXT NOT NULL,
+			rule TEXT NOT NULL, texts TEXT NOT NULL, case_sensitive BOOLEAN NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE)`,
+		`INSERT INTO projects (id, name, created_at) VALUES ('p1', 'Old', '2024-01-01'), ('p2', 'New', '2024-02-01')`,
+		`INSERT INTO rules (id, name, project_id, rule, texts, created_at) VALUES
+			('r1', 'Second', 'p1', 'contains', '["b"]', '2024-01-03'),
+			('r2', 'First', 'p1', 'contains', '["a"]', '2024-01-02'),
+			('r3', 'Only', 'p2', 'contains', '["c"]', '2024-02-02')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := old.Exec(stmt); err != nil {
+			t.Fatalf("failed to set up old schema: %v", err)
+		}
+	}
+	old.Close()
+
+	if err := InitializeDatabase(); err != nil {
+		t.Fatalf("InitializeDatabase() error = %v", err)
+	}
+	defer CloseDatabase()
+
+	want := map[string]int{"p1": 1, "p2": 0, "r1": 1, "r2": 0, "r3": 0}
+	for id, priority := range want {
+		var got int
+		err := GetDB().QueryRow(`SELECT priority FROM projects WHERE id = ? UNION ALL SELECT priority FROM rules WHERE id = ?`, id, id).Scan(&got)
+		if err != nil {
+			t.Fatalf("failed to read priority of %s: %v", id, err)
+		}
+		if got != priority {
+			t.Errorf("priority of %s = %d, want %d", id, got, priority)
+		}
+	}
+
+	// Reordered priorities survive the next start
+	if _, err := GetDB().Exec(`UPDATE rules SET priority = 1 - priority WHERE project_id = 'p1'`); err != nil {
+		t.Fatalf("failed to reorder rules: %v", err)
+	}
+	if err := InitializeDatabase(); err != nil {
+		t.Fatalf("InitializeDatabase() second run error = %v", err)
+	}
+	var priority int
+	GetDB().QueryRow(`SELECT priority FROM rules WHERE id = 'r1'`).Scan(&priority)
+	if priority != 0 {
+		t.Errorf("priority of r1 after restart = %d, want 0", priority)
+	}
+}
+
+// TestInitializeDatabaseMigratesFiles verifies that files indexed by older versions
+// get an assignment source and a rule reference.
+func TestInitializeDatabaseMigratesFiles(t *testing.T) {
+	prepareTestEnv(t)
+
+	appDir, err := getAppDataDirectory()
+	if err != nil {
+		t.Fatalf("getAppDataDirectory() error = %v", err)
+	}
+
+	old, err := sql.Open("sqlite3", filepath.Join(appDir, "kalycs.db"))
+	if err != nil {
+		t.Fatalf("failed to open old database: %v", err)
+	}
+	stmts := []string{
+		`CREATE TABLE projects (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, description TEXT,
+			is_active BOOLEAN NOT NULL DEFAULT 1, is_favourite BOOLEAN NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE files (id TEXT PRIMARY KEY, path TEXT UNIQUE, name TEXT NOT NULL, ext TEXT NOT NULL,
+			size INTEGER, mtime DATETIME, project_id TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE SET NULL)`,
+		`INSERT INTO projects (id, name) VALUES ('p1', 'Incoming'), ('p2', 'Invoices')`,
+		`INSERT INTO files (id, path, name, ext, project_id) VALUES
+			('f1', '/tmp/a.txt', 'a.txt', 'txt', 'p1'),
+			('f2', '/tmp/invoice.pdf', 'invoice.pdf', 'pdf', 'p2')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := old.Exec(stmt); err != nil {
+			t.Fatalf("failed to set up old schema: %v", err)
+		}
+	}
+	old.Close()
+
+	if err := InitializeDatabase(); err != nil {
+		t.Fatalf("InitializeDatabase() error = %v", err)
+	}
+	defer CloseDatabase()
+
+	want := map[string]string{"f1": AssignedIncoming, "f2": AssignedByRule}
+	for id, source := range want {
+		var got, mime string
+		var ruleID sql.NullString
+		err := GetDB().QueryRow(`SELECT assignment_source, rule_id, mime FROM files WHERE id = ?`, id).Scan(&got, &ruleID, &mime)
+		if err != nil {
+			t.Fatalf("failed to read file %s: %v", id, err)
+		}
+		if got != source || ruleID.Valid {
+			t.Errorf("file %s = %s, %v; want %s without a rule", id, got, ruleID, source)
+		}
+		if mime != "" {
+			t.Errorf("file %s mime = %q, want it left for detection", id, mime)
+		}
+	}
+}
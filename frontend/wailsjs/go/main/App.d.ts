@@ -2,6 +2,13 @@
 // This file is automatically generated. DO NOT EDIT
 import {context} from '../models';
 import {db} from '../models';
+import {classifier} from '../models';
+import {dedupe} from '../models';
+import {time} from '../models';
+
+export function AddWatchRoot(arg1:context.Context,arg2:db.WatchRoot):Promise<void>;
+
+export function AssignFile(arg1:context.Context,arg2:string,arg3:string):Promise<void>;
 
 export function CreateProject(arg1:context.Context,arg2:db.Project):Promise<void>;
 
@@ -11,12 +18,60 @@ export function DeleteProject(arg1:context.Context,arg2:string):Promise<void>;
 
 export function DeleteRule(arg1:context.Context,arg2:string):Promise<void>;
 
-export function ImportFolder(arg1:context.Context,arg2:string):Promise<void>;
+export function ExplainFile(arg1:context.Context,arg2:string):Promise<classifier.Explanation>;
+
+export function ForgetFile(arg1:context.Context,arg2:string):Promise<void>;
+
+export function GetAutoReclassify(arg1:context.Context):Promise<boolean>;
+
+export function GetDownloadsDirectory(arg1:context.Context):Promise<string>;
+
+export function GetRuleReport(arg1:context.Context):Promise<classifier.RuleReport>;
+
+export function ImportFolder(arg1:context.Context,arg2:string):Promise<string>;
+
+export function ListDuplicateGroups(arg1:context.Context):Promise<Array<dedupe.Group>>;
+
+export function ListFileActions(arg1:context.Context,arg2:number):Promise<Array<db.FileAction>>;
+
+export function ListMissingFiles(arg1:context.Context):Promise<Array<db.File>>;
+
+export function ListProjectFiles(arg1:context.Context,arg2:string):Promise<Array<db.File>>;
 
 export function ListProjects(arg1:context.Context):Promise<Array<db.Project>>;
 
 export function ListRules(arg1:context.Context,arg2:string):Promise<Array<db.Rule>>;
 
+export function ListWatchRoots(arg1:context.Context):Promise<Array<db.WatchRoot>>;
+
+export function MoveProject(arg1:context.Context,arg2:string,arg3:string,arg4:boolean):Promise<void>;
+
+export function MoveRule(arg1:context.Context,arg2:string,arg3:string,arg4:boolean):Promise<void>;
+
+export function PreviewRule(arg1:context.Context,arg2:db.Rule):Promise<classifier.PreviewResult>;
+
+export function PreviewRuleInDirectory(arg1:context.Context,arg2:db.Rule,arg3:string):Promise<classifier.PreviewResult>;
+
+export function ReclassifyFiles(arg1:context.Context,arg2:classifier.ReclassifyScope,arg3:boolean):Promise<classifier.ReclassifyResult>;
+
+export function RemoveWatchRoot(arg1:context.Context,arg2:string):Promise<void>;
+
+export function ResolveDuplicates(arg1:context.Context,arg2:string,arg3:string,arg4:string):Promise<dedupe.ResolveResult>;
+
+export function SetAutoReclassify(arg1:context.Context,arg2:boolean):Promise<void>;
+
+export function SetDownloadsDirectory(arg1:context.Context,arg2:string):Promise<void>;
+
+export function UndoAction(arg1:context.Context,arg2:string):Promise<classifier.UndoResult>;
+
+export function UndoBatch(arg1:context.Context,arg2:string):Promise<classifier.UndoResult>;
+
+export function UndoSince(arg1:context.Context,arg2:time.Time):Promise<classifier.UndoResult>;
+
+export function UnpinFile(arg1:context.Context,arg2:string):Promise<db.File>;
+
 export function UpdateProject(arg1:context.Context,arg2:db.Project):Promise<void>;
 
 export function UpdateRule(arg1:context.Context,arg2:db.Rule):Promise<void>;
+
+export function UpdateWatchRoot(arg1:context.Context,arg2:db.WatchRoot):Promise<void>;
@@ -2,6 +2,14 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddWatchRoot(arg1, arg2) {
+  return window['go']['main']['App']['AddWatchRoot'](arg1, arg2);
+}
+
+export function AssignFile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AssignFile'](arg1, arg2, arg3);
+}
+
 export function CreateProject(arg1, arg2) {
   return window['go']['main']['App']['CreateProject'](arg1, arg2);
 }
@@ -18,10 +26,46 @@ export function DeleteRule(arg1, arg2) {
   return window['go']['main']['App']['DeleteRule'](arg1, arg2);
 }
 
+export function ExplainFile(arg1, arg2) {
+  return window['go']['main']['App']['ExplainFile'](arg1, arg2);
+}
+
+export function ForgetFile(arg1, arg2) {
+  return window['go']['main']['App']['ForgetFile'](arg1, arg2);
+}
+
+export function GetAutoReclassify(arg1) {
+  return window['go']['main']['App']['GetAutoReclassify'](arg1);
+}
+
+export function GetDownloadsDirectory(arg1) {
+  return window['go']['main']['App']['GetDownloadsDirectory'](arg1);
+}
+
+export function GetRuleReport(arg1) {
+  return window['go']['main']['App']['GetRuleReport'](arg1);
+}
+
 export function ImportFolder(arg1, arg2) {
   return window['go']['main']['App']['ImportFolder'](arg1, arg2);
 }
 
+export function ListDuplicateGroups(arg1) {
+  return window['go']['main']['App']['ListDuplicateGroups'](arg1);
+}
+
+export function ListFileActions(arg1, arg2) {
+  return window['go']['main']['App']['ListFileActions'](arg1, arg2);
+}
+
+export function ListMissingFiles(arg1) {
+  return window['go']['main']['App']['ListMissingFiles'](arg1);
+}
+
+export function ListProjectFiles(arg1, arg2) {
+  return window['go']['main']['App']['ListProjectFiles'](arg1, arg2);
+}
+
 export function ListProjects(arg1) {
   return window['go']['main']['App']['ListProjects'](arg1);
 }
@@ -30,6 +74,62 @@ export function ListRules(arg1, arg2) {
   return window['go']['main']['App']['ListRules'](arg1, arg2);
 }
 
+export function ListWatchRoots(arg1) {
+  return window['go']['main']['App']['ListWatchRoots'](arg1);
+}
+
+export function MoveProject(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['MoveProject'](arg1, arg2, arg3, arg4);
+}
+
+export function MoveRule(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['MoveRule'](arg1, arg2, arg3, arg4);
+}
+
+export function PreviewRule(arg1, arg2) {
+  return window['go']['main']['App']['PreviewRule'](arg1, arg2);
+}
+
+export function PreviewRuleInDirectory(arg1, arg2, arg3) {
+  return window['go']['main']['App']['PreviewRuleInDirectory'](arg1, arg2, arg3);
+}
+
+export function ReclassifyFiles(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ReclassifyFiles'](arg1, arg2, arg3);
+}
+
+export function RemoveWatchRoot(arg1, arg2) {
+  return window['go']['main']['App']['RemoveWatchRoot'](arg1, arg2);
+}
+
+export function ResolveDuplicates(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ResolveDuplicates'](arg1, arg2, arg3, arg4);
+}
+
+export function SetAutoReclassify(arg1, arg2) {
+  return window['go']['main']['App']['SetAutoReclassify'](arg1, arg2);
+}
+
+export function SetDownloadsDirectory(arg1, arg2) {
+  return window['go']['main']['App']['SetDownloadsDirectory'](arg1, arg2);
+}
+
+export function UndoAction(arg1, arg2) {
+  return window['go']['main']['App']['UndoAction'](arg1, arg2);
+}
+
+export function UndoBatch(arg1, arg2) {
+  return window['go']['main']['App']['UndoBatch'](arg1, arg2);
+}
+
+export function UndoSince(arg1, arg2) {
+  return window['go']['main']['App']['UndoSince'](arg1, arg2);
+}
+
+export function UnpinFile(arg1, arg2) {
+  return window['go']['main']['App']['UnpinFile'](arg1, arg2);
+}
+
 export function UpdateProject(arg1, arg2) {
   return window['go']['main']['App']['UpdateProject'](arg1, arg2);
 }
@@ -37,3 +137,7 @@ export function UpdateProject(arg1, arg2) {
 export function UpdateRule(arg1, arg2) {
   return window['go']['main']['App']['UpdateRule'](arg1, arg2);
 }
+
+export function UpdateWatchRoot(arg1, arg2) {
+  return window['go']['main']['App']['UpdateWatchRoot'](arg1, arg2);
+}
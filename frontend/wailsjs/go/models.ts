@@ -1,15 +1,574 @@
+export namespace classifier {
+	
+	export class Explanation {
+	    file_id: string;
+	    path: string;
+	    project_id: string;
+	    project_name: string;
+	    source: string;
+	    rule_id: string;
+	    rule_name: string;
+	    match_kind: string;
+	    match_text: string;
+	    summary: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Explanation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file_id = source["file_id"];
+	        this.path = source["path"];
+	        this.project_id = source["project_id"];
+	        this.project_name = source["project_name"];
+	        this.source = source["source"];
+	        this.rule_id = source["rule_id"];
+	        this.rule_name = source["rule_name"];
+	        this.match_kind = source["match_kind"];
+	        this.match_text = source["match_text"];
+	        this.summary = source["summary"];
+	    }
+	}
+	export class PreviewFile {
+	    file_id: string;
+	    path: string;
+	    name: string;
+	    size: number;
+	    mtime: time.Time;
+	    current_project_id: string;
+	    rule_id?: string;
+	    new_name?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PreviewFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file_id = source["file_id"];
+	        this.path = source["path"];
+	        this.name = source["name"];
+	        this.size = source["size"];
+	        this.mtime = this.convertValues(source["mtime"], time.Time);
+	        this.current_project_id = source["current_project_id"];
+	        this.rule_id = source["rule_id"];
+	        this.new_name = source["new_name"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PreviewGroup {
+	    project_id: string;
+	    project_name: string;
+	    stolen: boolean;
+	    files: PreviewFile[];
+	
+	    static createFrom(source: any = {}) {
+	        return new PreviewGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.project_name = source["project_name"];
+	        this.stolen = source["stolen"];
+	        this.files = this.convertValues(source["files"], PreviewFile);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PreviewResult {
+	    project_id: string;
+	    matched: number;
+	    groups: PreviewGroup[];
+	    shadowed: PreviewFile[];
+	
+	    static createFrom(source: any = {}) {
+	        return new PreviewResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.matched = source["matched"];
+	        this.groups = this.convertValues(source["groups"], PreviewGroup);
+	        this.shadowed = this.convertValues(source["shadowed"], PreviewFile);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Reassignment {
+	    file_id: string;
+	    path: string;
+	    from_project_id: string;
+	    to_project_id: string;
+	    rule_id: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Reassignment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file_id = source["file_id"];
+	        this.path = source["path"];
+	        this.from_project_id = source["from_project_id"];
+	        this.to_project_id = source["to_project_id"];
+	        this.rule_id = source["rule_id"];
+	    }
+	}
+	export class ReclassifyResult {
+	    dry_run: boolean;
+	    scanned: number;
+	    pinned: number;
+	    moves: Reassignment[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ReclassifyResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.dry_run = source["dry_run"];
+	        this.scanned = source["scanned"];
+	        this.pinned = source["pinned"];
+	        this.moves = this.convertValues(source["moves"], Reassignment);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ReclassifyScope {
+	    kind: string;
+	    project_id: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReclassifyScope(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.project_id = source["project_id"];
+	    }
+	}
+	export class RuleRef {
+	    rule_id: string;
+	    rule_name: string;
+	    project_id: string;
+	    project_name: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RuleRef(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rule_id = source["rule_id"];
+	        this.rule_name = source["rule_name"];
+	        this.project_id = source["project_id"];
+	        this.project_name = source["project_name"];
+	    }
+	}
+	export class RuleOverlap {
+	    first: RuleRef;
+	    second: RuleRef;
+	    first_text: string;
+	    second_text: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RuleOverlap(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.first = this.convertValues(source["first"], RuleRef);
+	        this.second = this.convertValues(source["second"], RuleRef);
+	        this.first_text = source["first_text"];
+	        this.second_text = source["second_text"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class ShadowedRule {
+	    rule_id: string;
+	    rule_name: string;
+	    project_id: string;
+	    project_name: string;
+	    shadowed_by: RuleRef[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ShadowedRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rule_id = source["rule_id"];
+	        this.rule_name = source["rule_name"];
+	        this.project_id = source["project_id"];
+	        this.project_name = source["project_name"];
+	        this.shadowed_by = this.convertValues(source["shadowed_by"], RuleRef);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RuleStat {
+	    rule_id: string;
+	    rule_name: string;
+	    project_id: string;
+	    project_name: string;
+	    kind: string;
+	    total_matches: number;
+	    last_matched_at: sql.NullTime;
+	    recent_matches: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RuleStat(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rule_id = source["rule_id"];
+	        this.rule_name = source["rule_name"];
+	        this.project_id = source["project_id"];
+	        this.project_name = source["project_name"];
+	        this.kind = source["kind"];
+	        this.total_matches = source["total_matches"];
+	        this.last_matched_at = this.convertValues(source["last_matched_at"], sql.NullTime);
+	        this.recent_matches = source["recent_matches"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RuleReport {
+	    rules: RuleStat[];
+	    never_matched: RuleRef[];
+	    shadowed: ShadowedRule[];
+	    overlaps: RuleOverlap[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RuleReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rules = this.convertValues(source["rules"], RuleStat);
+	        this.never_matched = this.convertValues(source["never_matched"], RuleRef);
+	        this.shadowed = this.convertValues(source["shadowed"], ShadowedRule);
+	        this.overlaps = this.convertValues(source["overlaps"], RuleOverlap);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class UndoConflict {
+	    action_id: string;
+	    path: string;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new UndoConflict(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.action_id = source["action_id"];
+	        this.path = source["path"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class UndoResult {
+	    undone: string[];
+	    conflicts: UndoConflict[];
+	
+	    static createFrom(source: any = {}) {
+	        return new UndoResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.undone = source["undone"];
+	        this.conflicts = this.convertValues(source["conflicts"], UndoConflict);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace db {
 	
+	export class File {
+	    id: string;
+	    path: string;
+	    name: string;
+	    ext: string;
+	    size: number;
+	    mtime: time.Time;
+	    project_id: sql.NullString;
+	    assignment_source: string;
+	    rule_id: sql.NullString;
+	    match_kind: string;
+	    match_text: string;
+	    mime: string;
+	    origin_url: string;
+	    referrer_url: string;
+	    hash: sql.NullString;
+	    deleted_at: sql.NullTime;
+	    created_at: time.Time;
+	    updated_at: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new File(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.path = source["path"];
+	        this.name = source["name"];
+	        this.ext = source["ext"];
+	        this.size = source["size"];
+	        this.mtime = this.convertValues(source["mtime"], time.Time);
+	        this.project_id = this.convertValues(source["project_id"], sql.NullString);
+	        this.assignment_source = source["assignment_source"];
+	        this.rule_id = this.convertValues(source["rule_id"], sql.NullString);
+	        this.match_kind = source["match_kind"];
+	        this.match_text = source["match_text"];
+	        this.mime = source["mime"];
+	        this.origin_url = source["origin_url"];
+	        this.referrer_url = source["referrer_url"];
+	        this.hash = this.convertValues(source["hash"], sql.NullString);
+	        this.deleted_at = this.convertValues(source["deleted_at"], sql.NullTime);
+	        this.created_at = this.convertValues(source["created_at"], time.Time);
+	        this.updated_at = this.convertValues(source["updated_at"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FileAction {
+	    id: string;
+	    file_id: string;
+	    action: string;
+	    old_path: string;
+	    new_path: string;
+	    size: number;
+	    mtime: sql.NullTime;
+	    rule_id: sql.NullString;
+	    batch_id: sql.NullString;
+	    created_at: time.Time;
+	    undone_at: sql.NullTime;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileAction(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.file_id = source["file_id"];
+	        this.action = source["action"];
+	        this.old_path = source["old_path"];
+	        this.new_path = source["new_path"];
+	        this.size = source["size"];
+	        this.mtime = this.convertValues(source["mtime"], sql.NullTime);
+	        this.rule_id = this.convertValues(source["rule_id"], sql.NullString);
+	        this.batch_id = this.convertValues(source["batch_id"], sql.NullString);
+	        this.created_at = this.convertValues(source["created_at"], time.Time);
+	        this.undone_at = this.convertValues(source["undone_at"], sql.NullTime);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class Project {
 	    id: string;
 	    name: string;
 	    description: string;
 	    is_active: boolean;
 	    is_favourite: boolean;
-	    // Go type: time
-	    created_at: any;
-	    // Go type: time
-	    updated_at: any;
+	    destination_dir: string;
+	    action: string;
+	    priority: number;
+	    created_at: time.Time;
+	    updated_at: time.Time;
 	
 	    static createFrom(source: any = {}) {
 	        return new Project(source);
@@ -22,8 +581,11 @@ export namespace db {
 	        this.description = source["description"];
 	        this.is_active = source["is_active"];
 	        this.is_favourite = source["is_favourite"];
-	        this.created_at = this.convertValues(source["created_at"], null);
-	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.destination_dir = source["destination_dir"];
+	        this.action = source["action"];
+	        this.priority = source["priority"];
+	        this.created_at = this.convertValues(source["created_at"], time.Time);
+	        this.updated_at = this.convertValues(source["updated_at"], time.Time);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -50,11 +612,12 @@ export namespace db {
 	    project_id: string;
 	    rule: string;
 	    texts: string;
+	    conditions: string;
 	    case_sensitive: boolean;
-	    // Go type: time
-	    created_at: any;
-	    // Go type: time
-	    updated_at: any;
+	    priority: number;
+	    rename_template: string;
+	    created_at: time.Time;
+	    updated_at: time.Time;
 	
 	    static createFrom(source: any = {}) {
 	        return new Rule(source);
@@ -67,9 +630,12 @@ export namespace db {
 	        this.project_id = source["project_id"];
 	        this.rule = source["rule"];
 	        this.texts = source["texts"];
+	        this.conditions = source["conditions"];
 	        this.case_sensitive = source["case_sensitive"];
-	        this.created_at = this.convertValues(source["created_at"], null);
-	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.priority = source["priority"];
+	        this.rename_template = source["rename_template"];
+	        this.created_at = this.convertValues(source["created_at"], time.Time);
+	        this.updated_at = this.convertValues(source["updated_at"], time.Time);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -90,6 +656,213 @@ export namespace db {
 		    return a;
 		}
 	}
+	export class WatchRoot {
+	    id: string;
+	    path: string;
+	    enabled: boolean;
+	    recursive: boolean;
+	    max_depth: number;
+	    quiet_period_ms: number;
+	    ignore_patterns: string;
+	    created_at: time.Time;
+	    updated_at: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new WatchRoot(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.path = source["path"];
+	        this.enabled = source["enabled"];
+	        this.recursive = source["recursive"];
+	        this.max_depth = source["max_depth"];
+	        this.quiet_period_ms = source["quiet_period_ms"];
+	        this.ignore_patterns = source["ignore_patterns"];
+	        this.created_at = this.convertValues(source["created_at"], time.Time);
+	        this.updated_at = this.convertValues(source["updated_at"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace dedupe {
+	
+	export class Group {
+	    hash: string;
+	    size: number;
+	    files: db.File[];
+	    reclaimable: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Group(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hash = source["hash"];
+	        this.size = source["size"];
+	        this.files = this.convertValues(source["files"], db.File);
+	        this.reclaimable = source["reclaimable"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ResolveFailure {
+	    file_id: string;
+	    path: string;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ResolveFailure(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file_id = source["file_id"];
+	        this.path = source["path"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class ResolveResult {
+	    batch_id: string;
+	    resolved: string[];
+	    failures: ResolveFailure[];
+	    reclaimed: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ResolveResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.batch_id = source["batch_id"];
+	        this.resolved = source["resolved"];
+	        this.failures = this.convertValues(source["failures"], ResolveFailure);
+	        this.reclaimed = source["reclaimed"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace sql {
+	
+	export class NullString {
+	    String: string;
+	    Valid: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new NullString(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.String = source["String"];
+	        this.Valid = source["Valid"];
+	    }
+	}
+	export class NullTime {
+	    Time: time.Time;
+	    Valid: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new NullTime(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Time = this.convertValues(source["Time"], time.Time);
+	        this.Valid = source["Valid"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace time {
+	
+	export class Time {
+	
+	
+	    static createFrom(source: any = {}) {
+	        return new Time(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	
+	    }
+	}
 
 }
 
@@ -0,0 +1,63 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"kalycs/db"
+	"kalycs/internal/logging"
+	"time"
+)
+
+// Assign moves a file to the project the user chose and pins it there, so
+// neither the watcher nor reclassification changes it again
+func (c *Classifier) Assign(ctx context.Context, fileID, projectID string) error {
+	if _, err := c.store.Project.GetByID(ctx, projectID); err != nil {
+		return err
+	}
+	return c.store.File.SetProject(ctx, fileID, projectID)
+}
+
+// Unpin hands a manually assigned file back to the rules and assigns it to
+// the project they choose right away. It returns the updated file.
+func (c *Classifier) Unpin(ctx context.Context, fileID string) (*db.File, error) {
+	f, err := c.store.File.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return nil, fmt.Errorf("file with ID '%s' not found", fileID)
+	}
+
+	roots, err := c.watchRoots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.ensureMime(ctx, f, true)
+	c.ensureSource(ctx, f, true)
+
+	c.mu.RLock()
+	rules := c.set
+	c.mu.RUnlock()
+
+	var fields map[string]string
+	if usesMetadata(rules) {
+		if fields, err = c.store.Metadata.Get(ctx, f.ID); err != nil {
+			return nil, err
+		}
+		if fields == nil {
+			fields = c.ensureMetadata(ctx, *f, nil, true)
+		}
+	}
+
+	cand, _ := c.storedCandidate(ctx, *f, fields, roots, time.Now())
+	a := c.assignment(rules, cand)
+	if err := c.store.File.SetAssignment(ctx, f.ID, a); err != nil {
+		return nil, err
+	}
+	if a.RuleID != "" {
+		c.recordMatch(ctx, a.RuleID, f.Path, time.Now())
+	}
+	logging.L().Infow("File unpinned", "file_id", f.ID, "project_id", a.ProjectID, "rule_id", a.RuleID)
+	return c.store.File.GetByID(ctx, f.ID)
+}
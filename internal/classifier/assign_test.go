@@ -0,0 +1,80 @@
+package classifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kalycs/db"
+)
+
+func TestAssign_PinSurvivesClassifyAndUnpin(t *testing.T) {
+	c, s, destDir := setupMoveProject(t)
+	ctx := context.Background()
+
+	other := &db.Project{Name: "Taxes", IsActive: true}
+	if err := s.Project.Create(ctx, other); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "invoice-1.pdf")
+	classifyNewFile(t, ctx, c, path)
+	moved := filepath.Join(destDir, "invoice-1.pdf")
+	f, err := s.File.GetByPath(ctx, moved)
+	if err != nil || f == nil {
+		t.Fatalf("GetByPath() = %v, %v", f, err)
+	}
+	if f.AssignmentSource != db.AssignedByRule || !f.RuleID.Valid {
+		t.Fatalf("classified file = %+v, want a rule assignment with its rule", f)
+	}
+	ruleID := f.RuleID.String
+
+	if err := c.Assign(ctx, f.ID, other.ID); err != nil {
+		t.Fatalf("Assign() error = %v", err)
+	}
+	if err := c.Assign(ctx, f.ID, "missing-project"); err == nil {
+		t.Error("Assign() to unknown project should fail")
+	}
+
+	// Seeing the file again, e.g. after a touch, keeps the pin and the file in place
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(moved, later, later); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Classify(ctx, moved, info); err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	f, _ = s.File.GetByID(ctx, f.ID)
+	if f.ProjectID.String != other.ID || f.AssignmentSource != db.AssignedManually || f.RuleID.Valid {
+		t.Errorf("file after Classify() = %+v, want it pinned to Taxes", f)
+	}
+	if _, err := os.Stat(moved); err != nil {
+		t.Errorf("pinned file was acted on: %v", err)
+	}
+
+	unpinned, err := c.Unpin(ctx, f.ID)
+	if err != nil {
+		t.Fatalf("Unpin() error = %v", err)
+	}
+	if unpinned.ProjectID.String == other.ID || unpinned.AssignmentSource != db.AssignedByRule || unpinned.RuleID.String != ruleID {
+		t.Errorf("file after Unpin() = %+v, want it back under its rule", unpinned)
+	}
+	if n := totalMatches(t, s, ruleID); n != 2 {
+		t.Errorf("TotalMatches after Unpin() = %d, want the capture counted again", n)
+	}
+
+	// Deleting the rule keeps the file but forgets the rule
+	if err := s.Rule.Delete(ctx, ruleID); err != nil {
+		t.Fatalf("failed to delete rule: %v", err)
+	}
+	f, _ = s.File.GetByID(ctx, f.ID)
+	if f == nil || f.RuleID.Valid {
+		t.Errorf("file after rule deletion = %+v, want rule_id cleared", f)
+	}
+}
@@ -4,9 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"kalycs/db"
+	"kalycs/internal/condition"
+	"kalycs/internal/fileops"
+	"kalycs/internal/glob"
 	"kalycs/internal/logging"
+	"kalycs/internal/metadata"
+	"kalycs/internal/rename"
+	"kalycs/internal/ruleexpr"
 	"kalycs/internal/store"
 	"os"
 	"path/filepath"
@@ -14,6 +21,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 const IncomingProjectName = "Incoming"
@@ -24,8 +32,42 @@ type CompiledRule struct {
 	Kind          string
 	Texts         []string
 	CaseSensitive bool
-	Regexp        *regexp.Regexp
-	Priority      int
+	Patterns      []*regexp.Regexp     // regex and glob rules, one per text
+	Ranges        []ruleexpr.Range     // size, age and modified rules
+	Fields        []metadata.Condition // metadata rules, one per text
+	Condition     *CompiledCondition   // composite rules
+	Rename        *rename.Template     // new name for captured files; nil keeps their name
+	// Rules are tried by project priority, then by their priority within the
+	// project; the first match wins
+	ProjectPriority int
+	Priority        int
+}
+
+// CompiledCondition is a compiled node of a composite rule's condition tree
+type CompiledCondition struct {
+	Op       string
+	Children []CompiledCondition
+	Match    CompiledRule // for "match" nodes
+}
+
+// candidate is the file a rule is evaluated against
+type candidate struct {
+	name    string
+	ext     string
+	rel     string            // path relative to the watched root, with forward slashes
+	parent  string            // name of the directory holding the file
+	mime    string            // content type sniffed from the leading bytes; empty if unknown
+	content *contentText      // text for content rules, extracted on first use
+	fields  map[string]string // embedded metadata such as EXIF or PDF document info
+	sources []string          // hosts of the download and referring page URLs
+	size    int64
+	mtime   time.Time
+	now     time.Time // reference point for age rules
+}
+
+// HashQueue receives files after they are stored so their content can be hashed in the background
+type HashQueue interface {
+	Enqueue(f db.File)
 }
 
 type Classifier struct {
@@ -33,14 +75,23 @@ type Classifier struct {
 	set               []CompiledRule
 	store             *store.Store
 	incomingProjectID string
+	hashQueue         HashQueue
+	extractors        []metadata.Extractor
+	reclassifyMu      sync.Mutex // one reclassification at a time
 }
 
 func NewClassifier(s *store.Store) *Classifier {
 	return &Classifier{
-		store: s,
+		store:      s,
+		extractors: metadata.Default(),
 	}
 }
 
+// SetHashQueue makes Classify hand every stored file to q
+func (c *Classifier) SetHashQueue(q HashQueue) {
+	c.hashQueue = q
+}
+
 func (c *Classifier) LoadIncomingProject(ctx context.Context) error {
 	incoming, err := c.store.Project.GetByName(ctx, IncomingProjectName)
 	if err != nil {
@@ -72,6 +123,15 @@ func (c *Classifier) Reload(ctx context.Context) error {
 		return err
 	}
 
+	projects, err := c.store.Project.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	projectPriority := make(map[string]int, len(projects))
+	for _, p := range projects {
+		projectPriority[p.ID] = p.Priority
+	}
+
 	compiled := make([]CompiledRule, 0, len(rules))
 	for _, r := range rules {
 		compiledRule, err := compileRule(r)
@@ -79,12 +139,11 @@ func (c *Classifier) Reload(ctx context.Context) error {
 			logging.L().Warnw("Skipping invalid rule", "rule_name", r.Name, "rule_id", r.ID, "error", err)
 			continue
 		}
+		compiledRule.ProjectPriority = projectPriority[r.ProjectID]
 		compiled = append(compiled, compiledRule)
 	}
 
-	sort.Slice(compiled, func(i, j int) bool {
-		return compiled[i].Priority < compiled[j].Priority
-	})
+	sortRules(compiled)
 
 	c.mu.Lock()
 	c.set = compiled
@@ -94,34 +153,111 @@ func (c *Classifier) Reload(ctx context.Context) error {
 	return nil
 }
 
+// sortRules orders rules by project priority and then rule priority. Rules
+// from ListActive are already in order with ties broken by creation time and
+// ID; the stable sort keeps that for equal priorities.
+func sortRules(rules []CompiledRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].ProjectPriority != rules[j].ProjectPriority {
+			return rules[i].ProjectPriority < rules[j].ProjectPriority
+		}
+		return rules[i].Priority < rules[j].Priority
+	})
+}
+
 func compileRule(r db.Rule) (CompiledRule, error) {
+	var tmpl *rename.Template
+	if r.RenameTemplate != "" {
+		t, err := rename.Parse(r.RenameTemplate)
+		if err != nil {
+			return CompiledRule{}, err
+		}
+		tmpl = t
+	}
+
+	if r.Rule == condition.KindComposite {
+		tree, err := condition.Parse(r.Conditions)
+		if err != nil {
+			return CompiledRule{}, err
+		}
+		cc, err := compileCondition(tree)
+		if err != nil {
+			return CompiledRule{}, err
+		}
+		return CompiledRule{
+			RuleID:    r.ID,
+			ProjectID: r.ProjectID,
+			Kind:      r.Rule,
+			Condition: &cc,
+			Rename:    tmpl,
+			Priority:  r.Priority,
+		}, nil
+	}
+
 	var texts []string
 	if err := json.Unmarshal([]byte(r.Texts), &texts); err != nil {
 		return CompiledRule{}, err
 	}
 
+	cr, err := compileMatch(r.Rule, texts, r.CaseSensitive)
+	if err != nil {
+		return CompiledRule{}, err
+	}
+	cr.RuleID = r.ID
+	cr.ProjectID = r.ProjectID
+	cr.Rename = tmpl
+	cr.Priority = r.Priority
+	return cr, nil
+}
+
+// compileMatch compiles a single rule kind with its texts
+func compileMatch(kind string, texts []string, caseSensitive bool) (CompiledRule, error) {
 	cr := CompiledRule{
-		RuleID:        r.ID,
-		ProjectID:     r.ProjectID,
-		Kind:          r.Rule,
-		CaseSensitive: r.CaseSensitive,
+		Kind:          kind,
+		CaseSensitive: caseSensitive,
 		Texts:         texts,
 	}
 
-	if cr.Kind == "regex" {
+	if ruleexpr.IsKind(cr.Kind) {
+		for _, t := range cr.Texts {
+			rng, err := ruleexpr.Parse(cr.Kind, t)
+			if err != nil {
+				return CompiledRule{}, err
+			}
+			cr.Ranges = append(cr.Ranges, rng)
+		}
+	} else if cr.Kind == "glob" || cr.Kind == KindPath {
+		for _, t := range cr.Texts {
+			re, err := glob.Compile(t, cr.CaseSensitive)
+			if err != nil {
+				return CompiledRule{}, err
+			}
+			cr.Patterns = append(cr.Patterns, re)
+		}
+	} else if cr.Kind == KindMetadata {
+		for _, t := range cr.Texts {
+			cond, err := metadata.ParseCondition(t)
+			if err != nil {
+				return CompiledRule{}, err
+			}
+			cr.Fields = append(cr.Fields, cond)
+		}
+	} else if cr.Kind == "regex" || cr.Kind == KindContentRegex {
 		if len(cr.Texts) == 0 {
 			return CompiledRule{}, fmt.Errorf("regex rule requires at least one text pattern")
 		}
 
-		pattern := cr.Texts[0]
-		if !cr.CaseSensitive {
-			pattern = "(?i)" + pattern
-		}
-		re, err := regexp.Compile(pattern)
-		if err != nil {
-			return CompiledRule{}, err
+		for i, t := range cr.Texts {
+			pattern := t
+			if !cr.CaseSensitive {
+				pattern = "(?i)" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return CompiledRule{}, fmt.Errorf("regex pattern %d: %w", i, err)
+			}
+			cr.Patterns = append(cr.Patterns, re)
 		}
-		cr.Regexp = re
 	} else if !cr.CaseSensitive {
 		for i, t := range cr.Texts {
 			cr.Texts[i] = strings.ToLower(t)
@@ -132,55 +268,361 @@ func compileRule(r db.Rule) (CompiledRule, error) {
 	return cr, nil
 }
 
+func compileCondition(n condition.Node) (CompiledCondition, error) {
+	cc := CompiledCondition{Op: n.Op}
+	if n.Op == condition.OpMatch {
+		m, err := compileMatch(n.Kind, n.Texts, n.CaseSensitive)
+		if err != nil {
+			return CompiledCondition{}, err
+		}
+		cc.Match = m
+		return cc, nil
+	}
+	for _, child := range n.Children {
+		c, err := compileCondition(child)
+		if err != nil {
+			return CompiledCondition{}, err
+		}
+		cc.Children = append(cc.Children, c)
+	}
+	return cc, nil
+}
+
+// eval reports whether the file satisfies this node of the tree
+func (cc CompiledCondition) eval(c candidate) bool {
+	_, ok := cc.explain(c)
+	return ok
+}
+
+// explain reports whether the file satisfies this node and, if so, describes
+// the leaves that decided it, e.g. `extension "pdf" and contains "invoice"`
+func (cc CompiledCondition) explain(c candidate) (string, bool) {
+	switch cc.Op {
+	case condition.OpAnd:
+		parts := make([]string, 0, len(cc.Children))
+		for _, child := range cc.Children {
+			why, ok := child.explain(c)
+			if !ok {
+				return "", false
+			}
+			// An or child explains itself by a single branch, so no grouping is needed
+			parts = append(parts, why)
+		}
+		return strings.Join(parts, " and "), true
+	case condition.OpOr:
+		for _, child := range cc.Children {
+			if why, ok := child.explain(c); ok {
+				return why, true
+			}
+		}
+		return "", false
+	case condition.OpNot:
+		if _, ok := cc.Children[0].explain(c); ok {
+			return "", false
+		}
+		return "not (" + cc.Children[0].describe() + ")", true
+	case condition.OpMatch:
+		text, ok := matchedText(cc.Match, c)
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf("%s %q", cc.Match.Kind, text), true
+	}
+	return "", false
+}
+
+// describe renders the whole node, e.g. `starts_with "draft" or "tmp"`
+func (cc CompiledCondition) describe() string {
+	switch cc.Op {
+	case condition.OpAnd, condition.OpOr:
+		parts := make([]string, 0, len(cc.Children))
+		for _, child := range cc.Children {
+			parts = append(parts, child.group(child.describe()))
+		}
+		return strings.Join(parts, " "+cc.Op+" ")
+	case condition.OpNot:
+		return "not (" + cc.Children[0].describe() + ")"
+	}
+	quoted := make([]string, len(cc.Match.Texts))
+	for i, t := range cc.Match.Texts {
+		quoted[i] = fmt.Sprintf("%q", t)
+	}
+	return cc.Match.Kind + " " + strings.Join(quoted, " or ")
+}
+
+// group parenthesises text describing this node when it joins several children
+func (cc CompiledCondition) group(text string) string {
+	if (cc.Op == condition.OpAnd || cc.Op == condition.OpOr) && len(cc.Children) > 1 {
+		return "(" + text + ")"
+	}
+	return text
+}
+
 func (c *Classifier) Classify(ctx context.Context, absPath string, meta os.FileInfo) error {
 	name := meta.Name()
-	ext := filepath.Ext(name)
-	if len(ext) > 0 {
-		ext = ext[1:]
-	}
+	ext := extOf(name)
 
 	c.mu.RLock()
 	rules := c.set
 	c.mu.RUnlock()
 
+	// A file the user assigned by hand keeps its project and is not acted on
+	existing, err := c.store.File.GetByPath(ctx, absPath)
+	if err != nil {
+		logging.L().Warnw("Failed to look up file before classifying", "file_path", absPath, "error", err)
+	}
+	pinned := existing != nil && existing.AssignmentSource == db.AssignedManually
+
+	// The source of a copy or link was classified through the copy, which is
+	// tracked in its place; it is not acted on again
+	liveSource, err := c.IsActionSource(ctx, absPath)
+	if err != nil {
+		logging.L().Warnw("Failed to check file action journal", "file_path", absPath, "error", err)
+	}
+	if liveSource && existing == nil {
+		logging.L().Infow("File is the source of a copy or link, skipping", "file_path", absPath)
+		return nil
+	}
+	mime := detectMime(existing, absPath, meta)
+	// Metadata is only read from the file when a rule may test it
+	var fields map[string]string
+	if !pinned && usesMetadata(rules) {
+		fields = c.detectMetadata(ctx, existing, absPath, mime, meta)
+	}
+	source := detectSource(existing, absPath)
+
+	// A file the classifier renamed is matched under its old name and not renamed again
+	renamedFrom := ""
+	if existing != nil {
+		renamedFrom = c.renamedFrom(ctx, existing.ID)
+	}
+
 	// TODO: Get default "Incoming" project ID
 	projectID := ""
 	matchedRule := ""
+	var matchKind, matchText string
+	var match CompiledRule
 
-	for _, r := range rules {
-		if matches(r, name, ext) {
+	if !pinned {
+		roots, err := c.watchRoots(ctx)
+		if err != nil {
+			logging.L().Warnw("Failed to load watch roots before classifying", "file_path", absPath, "error", err)
+		}
+		cand := candidate{
+			name:    name,
+			ext:     ext,
+			rel:     relativePath(roots, absPath),
+			parent:  parentName(absPath),
+			mime:    mime,
+			content: newContentText(ctx, absPath, mime),
+			fields:  fields,
+			sources: source.Hosts(),
+			size:    meta.Size(),
+			mtime:   meta.ModTime(),
+			now:     time.Now(),
+		}
+		if renamedFrom != "" {
+			cand = cand.withName(filepath.Base(renamedFrom), absPath, roots)
+		}
+		if r, text, ok := firstMatch(rules, cand); ok {
 			projectID = r.ProjectID
 			matchedRule = r.RuleID
-			break
+			matchKind, matchText = r.Kind, text
+			match = r
 		}
 	}
 
+	targetID := projectID
+	if targetID == "" {
+		targetID = c.incomingProjectID
+	}
+	originalPath := absPath
+	actedPath := absPath // where the project action left the file, before renaming
+	var action string
+	var actionErr, renameErr error
+	if !pinned && !liveSource {
+		actedPath, action, actionErr = c.applyProjectAction(ctx, targetID, absPath, meta)
+		absPath = actedPath
+		if match.Rename != nil && renamedFrom == "" {
+			// Copies and links are new files, so a change to the original does not concern them
+			expected := meta
+			if action != "" && action != fileops.ActionMove {
+				expected = nil
+			}
+			absPath, renameErr = c.renameFile(ctx, match, matchText, actedPath, expected)
+		}
+	}
+	name = filepath.Base(absPath)
+
 	f := &db.File{
-		Path:  absPath,
-		Name:  name,
-		Ext:   ext,
-		Size:  meta.Size(),
-		Mtime: meta.ModTime(),
+		Path:        absPath,
+		Name:        name,
+		Ext:         extOf(name),
+		Size:        meta.Size(),
+		Mtime:       meta.ModTime(),
+		Mime:        mime,
+		OriginURL:   source.OriginURL,
+		ReferrerURL: source.ReferrerURL,
 	}
 
-	if projectID != "" {
+	switch {
+	case pinned:
+		f.ProjectID = existing.ProjectID
+		f.AssignmentSource = db.AssignedManually
+		logging.L().Infow("File keeps manually assigned project", "file_path", absPath, "file_name", name, "project_id", existing.ProjectID.String)
+	case projectID != "":
 		f.ProjectID = sql.NullString{String: projectID, Valid: true}
-		logging.L().Infow("File classified by rule", "file_path", absPath, "file_name", name, "rule_id", matchedRule, "project_id", projectID)
-	} else {
+		f.AssignmentSource = db.AssignedByRule
+		f.RuleID = sql.NullString{String: matchedRule, Valid: true}
+		f.MatchKind, f.MatchText = matchKind, matchText
+		logging.L().Infow("File classified by rule", "file_path", absPath, "file_name", name, "rule_id", matchedRule, "project_id", projectID, "match_text", matchText)
+	default:
 		f.ProjectID = sql.NullString{String: c.incomingProjectID, Valid: true}
+		f.AssignmentSource = db.AssignedIncoming
 		logging.L().Infow("File classified to incoming project", "file_path", absPath, "file_name", name, "project_id", c.incomingProjectID)
 	}
 
-	err := c.store.File.Upsert(ctx, f)
+	// A file that left its path takes its row along, keeping the ID its
+	// journaled actions refer to
+	if existing != nil && absPath != originalPath && (action == "" || action == fileops.ActionMove) {
+		if err := c.Relocate(ctx, existing.ID, absPath); err != nil {
+			logging.L().Warnw("Failed to move file row to new path", "file_id", existing.ID, "file_path", absPath, "error", err)
+		}
+	}
+
+	err = c.store.File.Upsert(ctx, f)
 	if err != nil {
 		logging.L().Errorw("Failed to upsert classified file", "file_path", absPath, "file_name", name, "error", err)
+		return err
+	}
+
+	// Fields that were not read stay stored only while they still describe the file
+	if fields != nil || !unchanged(existing, meta) {
+		if err := c.store.Metadata.Replace(ctx, f.ID, fields); err != nil {
+			logging.L().Warnw("Failed to store file metadata", "file_id", f.ID, "file_path", absPath, "error", err)
+		}
+	}
+	if action != "" {
+		c.recordAction(ctx, f.ID, action, originalPath, actedPath, matchedRule)
+	}
+	if absPath != actedPath {
+		c.recordAction(ctx, f.ID, fileops.ActionRename, actedPath, absPath, matchedRule)
 	}
-	return err
+	if matchedRule != "" && capturedAnew(existing, matchedRule) {
+		c.recordMatch(ctx, matchedRule, absPath, time.Now())
+	}
+	if c.hashQueue != nil {
+		c.hashQueue.Enqueue(*f)
+	}
+	return errors.Join(actionErr, renameErr)
+}
+
+// capturedAnew reports whether ruleID capturing the file counts as a match:
+// rescanning a file the same rule already holds does not count again
+func capturedAnew(existing *db.File, ruleID string) bool {
+	return existing == nil || existing.DeletedAt.Valid || existing.RuleID.String != ruleID
+}
+
+// recordMatch counts ruleID capturing the file at path in the rule's
+// statistics. Failures are logged since the file itself was classified.
+func (c *Classifier) recordMatch(ctx context.Context, ruleID, path string, at time.Time) {
+	if err := c.store.Stats.RecordMatch(ctx, ruleID, at); err != nil {
+		logging.L().Warnw("Failed to record rule match", "rule_id", ruleID, "file_path", path, "error", err)
+	}
+}
+
+// applyProjectAction moves, copies or links the file into the project's
+// destination directory. The file is left where it is when the project has no
+// action, the action fails, or the user undid an earlier action on this file;
+// the returned path is where the file now lives and the action that was performed.
+func (c *Classifier) applyProjectAction(ctx context.Context, projectID, absPath string, meta os.FileInfo) (string, string, error) {
+	project, err := c.store.Project.GetByID(ctx, projectID)
+	if err != nil {
+		logging.L().Warnw("Failed to load project for file action", "project_id", projectID, "error", err)
+		return absPath, "", nil
+	}
+	if project.Action == "" || project.Action == fileops.ActionNone {
+		return absPath, "", nil
+	}
+
+	// A file restored by undo keeps its old mtime, so an undo newer than the
+	// file means the user put it back on purpose.
+	undone, err := c.store.Action.WasUndone(ctx, absPath, meta.ModTime())
+	if err != nil {
+		logging.L().Warnw("Failed to check undo journal", "file_path", absPath, "error", err)
+	} else if undone {
+		logging.L().Infow("File action was undone earlier, leaving in place", "file_path", absPath, "project_id", projectID)
+		return absPath, "", nil
+	}
+
+	newPath, err := fileops.Apply(project.Action, absPath, project.DestinationDir, meta)
+	if err != nil {
+		if errors.Is(err, fileops.ErrSourceChanged) {
+			logging.L().Infow("File still changing, leaving in place", "file_path", absPath, "project_id", projectID)
+			return absPath, "", nil
+		}
+		logging.L().Errorw("Failed to apply project file action", "file_path", absPath, "project_id", projectID, "action", project.Action, "error", err)
+		return absPath, "", err
+	}
+	if newPath == absPath {
+		return absPath, "", nil
+	}
+	return newPath, project.Action, nil
+}
+
+// matchesFile evaluates a rule against a file, covering both name-based rules
+// and the size, age and modified rules that look at file metadata
+func matchesFile(r CompiledRule, c candidate) bool {
+	_, ok := matchedText(r, c)
+	return ok
+}
+
+// matchedText evaluates a rule against a file and returns the rule text that
+// matched: the alternative for plain rules, or a description of the leaves
+// that decided it for composite rules
+func matchedText(r CompiledRule, c candidate) (string, bool) {
+	var v int64
+	switch r.Kind {
+	case ruleexpr.KindSize:
+		v = c.size
+	case ruleexpr.KindAge:
+		v = int64(c.now.Sub(c.mtime) / time.Second)
+	case ruleexpr.KindModified:
+		v = c.mtime.Unix()
+	case condition.KindComposite:
+		return r.Condition.explain(c)
+	case KindPath:
+		return matchedPath(r, c.rel)
+	case KindParent:
+		return matchedName(r, c.parent, "")
+	case KindMime:
+		return matchedMime(r, c.mime)
+	case KindContent, KindContentRegex:
+		return matchedContent(r, c)
+	case KindMetadata:
+		return matchedMetadata(r, c.fields)
+	case KindSourceDomain:
+		return matchedSourceDomain(r, c.sources)
+	default:
+		return matchedName(r, c.name, c.ext)
+	}
+	for i, rng := range r.Ranges {
+		if rng.Contains(v) {
+			return r.Texts[i], true
+		}
+	}
+	return "", false
 }
 
 func matches(r CompiledRule, name, ext string) bool {
+	_, ok := matchedName(r, name, ext)
+	return ok
+}
+
+// matchedName evaluates a name-based rule and returns the text that matched
+func matchedName(r CompiledRule, name, ext string) (string, bool) {
 	testName := name
-	if !r.CaseSensitive && r.Kind != "regex" {
+	if !r.CaseSensitive && r.Kind != "regex" && r.Kind != "glob" {
 		testName = strings.ToLower(testName)
 	}
 
@@ -188,24 +630,21 @@ func matches(r CompiledRule, name, ext string) bool {
 	case "starts_with":
 		for _, t := range r.Texts {
 			if strings.HasPrefix(testName, t) {
-				return true
+				return t, true
 			}
 		}
-		return false
 	case "contains":
 		for _, t := range r.Texts {
 			if strings.Contains(testName, t) {
-				return true
+				return t, true
 			}
 		}
-		return false
 	case "ends_with":
 		for _, t := range r.Texts {
 			if strings.HasSuffix(testName, t) {
-				return true
+				return t, true
 			}
 		}
-		return false
 	case "extension":
 		testExt := ext
 		if !r.CaseSensitive {
@@ -213,12 +652,22 @@ func matches(r CompiledRule, name, ext string) bool {
 		}
 		for _, t := range r.Texts {
 			if testExt == t {
-				return true
+				return t, true
+			}
+		}
+	case KindParent:
+		// name is the parent directory's name here
+		for _, t := range r.Texts {
+			if testName == t {
+				return t, true
+			}
+		}
+	case "regex", "glob":
+		for i, re := range r.Patterns {
+			if re.MatchString(name) {
+				return r.Texts[i], true
 			}
 		}
-		return false
-	case "regex":
-		return r.Regexp.MatchString(name)
 	}
-	return false
+	return "", false
 }
@@ -3,8 +3,11 @@ package classifier
 import (
 	"encoding/json"
 
+	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"kalycs/db"
 )
@@ -32,10 +35,10 @@ func TestCompileRule_RegexCaseSensitivity(t *testing.T) {
 		t.Fatalf("compileRule returned error: %v", err)
 	}
 
-	if !cr.Regexp.MatchString("foo123") {
+	if !cr.Patterns[0].MatchString("foo123") {
 		t.Error("case sensitive regex failed to match lowercase")
 	}
-	if cr.Regexp.MatchString("FOO123") {
+	if cr.Patterns[0].MatchString("FOO123") {
 		t.Error("case sensitive regex matched uppercase")
 	}
 
@@ -46,10 +49,10 @@ func TestCompileRule_RegexCaseSensitivity(t *testing.T) {
 		t.Fatalf("compileRule returned error: %v", err)
 	}
 
-	if !cr2.Regexp.MatchString("foo123") {
+	if !cr2.Patterns[0].MatchString("foo123") {
 		t.Error("case insensitive regex failed to match lowercase")
 	}
-	if !cr2.Regexp.MatchString("FOO123") {
+	if !cr2.Patterns[0].MatchString("FOO123") {
 		t.Error("case insensitive regex failed to match uppercase")
 	}
 }
@@ -116,6 +119,129 @@ func TestMatches_ExtensionCaseSensitive(t *testing.T) {
 	}
 }
 
+func TestMatches_MultipleRegexPatterns(t *testing.T) {
+	rule := db.Rule{
+		ID:        "re1",
+		ProjectID: "p1",
+		Rule:      "regex",
+		Texts:     mustJSON(t, []string{`^scan_\d+`, `^IMG_\d{4}\.`}),
+	}
+
+	cr, err := compileRule(rule)
+	if err != nil {
+		t.Fatalf("compileRule error: %v", err)
+	}
+	if text, ok := matchedName(cr, "img_0042.heic", "heic"); !ok || text != `^IMG_\d{4}\.` {
+		t.Errorf("matchedName() = %q, %v; want the second pattern", text, ok)
+	}
+	if text, ok := matchedName(cr, "scan_7.pdf", "pdf"); !ok || text != `^scan_\d+` {
+		t.Errorf("matchedName() = %q, %v; want the first pattern", text, ok)
+	}
+	if matches(cr, "photo.jpg", "jpg") {
+		t.Error("unexpected match for a name no pattern matches")
+	}
+}
+
+func TestMatches_Glob(t *testing.T) {
+	rule := db.Rule{
+		ID:        "glob1",
+		ProjectID: "p1",
+		Rule:      "glob",
+		Texts:     mustJSON(t, []string{"IMG_*.HEIC", "invoice-????-??.pdf"}),
+	}
+
+	cr, err := compileRule(rule)
+	if err != nil {
+		t.Fatalf("compileRule error: %v", err)
+	}
+
+	if text, ok := matchedName(cr, "img_0042.heic", "heic"); !ok || text != "IMG_*.HEIC" {
+		t.Errorf("matchedName() = %q, %v; want the first pattern regardless of case", text, ok)
+	}
+	if text, ok := matchedName(cr, "invoice-2024-03.pdf", "pdf"); !ok || text != "invoice-????-??.pdf" {
+		t.Errorf("matchedName() = %q, %v; want the second pattern", text, ok)
+	}
+	if matches(cr, "invoice-2024-3.pdf", "pdf") {
+		t.Error("unexpected match for a name one character short")
+	}
+
+	rule.CaseSensitive = true
+	cr, err = compileRule(rule)
+	if err != nil {
+		t.Fatalf("compileRule error: %v", err)
+	}
+	if matches(cr, "img_0042.heic", "heic") {
+		t.Error("case-sensitive glob matched a lower case name")
+	}
+}
+
+func TestMatchesFile_SizeAgeModified(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	installer := candidate{name: "setup.exe", ext: "exe", size: 150 << 20, mtime: now.Add(-time.Hour), now: now}
+	screenshot := candidate{name: "Screenshot.png", ext: "png", size: 300 << 10, mtime: now.AddDate(0, 0, -45), now: now}
+
+	tests := []struct {
+		name  string
+		kind  string
+		texts []string
+		want  map[string]bool
+	}{
+		{"larger than 100MB", "size", []string{">100MB"}, map[string]bool{"installer": true, "screenshot": false}},
+		{"size ranges are OR-ed", "size", []string{"<1KB", "100KB..1MB"}, map[string]bool{"installer": false, "screenshot": true}},
+		{"older than 30 days", "age", []string{">30d"}, map[string]bool{"installer": false, "screenshot": true}},
+		{"modified this month", "modified", []string{">=2024-06-01"}, map[string]bool{"installer": true, "screenshot": false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr, err := compileRule(db.Rule{ID: "r", ProjectID: "p", Rule: tt.kind, Texts: mustJSON(t, tt.texts)})
+			if err != nil {
+				t.Fatalf("compileRule error: %v", err)
+			}
+			for label, c := range map[string]candidate{"installer": installer, "screenshot": screenshot} {
+				if got := matchesFile(cr, c); got != tt.want[label] {
+					t.Errorf("matchesFile(%s) = %v, want %v", label, got, tt.want[label])
+				}
+			}
+		})
+	}
+
+	if _, err := compileRule(db.Rule{ID: "r", ProjectID: "p", Rule: "size", Texts: mustJSON(t, []string{"big"})}); err == nil {
+		t.Error("compileRule accepted an invalid size expression")
+	}
+}
+
+func TestMatchesFile_Composite(t *testing.T) {
+	conditions := `{"op": "and", "children": [
+		{"op": "match", "kind": "extension", "texts": ["pdf"]},
+		{"op": "match", "kind": "contains", "texts": ["invoice"]},
+		{"op": "not", "children": [{"op": "match", "kind": "starts_with", "texts": ["draft"]}]}
+	]}`
+	cr, err := compileRule(db.Rule{ID: "r", ProjectID: "p", Rule: "composite", Texts: "[]", Conditions: conditions})
+	if err != nil {
+		t.Fatalf("compileRule error: %v", err)
+	}
+
+	tests := map[string]bool{
+		"Invoice-2024.pdf":       true,
+		"acme_invoice.PDF":       true,
+		"draft-invoice.pdf":      false,
+		"invoice.docx":           false,
+		"receipt.pdf":            false,
+		"DRAFT_acme_invoice.pdf": false,
+	}
+	for name, want := range tests {
+		ext := strings.TrimPrefix(filepath.Ext(name), ".")
+		if got := matchesFile(cr, candidate{name: name, ext: ext}); got != want {
+			t.Errorf("matchesFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	if _, err := compileRule(db.Rule{ID: "r", ProjectID: "p", Rule: "composite", Conditions: `{"op": "not", "children": []}`}); err == nil {
+		t.Error("compileRule accepted a not condition without a child")
+	}
+}
+
 func TestPriorityBehavior(t *testing.T) {
 	r1 := db.Rule{
 		ID:            "1",
@@ -123,6 +249,7 @@ func TestPriorityBehavior(t *testing.T) {
 		Rule:          "starts_with",
 		Texts:         mustJSON(t, []string{"report"}),
 		CaseSensitive: false,
+		Priority:      1,
 	}
 	r2 := db.Rule{
 		ID:            "2",
@@ -130,19 +257,18 @@ func TestPriorityBehavior(t *testing.T) {
 		Rule:          "starts_with",
 		Texts:         mustJSON(t, []string{"rep"}),
 		CaseSensitive: false,
+		Priority:      0,
 	}
 
 	cr1, err := compileRule(r1)
 	if err != nil {
 		t.Fatalf("compileRule error: %v", err)
 	}
-	cr1.Priority = 1
 
 	cr2, err := compileRule(r2)
 	if err != nil {
 		t.Fatalf("compileRule error: %v", err)
 	}
-	cr2.Priority = 0
 
 	rules := []CompiledRule{cr1, cr2}
 	sort.Slice(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
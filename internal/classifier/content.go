@@ -0,0 +1,120 @@
+package classifier
+
+import (
+	"context"
+	"errors"
+	"kalycs/internal/condition"
+	"kalycs/internal/extract"
+	"kalycs/internal/logging"
+	"strings"
+	"sync"
+)
+
+// Rule kinds that search the text inside a file. Extracting text is far more
+// expensive than looking at names, so these rules are only tried once every
+// other rule has failed to match.
+const (
+	KindContent      = "content"       // keywords, any of which may appear in the text
+	KindContentRegex = "content_regex" // regular expressions, any of which may match the text
+)
+
+// contentText extracts a file's text the first time a content rule asks for it
+type contentText struct {
+	ctx  context.Context
+	path string
+	mime string
+
+	once  sync.Once
+	text  string
+	lower string
+	ok    bool
+}
+
+func newContentText(ctx context.Context, path, mime string) *contentText {
+	return &contentText{ctx: ctx, path: path, mime: mime}
+}
+
+// get returns the file's text, lowercased as well for case-insensitive
+// keywords; ok is false when no text could be extracted
+func (t *contentText) get() (text, lower string, ok bool) {
+	if t == nil {
+		return "", "", false
+	}
+	t.once.Do(func() {
+		if !extract.Supported(t.path, t.mime) {
+			return
+		}
+		text, err := extract.Text(t.ctx, t.path, t.mime, extract.DefaultLimits)
+		if err != nil {
+			if !errors.Is(err, extract.ErrUnsupported) {
+				logging.L().Warnw("Failed to extract file text for content rules", "file_path", t.path, "error", err)
+			}
+			return
+		}
+		t.text, t.lower, t.ok = text, strings.ToLower(text), true
+	})
+	return t.text, t.lower, t.ok
+}
+
+// matchedContent evaluates a content rule against the file's text
+func matchedContent(r CompiledRule, c candidate) (string, bool) {
+	text, lower, ok := c.content.get()
+	if !ok {
+		return "", false
+	}
+	switch r.Kind {
+	case KindContent:
+		if !r.CaseSensitive {
+			text = lower
+		}
+		for _, t := range r.Texts {
+			if strings.Contains(text, t) {
+				return t, true
+			}
+		}
+	case KindContentRegex:
+		for i, re := range r.Patterns {
+			if re.MatchString(text) {
+				return r.Texts[i], true
+			}
+		}
+	}
+	return "", false
+}
+
+// readsContent reports whether evaluating r may need the file's text
+func readsContent(r CompiledRule) bool {
+	if r.Kind == condition.KindComposite {
+		return r.Condition.readsContent()
+	}
+	return r.Kind == KindContent || r.Kind == KindContentRegex
+}
+
+func (cc CompiledCondition) readsContent() bool {
+	if cc.Op == condition.OpMatch {
+		return readsContent(cc.Match)
+	}
+	for _, child := range cc.Children {
+		if child.readsContent() {
+			return true
+		}
+	}
+	return false
+}
+
+// firstMatch returns the rule that captures the file and the text that
+// matched. Rules that read the file's text are tried only after every other
+// rule, each group in priority order.
+func firstMatch(rules []CompiledRule, cand candidate) (CompiledRule, string, bool) {
+	for _, content := range []bool{false, true} {
+		for _, r := range rules {
+			if readsContent(r) != content {
+				continue
+			}
+			if text, ok := matchedText(r, cand); ok {
+				return r, text, true
+			}
+		}
+	}
+	return CompiledRule{}, "", false
+}
@@ -0,0 +1,77 @@
+package classifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kalycs/db"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+const invoicePDF = "%PDF-1.4\n4 0 obj\n<< /Length 44 >>\nstream\nBT /F1 12 Tf 72 700 Td (INVOICE No. 42) Tj ET\nendstream\nendobj\n%%EOF\n"
+
+func TestClassify_ContentRules(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	// Invoices is created last, so its content rule comes first by priority
+	downloads := &db.Project{Name: "Downloads", IsActive: true}
+	invoices := &db.Project{Name: "Invoices", IsActive: true}
+	for _, p := range []*db.Project{downloads, invoices} {
+		if err := s.Project.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create project: %v", err)
+		}
+	}
+	rules := []*db.Rule{
+		{Name: "Invoice text", ProjectID: invoices.ID, Rule: KindContent, Texts: mustJSON(t, []string{"invoice no"})},
+		{Name: "Amounts", ProjectID: invoices.ID, Rule: KindContentRegex, Texts: mustJSON(t, []string{`total: \d+ EUR`})},
+		{Name: "Renamed downloads", ProjectID: downloads.ID, Rule: "starts_with", Texts: mustJSON(t, []string{"download ("})},
+	}
+	for _, r := range rules {
+		if err := s.Rule.Create(ctx, r); err != nil {
+			t.Fatalf("failed to create rule: %v", err)
+		}
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"download (3).pdf": invoicePDF,             // a name rule still wins over content rules
+		"scan.pdf":         invoicePDF,             // keyword found in the PDF text
+		"export.csv":       "item,total: 12 EUR\n", // regex found in plain text
+		"photo.jpg":        "\xFF\xD8\xFF\xE0 invoice no",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		info, _ := os.Stat(path)
+		if err := c.Classify(ctx, path, info); err != nil {
+			t.Fatalf("Classify(%s) error = %v", name, err)
+		}
+	}
+
+	want := map[string]struct{ project, kind, text string }{
+		"download (3).pdf": {downloads.ID, "starts_with", "download ("},
+		"scan.pdf":         {invoices.ID, KindContent, "invoice no"},
+		"export.csv":       {invoices.ID, KindContentRegex, `total: \d+ EUR`},
+		"photo.jpg":        {c.incomingProjectID, "", ""},
+	}
+	for name, w := range want {
+		f, _ := s.File.GetByPath(ctx, filepath.Join(dir, name))
+		if f.ProjectID.String != w.project || f.MatchKind != w.kind || f.MatchText != w.text {
+			t.Errorf("%s = project %s, %s %q; want project %s, %s %q", name, f.ProjectID.String, f.MatchKind, f.MatchText, w.project, w.kind, w.text)
+		}
+	}
+}
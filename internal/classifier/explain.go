@@ -0,0 +1,86 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"kalycs/db"
+	"kalycs/internal/condition"
+)
+
+// Explanation answers why a file is in its project
+type Explanation struct {
+	FileID      string `json:"file_id"`
+	Path        string `json:"path"`
+	ProjectID   string `json:"project_id"`
+	ProjectName string `json:"project_name"`
+	Source      string `json:"source"`  // rule, incoming or manual
+	RuleID      string `json:"rule_id"` // empty when the rule has since been deleted
+	RuleName    string `json:"rule_name"`
+	MatchKind   string `json:"match_kind"`
+	MatchText   string `json:"match_text"`
+	Summary     string `json:"summary"` // one sentence for display
+}
+
+// Explain describes how a file came to be in its project
+func (c *Classifier) Explain(ctx context.Context, fileID string) (Explanation, error) {
+	f, err := c.store.File.GetByID(ctx, fileID)
+	if err != nil {
+		return Explanation{}, err
+	}
+	if f == nil {
+		return Explanation{}, fmt.Errorf("file with ID '%s' not found", fileID)
+	}
+
+	e := Explanation{
+		FileID:    f.ID,
+		Path:      f.Path,
+		ProjectID: f.ProjectID.String,
+		Source:    f.AssignmentSource,
+		RuleID:    f.RuleID.String,
+		MatchKind: f.MatchKind,
+		MatchText: f.MatchText,
+	}
+	if f.ProjectID.Valid {
+		p, err := c.store.Project.GetByID(ctx, f.ProjectID.String)
+		if err != nil {
+			return Explanation{}, err
+		}
+		e.ProjectName = p.Name
+	}
+	if f.RuleID.Valid {
+		r, err := c.store.Rule.GetByID(ctx, f.RuleID.String)
+		if err != nil {
+			return Explanation{}, err
+		}
+		if r != nil {
+			e.RuleName = r.Name
+		}
+	}
+	e.Summary = summarize(e)
+	return e, nil
+}
+
+func summarize(e Explanation) string {
+	switch {
+	case e.ProjectID == "":
+		return "The file's project was deleted, so it is not in any project."
+	case e.Source == db.AssignedManually:
+		return fmt.Sprintf("Assigned to %q by hand.", e.ProjectName)
+	case e.Source == db.AssignedIncoming:
+		return fmt.Sprintf("No rule matched, so the file went to %q.", e.ProjectName)
+	}
+
+	match := fmt.Sprintf("%s %q", e.MatchKind, e.MatchText)
+	if e.MatchKind == condition.KindComposite {
+		match = e.MatchText
+	}
+	switch {
+	case e.MatchKind == "" && e.RuleName == "":
+		return fmt.Sprintf("Assigned to %q by a rule.", e.ProjectName)
+	case e.MatchKind == "":
+		return fmt.Sprintf("Rule %q assigned the file to %q.", e.RuleName, e.ProjectName)
+	case e.RuleName == "":
+		return fmt.Sprintf("Matched %s by a rule that has since been deleted, so the file went to %q.", match, e.ProjectName)
+	}
+	return fmt.Sprintf("Rule %q matched %s, so the file went to %q.", e.RuleName, match, e.ProjectName)
+}
@@ -0,0 +1,97 @@
+package classifier
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"kalycs/db"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+func TestMatchedText_Composite(t *testing.T) {
+	r := db.Rule{ID: "r", ProjectID: "p", Rule: "composite", Conditions: `{"op": "and", "children": [
+		{"op": "match", "kind": "extension", "texts": ["pdf"]},
+		{"op": "or", "children": [
+			{"op": "match", "kind": "contains", "texts": ["invoice", "receipt"]},
+			{"op": "match", "kind": "size", "texts": [">1MB"]}
+		]},
+		{"op": "not", "children": [{"op": "match", "kind": "starts_with", "texts": ["draft", "tmp"]}]}
+	]}`}
+	cr, err := compileRule(r)
+	if err != nil {
+		t.Fatalf("compileRule error: %v", err)
+	}
+
+	got, ok := matchedText(cr, candidate{name: "receipt-42.pdf", ext: "pdf"})
+	want := `extension "pdf" and contains "receipt" and not (starts_with "draft" or "tmp")`
+	if !ok || got != want {
+		t.Errorf("matchedText() = %q, %v; want %q", got, ok, want)
+	}
+	if _, ok := matchedText(cr, candidate{name: "draft-receipt.pdf", ext: "pdf"}); ok {
+		t.Error("matchedText() matched a file excluded by the not condition")
+	}
+}
+
+func TestExplain(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	project := &db.Project{Name: "Documents", IsActive: true}
+	if err := s.Project.Create(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	rule := &db.Rule{Name: "PDFs", ProjectID: project.ID, Rule: "extension", Texts: mustJSON(t, []string{"doc", "PDF"})}
+	if err := s.Rule.Create(ctx, rule); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+
+	dir := t.TempDir()
+	classifyNewFile(t, ctx, c, filepath.Join(dir, "report.pdf"))
+	classifyNewFile(t, ctx, c, filepath.Join(dir, "photo.jpg"))
+	pdf, _ := s.File.GetByPath(ctx, filepath.Join(dir, "report.pdf"))
+	jpg, _ := s.File.GetByPath(ctx, filepath.Join(dir, "photo.jpg"))
+
+	e, err := c.Explain(ctx, pdf.ID)
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	if e.RuleID != rule.ID || e.RuleName != "PDFs" || e.MatchKind != "extension" || e.MatchText != "pdf" {
+		t.Errorf("Explain() = %+v, want the PDFs rule matching pdf", e)
+	}
+	if want := `Rule "PDFs" matched extension "pdf", so the file went to "Documents".`; e.Summary != want {
+		t.Errorf("Summary = %q, want %q", e.Summary, want)
+	}
+
+	e, err = c.Explain(ctx, jpg.ID)
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	if e.Source != db.AssignedIncoming || e.Summary != `No rule matched, so the file went to "Incoming".` {
+		t.Errorf("Explain() = %+v, want an incoming explanation", e)
+	}
+
+	if err := s.Rule.Delete(ctx, rule.ID); err != nil {
+		t.Fatalf("failed to delete rule: %v", err)
+	}
+	e, err = c.Explain(ctx, pdf.ID)
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	if e.RuleID != "" || e.MatchText != "pdf" {
+		t.Errorf("Explain() after rule deletion = %+v, want the match kept without the rule", e)
+	}
+
+	if _, err := c.Explain(ctx, "missing-id"); err == nil {
+		t.Error("Explain() of unknown file should fail")
+	}
+}
@@ -0,0 +1,108 @@
+package classifier
+
+import (
+	"context"
+	"kalycs/db"
+	"kalycs/internal/condition"
+	"kalycs/internal/logging"
+	"kalycs/internal/metadata"
+	"os"
+)
+
+// KindMetadata is the rule kind testing fields read from a file's embedded
+// metadata, e.g. "camera_model = iPhone 15 Pro" or "gps = true"
+const KindMetadata = "metadata"
+
+// SetMetadataExtractors replaces the extractors Classify reads metadata
+// fields with; the built-in EXIF and PDF extractors are used by default
+func (c *Classifier) SetMetadataExtractors(extractors ...metadata.Extractor) {
+	c.extractors = extractors
+}
+
+// readMetadata runs the extractors against the file at path
+func (c *Classifier) readMetadata(ctx context.Context, path, mime string) map[string]string {
+	fields, err := metadata.Extract(ctx, path, mime, c.extractors)
+	if err != nil {
+		logging.L().Warnw("Failed to read file metadata", "file_path", path, "error", err)
+	}
+	return fields
+}
+
+// detectMetadata returns the metadata fields of the file at path. The fields
+// stored for the file's row are reused while its size and modification time
+// are unchanged.
+func (c *Classifier) detectMetadata(ctx context.Context, existing *db.File, path, mime string, meta os.FileInfo) map[string]string {
+	if unchanged(existing, meta) {
+		stored, err := c.store.Metadata.Get(ctx, existing.ID)
+		if err != nil {
+			logging.L().Warnw("Failed to load stored file metadata", "file_id", existing.ID, "error", err)
+		} else if stored != nil {
+			return stored
+		}
+	}
+	return c.readMetadata(ctx, path, mime)
+}
+
+// unchanged reports whether the indexed file f still has the size and
+// modification time of meta
+func unchanged(f *db.File, meta os.FileInfo) bool {
+	return f != nil && f.Size == meta.Size() && f.Mtime.Equal(meta.ModTime())
+}
+
+// ensureMetadata returns the metadata fields of an indexed file from stored,
+// the fields of every file by ID. A file without stored fields, such as one
+// indexed before metadata was recorded, is read and, when persist is set,
+// its fields stored.
+func (c *Classifier) ensureMetadata(ctx context.Context, f db.File, stored map[string]map[string]string, persist bool) map[string]string {
+	if fields, ok := stored[f.ID]; ok {
+		return fields
+	}
+	fields := c.readMetadata(ctx, f.Path, f.Mime)
+	if persist && len(fields) > 0 {
+		if err := c.store.Metadata.Replace(ctx, f.ID, fields); err != nil {
+			logging.L().Warnw("Failed to store file metadata", "file_id", f.ID, "error", err)
+		}
+	}
+	return fields
+}
+
+// usesMetadata reports whether any of rules may need a file's metadata
+// fields; files are only read for them when one does
+func usesMetadata(rules []CompiledRule) bool {
+	for _, r := range rules {
+		if readsMetadata(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// readsMetadata reports whether evaluating r may need the file's metadata fields
+func readsMetadata(r CompiledRule) bool {
+	if r.Kind == condition.KindComposite {
+		return r.Condition.readsMetadata()
+	}
+	return r.Kind == KindMetadata
+}
+
+func (cc CompiledCondition) readsMetadata() bool {
+	if cc.Op == condition.OpMatch {
+		return readsMetadata(cc.Match)
+	}
+	for _, child := range cc.Children {
+		if child.readsMetadata() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchedMetadata returns the condition that the file's fields satisfy
+func matchedMetadata(r CompiledRule, fields map[string]string) (string, bool) {
+	for i, cond := range r.Fields {
+		if cond.Match(fields, r.CaseSensitive) {
+			return r.Texts[i], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,175 @@
+package classifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kalycs/db"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+func TestMatchedMetadata(t *testing.T) {
+	cr, err := compileRule(db.Rule{ID: "r", ProjectID: "p", Rule: KindMetadata, Texts: mustJSON(t, []string{"camera_model = x100v", "date_taken >= 2024-01-01"})})
+	if err != nil {
+		t.Fatalf("compileRule error: %v", err)
+	}
+	tests := []struct {
+		fields map[string]string
+		want   string
+	}{
+		{map[string]string{"camera_model": "X100V", "date_taken": "2020-05-01T10:00:00"}, "camera_model = x100v"},
+		{map[string]string{"camera_model": "Pixel 8", "date_taken": "2024-02-03T04:05:06"}, "date_taken >= 2024-01-01"},
+		{map[string]string{"camera_model": "Pixel 8"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		got, ok := matchedText(cr, candidate{fields: tt.fields})
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("matchedText(%v) = %q, %v; want %q", tt.fields, got, ok, tt.want)
+		}
+	}
+}
+
+// pathExtractor returns fixed fields for the files it knows
+type pathExtractor map[string]map[string]string
+
+func (e pathExtractor) Supports(mime string) bool { return true }
+
+func (e pathExtractor) Extract(_ context.Context, path string) (map[string]string, error) {
+	return e[path], nil
+}
+
+func TestClassify_MetadataRule(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	project := &db.Project{Name: "Travel photos", IsActive: true}
+	if err := s.Project.Create(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	rule := &db.Rule{Name: "Geotagged", ProjectID: project.ID, Rule: KindMetadata, Texts: mustJSON(t, []string{"gps = true"})}
+	if err := s.Rule.Create(ctx, rule); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+
+	dir := t.TempDir()
+	tagged := filepath.Join(dir, "IMG_0001.jpg")
+	plain := filepath.Join(dir, "IMG_0002.jpg")
+	extractor := pathExtractor{
+		tagged: {"camera_model": "iPhone 15 Pro", "gps": "true"},
+		plain:  {"camera_model": "iPhone 15 Pro", "gps": "false"},
+	}
+	c.SetMetadataExtractors(extractor)
+	for _, path := range []string{tagged, plain} {
+		if err := os.WriteFile(path, []byte("\xFF\xD8\xFF"), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		info, _ := os.Stat(path)
+		if err := c.Classify(ctx, path, info); err != nil {
+			t.Fatalf("Classify() error = %v", err)
+		}
+	}
+
+	f, _ := s.File.GetByPath(ctx, tagged)
+	if f.ProjectID.String != project.ID || f.MatchKind != KindMetadata || f.MatchText != "gps = true" {
+		t.Errorf("geotagged photo = %+v, want it captured by the metadata rule", f)
+	}
+	if fields, _ := s.Metadata.Get(ctx, f.ID); fields["camera_model"] != "iPhone 15 Pro" {
+		t.Errorf("stored metadata = %v, want the extracted fields", fields)
+	}
+	f, _ = s.File.GetByPath(ctx, plain)
+	if f.ProjectID.String == project.ID {
+		t.Errorf("photo without GPS = %+v, want it outside the project", f)
+	}
+
+	// Files indexed without metadata get it read when reclassified
+	if err := s.Metadata.Replace(ctx, f.ID, nil); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+	extractor[plain] = map[string]string{"gps": "true"}
+	result, err := c.Reclassify(ctx, ReclassifyScope{Kind: ScopeAll}, false)
+	if err != nil {
+		t.Fatalf("Reclassify() error = %v", err)
+	}
+	if len(result.Moves) != 1 || result.Moves[0].FileID != f.ID {
+		t.Errorf("Reclassify() moves = %+v, want the photo now tagged", result.Moves)
+	}
+	if fields, _ := s.Metadata.Get(ctx, f.ID); fields["gps"] != "true" {
+		t.Errorf("stored metadata = %v, want it read again", fields)
+	}
+}
+
+// countingExtractor counts the files it is asked to read
+type countingExtractor struct{ calls int }
+
+func (e *countingExtractor) Supports(mime string) bool { return true }
+
+func (e *countingExtractor) Extract(context.Context, string) (map[string]string, error) {
+	e.calls++
+	return map[string]string{"gps": "true"}, nil
+}
+
+func TestClassify_MetadataReadOnlyForMetadataRules(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+	project := &db.Project{Name: "Photos", IsActive: true}
+	if err := s.Project.Create(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	rule := &db.Rule{Name: "JPEGs", ProjectID: project.ID, Rule: "extension", Texts: mustJSON(t, []string{"jpg"})}
+	if err := s.Rule.Create(ctx, rule); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	extractor := &countingExtractor{}
+	c.SetMetadataExtractors(extractor)
+
+	dir := t.TempDir()
+	classifyNewFile(t, ctx, c, filepath.Join(dir, "IMG_0001.jpg"))
+	if _, err := c.Reclassify(ctx, ReclassifyScope{Kind: ScopeAll}, true); err != nil {
+		t.Fatalf("Reclassify() error = %v", err)
+	}
+	if extractor.calls != 0 {
+		t.Errorf("extractor called %d times without a metadata rule, want 0", extractor.calls)
+	}
+
+	// A metadata condition inside a composite rule needs the fields
+	rule.Rule = "composite"
+	rule.Texts = "[]"
+	rule.Conditions = `{"op": "and", "children": [
+		{"op": "match", "kind": "extension", "texts": ["jpg"]},
+		{"op": "not", "children": [{"op": "match", "kind": "metadata", "texts": ["gps = true"]}]}
+	]}`
+	if err := s.Rule.Update(ctx, rule); err != nil {
+		t.Fatalf("failed to update rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	classifyNewFile(t, ctx, c, filepath.Join(dir, "IMG_0002.jpg"))
+	if extractor.calls != 1 {
+		t.Errorf("extractor called %d times with a metadata condition, want 1", extractor.calls)
+	}
+	f, err := s.File.GetByPath(ctx, filepath.Join(dir, "IMG_0002.jpg"))
+	if err != nil || f == nil || f.ProjectID.String == project.ID {
+		t.Errorf("geotagged photo = %+v, %v; want it kept out by the composite rule", f, err)
+	}
+}
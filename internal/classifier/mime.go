@@ -0,0 +1,65 @@
+package classifier
+
+import (
+	"context"
+	"kalycs/db"
+	"kalycs/internal/logging"
+	"kalycs/internal/sniff"
+	"os"
+	"strings"
+)
+
+// KindMime is the rule kind matching the content type sniffed from a file,
+// e.g. "application/pdf" or "image/*"
+const KindMime = "mime"
+
+// detectMime returns the content type of the file at path. The type cached on
+// the file's row is reused while its size and modification time are unchanged.
+func detectMime(existing *db.File, path string, meta os.FileInfo) string {
+	if existing != nil && existing.Mime != "" && existing.Size == meta.Size() && existing.Mtime.Equal(meta.ModTime()) {
+		return existing.Mime
+	}
+	mime, err := sniff.Detect(path)
+	if err != nil {
+		logging.L().Warnw("Failed to detect content type", "file_path", path, "error", err)
+		return ""
+	}
+	return mime
+}
+
+// ensureMime detects the content type of an indexed file that was stored
+// before content types were recorded, caching it when persist is set
+func (c *Classifier) ensureMime(ctx context.Context, f *db.File, persist bool) {
+	if f.Mime != "" {
+		return
+	}
+	mime, err := sniff.Detect(f.Path)
+	if err != nil {
+		return
+	}
+	f.Mime = mime
+	if !persist {
+		return
+	}
+	if err := c.store.File.SetMime(ctx, f.ID, mime); err != nil {
+		logging.L().Warnw("Failed to cache content type", "file_id", f.ID, "error", err)
+	}
+}
+
+// matchedMime returns the type pattern that matches mime: an exact type, or
+// "type/*" for any subtype
+func matchedMime(r CompiledRule, mime string) (string, bool) {
+	if mime == "" {
+		return "", false
+	}
+	mime = strings.ToLower(mime)
+	for _, t := range r.Texts {
+		if t == mime || t == "*/*" {
+			return t, true
+		}
+		if major, ok := strings.CutSuffix(t, "/*"); ok && strings.HasPrefix(mime, major+"/") {
+			return t, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,90 @@
+package classifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kalycs/db"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+func TestMatchedMime(t *testing.T) {
+	cr, err := compileRule(db.Rule{ID: "r", ProjectID: "p", Rule: KindMime, Texts: mustJSON(t, []string{"application/pdf", "image/*"})})
+	if err != nil {
+		t.Fatalf("compileRule error: %v", err)
+	}
+	tests := map[string]string{
+		"application/pdf": "application/pdf",
+		"image/png":       "image/*",
+		"IMAGE/HEIC":      "image/*",
+		"video/mp4":       "",
+		"imagery/x":       "",
+		"":                "",
+	}
+	for mime, want := range tests {
+		got, ok := matchedText(cr, candidate{mime: mime})
+		if got != want || ok != (want != "") {
+			t.Errorf("matchedText(%q) = %q, %v; want %q", mime, got, ok, want)
+		}
+	}
+}
+
+func TestClassify_MimeRuleIgnoresExtension(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	project := &db.Project{Name: "Documents", IsActive: true}
+	if err := s.Project.Create(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	rule := &db.Rule{Name: "PDFs", ProjectID: project.ID, Rule: KindMime, Texts: mustJSON(t, []string{"Application/PDF"})}
+	if err := s.Rule.Create(ctx, rule); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+
+	// A PDF without an extension, and a text file pretending to be a PDF
+	dir := t.TempDir()
+	pdf := filepath.Join(dir, "scan")
+	fake := filepath.Join(dir, "notes.pdf")
+	for path, content := range map[string]string{pdf: "%PDF-1.4\n", fake: "plain text\n"} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		info, _ := os.Stat(path)
+		if err := c.Classify(ctx, path, info); err != nil {
+			t.Fatalf("Classify() error = %v", err)
+		}
+	}
+
+	f, _ := s.File.GetByPath(ctx, pdf)
+	if f.Mime != "application/pdf" || f.ProjectID.String != project.ID || f.MatchText != "application/pdf" {
+		t.Errorf("extensionless PDF = %+v, want application/pdf in Documents", f)
+	}
+	f, _ = s.File.GetByPath(ctx, fake)
+	if f.Mime != "text/plain" || f.ProjectID.String == project.ID {
+		t.Errorf("fake PDF = %+v, want text/plain outside Documents", f)
+	}
+
+	// Rows stored before detection get their type when reclassified
+	if err := s.File.SetMime(ctx, f.ID, ""); err != nil {
+		t.Fatalf("SetMime() error = %v", err)
+	}
+	if _, err := c.Reclassify(ctx, ReclassifyScope{Kind: ScopeAll}, false); err != nil {
+		t.Fatalf("Reclassify() error = %v", err)
+	}
+	f, _ = s.File.GetByPath(ctx, fake)
+	if f.Mime != "text/plain" {
+		t.Errorf("mime after reclassify = %q, want text/plain", f.Mime)
+	}
+}
@@ -0,0 +1,82 @@
+package classifier
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Rule kinds that look at where a file lives rather than its name
+const (
+	KindPath   = "path"   // glob over the path relative to the watched root, e.g. "Slack/**"
+	KindParent = "parent" // name of the directory holding the file, e.g. "receipts"
+)
+
+type rootKey struct{}
+
+// WithRoot returns a context whose classifications take paths relative to
+// root, the watched or imported directory the files were found under
+func WithRoot(ctx context.Context, root string) context.Context {
+	return context.WithValue(ctx, rootKey{}, filepath.Clean(root))
+}
+
+func rootFrom(ctx context.Context) string {
+	root, _ := ctx.Value(rootKey{}).(string)
+	return root
+}
+
+// watchRoots returns the directories paths can be relative to: the root
+// carried by ctx, if any, followed by the configured watch roots, innermost
+// first so a file in nested roots is relative to the closest one
+func (c *Classifier) watchRoots(ctx context.Context) ([]string, error) {
+	configured, err := c.store.Watch.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roots := make([]string, 0, len(configured)+1)
+	for _, r := range configured {
+		roots = append(roots, filepath.Clean(r.Path))
+	}
+	sort.SliceStable(roots, func(i, j int) bool { return len(roots[i]) > len(roots[j]) })
+	if root := rootFrom(ctx); root != "" {
+		roots = append([]string{root}, roots...)
+	}
+	return roots, nil
+}
+
+// relativePath returns path relative to the first of roots that contains it,
+// using forward slashes. A file outside every root, such as one an action
+// moved into a project's destination directory, is relative to its own directory.
+func relativePath(roots []string, path string) string {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(path)
+}
+
+// parentName returns the name of the directory holding path
+func parentName(path string) string {
+	return filepath.Base(filepath.Dir(path))
+}
+
+// matchedPath returns the path pattern that matches rel or one of the
+// directories leading to it, so "Slack" matches everything under Slack
+func matchedPath(r CompiledRule, rel string) (string, bool) {
+	for i, re := range r.Patterns {
+		for p := rel; ; {
+			if re.MatchString(p) {
+				return r.Texts[i], true
+			}
+			slash := strings.LastIndexByte(p, '/')
+			if slash < 0 {
+				break
+			}
+			p = p[:slash]
+		}
+	}
+	return "", false
+}
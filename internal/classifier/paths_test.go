@@ -0,0 +1,95 @@
+package classifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kalycs/db"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+func TestRelativePath(t *testing.T) {
+	roots := []string{"/home/u/Downloads/Slack", "/home/u/Downloads"}
+	tests := map[string]string{
+		"/home/u/Downloads/Slack/a.pdf":         "a.pdf",
+		"/home/u/Downloads/receipts/2024/b.pdf": "receipts/2024/b.pdf",
+		"/home/u/Downloads/c.pdf":               "c.pdf",
+		"/home/u/Documents/d.pdf":               "d.pdf",
+		"/home/u/Downloads-old/e.pdf":           "e.pdf",
+	}
+	for path, want := range tests {
+		if got := relativePath(roots, filepath.FromSlash(path)); got != want {
+			t.Errorf("relativePath(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMatchesFile_PathAndParent(t *testing.T) {
+	tests := []struct {
+		kind  string
+		texts []string
+		want  map[string]bool // root-relative path -> match
+	}{
+		{KindPath, []string{"Slack"}, map[string]bool{"Slack/a.pdf": true, "Slack/team/b.pdf": true, "slack/c.pdf": true, "Slackware/d.pdf": false, "a.pdf": false}},
+		{KindPath, []string{"Slack/*.pdf"}, map[string]bool{"Slack/a.pdf": true, "Slack/team/b.pdf": false}},
+		{KindPath, []string{"**/receipts"}, map[string]bool{"2024/receipts/a.pdf": true, "receipts/a.pdf": false, "2024/a.pdf": false}},
+		{KindParent, []string{"Receipts"}, map[string]bool{"2024/receipts/a.pdf": true, "receipts/2024/a.pdf": false}},
+	}
+	for _, tt := range tests {
+		cr, err := compileRule(db.Rule{ID: "r", ProjectID: "p", Rule: tt.kind, Texts: mustJSON(t, tt.texts)})
+		if err != nil {
+			t.Fatalf("compileRule error: %v", err)
+		}
+		for rel, want := range tt.want {
+			path := filepath.Join("/root", filepath.FromSlash(rel))
+			cand := candidate{name: filepath.Base(path), rel: rel, parent: parentName(path)}
+			if got := matchesFile(cr, cand); got != want {
+				t.Errorf("%s %v on %s = %v, want %v", tt.kind, tt.texts, rel, got, want)
+			}
+		}
+	}
+}
+
+func TestClassify_PathRuleRelativeToRoot(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	project := &db.Project{Name: "Slack", IsActive: true}
+	if err := s.Project.Create(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	rule := &db.Rule{Name: "From Slack", ProjectID: project.ID, Rule: KindPath, Texts: mustJSON(t, []string{"/Slack/"})}
+	if err := s.Rule.Create(ctx, rule); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "Slack", "team"), 0700); err != nil {
+		t.Fatalf("failed to create directories: %v", err)
+	}
+	inside := filepath.Join(root, "Slack", "team", "notes.txt")
+	outside := filepath.Join(root, "notes.txt")
+	rootCtx := WithRoot(ctx, root)
+	classifyNewFile(t, rootCtx, c, inside)
+	classifyNewFile(t, rootCtx, c, outside)
+
+	f, _ := s.File.GetByPath(ctx, inside)
+	if f.ProjectID.String != project.ID || f.MatchKind != KindPath || f.MatchText != "Slack" {
+		t.Errorf("file under Slack = %+v, want the Slack project via path \"Slack\"", f)
+	}
+	f, _ = s.File.GetByPath(ctx, outside)
+	if f.ProjectID.String == project.ID {
+		t.Error("file outside Slack was assigned to the Slack project")
+	}
+}
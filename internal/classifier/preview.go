@@ -0,0 +1,282 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"kalycs/db"
+	"kalycs/internal/fileops"
+	"kalycs/internal/validation"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// previewRuleID stands in for the ID of a rule that has not been saved yet
+const previewRuleID = "preview"
+
+// PreviewFile is a file matched by a previewed rule
+type PreviewFile struct {
+	FileID           string    `json:"file_id"` // empty for files that are not indexed yet
+	Path             string    `json:"path"`
+	Name             string    `json:"name"`
+	Size             int64     `json:"size"`
+	Mtime            time.Time `json:"mtime"`
+	CurrentProjectID string    `json:"current_project_id"` // empty for files that are not indexed yet
+	RuleID           string    `json:"rule_id,omitempty"`  // for shadowed files, the earlier rule that captures the file
+	NewName          string    `json:"new_name,omitempty"` // for captured files, the name the rule's rename template gives them
+}
+
+// PreviewGroup holds the captured files currently assigned to one project
+type PreviewGroup struct {
+	ProjectID   string        `json:"project_id"` // empty for files that are not indexed yet
+	ProjectName string        `json:"project_name"`
+	Stolen      bool          `json:"stolen"` // the files would leave another project for the rule's project
+	Files       []PreviewFile `json:"files"`
+}
+
+// PreviewResult describes what saving a rule would change
+type PreviewResult struct {
+	ProjectID string         `json:"project_id"` // project the rule assigns files to
+	Matched   int            `json:"matched"`    // files the rule would capture
+	Groups    []PreviewGroup `json:"groups"`     // captured files by their current project
+	Shadowed  []PreviewFile  `json:"shadowed"`   // files the rule matches but an earlier rule captures
+}
+
+// Preview evaluates an unsaved rule against the indexed files. The rule is
+// placed among the loaded rules the way saving it would: a rule being edited
+// keeps its position and a new rule goes last in its project.
+func (c *Classifier) Preview(ctx context.Context, r db.Rule) (PreviewResult, error) {
+	files, err := c.store.File.ListPresent(ctx)
+	if err != nil {
+		return PreviewResult{}, err
+	}
+
+	roots, err := c.watchRoots(ctx)
+	if err != nil {
+		return PreviewResult{}, err
+	}
+	needMetadata := c.previewUsesMetadata(r)
+	var stored map[string]map[string]string
+	if needMetadata {
+		if stored, err = c.store.Metadata.ListAll(ctx); err != nil {
+			return PreviewResult{}, err
+		}
+	}
+
+	now := time.Now()
+	targets := make([]previewTarget, 0, len(files))
+	for _, f := range files {
+		// A preview leaves the index as it is
+		c.ensureMime(ctx, &f, false)
+		c.ensureSource(ctx, &f, false)
+		var fields map[string]string
+		if needMetadata {
+			fields = c.ensureMetadata(ctx, f, stored, false)
+		}
+		cand, renamed := c.storedCandidate(ctx, f, fields, roots, now)
+		targets = append(targets, previewTarget{
+			file:    PreviewFile{FileID: f.ID, Path: f.Path, Name: f.Name, Size: f.Size, Mtime: f.Mtime, CurrentProjectID: f.ProjectID.String},
+			cand:    cand,
+			renamed: renamed,
+		})
+	}
+	return c.preview(ctx, r, targets)
+}
+
+// PreviewDir evaluates an unsaved rule against the files directly inside dir,
+// indexed or not, the same way as Preview
+func (c *Classifier) PreviewDir(ctx context.Context, r db.Rule, dir string) (PreviewResult, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return PreviewResult{}, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	roots, err := c.watchRoots(ctx)
+	if err != nil {
+		return PreviewResult{}, err
+	}
+
+	needMetadata := c.previewUsesMetadata(r)
+	now := time.Now()
+	var targets []previewTarget
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		pf := PreviewFile{Path: path, Name: e.Name(), Size: info.Size(), Mtime: info.ModTime()}
+		f, err := c.store.File.GetByPath(ctx, path)
+		if err != nil {
+			return PreviewResult{}, err
+		}
+		renamedFrom := ""
+		if f != nil && !f.DeletedAt.Valid {
+			pf.FileID = f.ID
+			pf.CurrentProjectID = f.ProjectID.String
+			renamedFrom = c.renamedFrom(ctx, f.ID)
+		}
+		mime := detectMime(f, path, info)
+		var fields map[string]string
+		if needMetadata {
+			fields = c.detectMetadata(ctx, f, path, mime, info)
+		}
+		cand := candidate{
+			name:    e.Name(),
+			ext:     extOf(e.Name()),
+			rel:     relativePath(roots, path),
+			parent:  parentName(path),
+			mime:    mime,
+			content: newContentText(ctx, path, mime),
+			fields:  fields,
+			sources: detectSource(f, path).Hosts(),
+			size:    info.Size(),
+			mtime:   info.ModTime(),
+			now:     now,
+		}
+		if renamedFrom != "" {
+			cand = cand.withName(filepath.Base(renamedFrom), path, roots)
+		}
+		targets = append(targets, previewTarget{file: pf, cand: cand, renamed: renamedFrom != ""})
+	}
+	return c.preview(ctx, r, targets)
+}
+
+// previewUsesMetadata reports whether r or any loaded rule may test files'
+// metadata fields. A rule that does not compile uses none; preview rejects it.
+func (c *Classifier) previewUsesMetadata(r db.Rule) bool {
+	c.mu.RLock()
+	rules := c.set
+	c.mu.RUnlock()
+	if usesMetadata(rules) {
+		return true
+	}
+	compiled, err := compileRule(r)
+	return err == nil && readsMetadata(compiled)
+}
+
+type previewTarget struct {
+	file    PreviewFile
+	cand    candidate
+	renamed bool // the classifier renamed the file already, so it keeps its name
+}
+
+func (c *Classifier) preview(ctx context.Context, r db.Rule, targets []previewTarget) (PreviewResult, error) {
+	// The name plays no part in matching and may not be chosen yet
+	if r.Name == "" {
+		r.Name = "Preview"
+	}
+	if err := validation.NewRuleValidator().Validate(&r); err != nil {
+		return PreviewResult{}, err
+	}
+	if r.ID == "" {
+		r.ID = previewRuleID
+	}
+	compiled, err := compileRule(r)
+	if err != nil {
+		return PreviewResult{}, err
+	}
+
+	projects, err := c.store.Project.GetAll(ctx)
+	if err != nil {
+		return PreviewResult{}, err
+	}
+	names := make(map[string]string, len(projects))
+	for _, p := range projects {
+		names[p.ID] = p.Name
+		if p.ID == r.ProjectID {
+			compiled.ProjectPriority = p.Priority
+		}
+	}
+	if _, ok := names[r.ProjectID]; !ok {
+		return PreviewResult{}, fmt.Errorf("project with ID '%s' not found", r.ProjectID)
+	}
+
+	rules := c.withPreviewRule(compiled)
+
+	result := PreviewResult{ProjectID: r.ProjectID, Groups: []PreviewGroup{}, Shadowed: []PreviewFile{}}
+	groups := make(map[string]int)
+	now := time.Now()
+	for _, t := range targets {
+		if !matchesFile(compiled, t.cand) {
+			continue
+		}
+		winner, text, _ := firstMatch(rules, t.cand)
+		if winner.RuleID != compiled.RuleID {
+			t.file.RuleID = winner.RuleID
+			result.Shadowed = append(result.Shadowed, t.file)
+			continue
+		}
+		if compiled.Rename != nil && !t.renamed {
+			t.file.NewName = previewName(compiled, text, t, names[r.ProjectID], now)
+		}
+
+		result.Matched++
+		current := t.file.CurrentProjectID
+		i, ok := groups[current]
+		if !ok {
+			i = len(result.Groups)
+			groups[current] = i
+			result.Groups = append(result.Groups, PreviewGroup{
+				ProjectID:   current,
+				ProjectName: names[current],
+				Stolen:      current != "" && current != r.ProjectID && current != c.incomingProjectID,
+			})
+		}
+		result.Groups[i].Files = append(result.Groups[i].Files, t.file)
+	}
+	return result, nil
+}
+
+// withPreviewRule returns the loaded rules with cr in the position it would
+// take once saved, replacing the stored version of the same rule
+func (c *Classifier) withPreviewRule(cr CompiledRule) []CompiledRule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rules := make([]CompiledRule, 0, len(c.set)+1)
+	last, editing := -1, false
+	for _, existing := range c.set {
+		if existing.RuleID == cr.RuleID {
+			if existing.ProjectID == cr.ProjectID {
+				cr.Priority, editing = existing.Priority, true
+			}
+			continue
+		}
+		if existing.ProjectID == cr.ProjectID && existing.Priority > last {
+			last = existing.Priority
+		}
+		rules = append(rules, existing)
+	}
+	if !editing {
+		cr.Priority = last + 1
+	}
+	rules = append(rules, cr)
+	sortRules(rules)
+	return rules
+}
+
+// previewName returns the name the rule's rename template would give a
+// captured file, with the suffix a collision in its directory would add. It
+// is empty when the file keeps its name.
+func previewName(r CompiledRule, text string, t previewTarget, project string, now time.Time) string {
+	name, err := newName(r, text, t.cand.name, t.file.Name, project, t.file.Mtime, now)
+	if err != nil || name == t.file.Name {
+		return ""
+	}
+	if path, err := fileops.UniquePath(filepath.Dir(t.file.Path), name); err == nil {
+		name = filepath.Base(path)
+	}
+	return name
+}
+
+func extOf(name string) string {
+	ext := filepath.Ext(name)
+	if len(ext) > 0 {
+		ext = ext[1:]
+	}
+	return ext
+}
@@ -0,0 +1,146 @@
+package classifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kalycs/db"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+func TestPreview_GroupsByCurrentProject(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	reports := &db.Project{Name: "Reports", IsActive: true}
+	docs := &db.Project{Name: "Documents", IsActive: true}
+	for _, p := range []*db.Project{reports, docs} {
+		if err := s.Project.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create project: %v", err)
+		}
+	}
+	// Documents was created last and is tried first
+	rules := []*db.Rule{
+		{Name: "Reports", ProjectID: reports.ID, Rule: "starts_with", Texts: mustJSON(t, []string{"report"})},
+		{Name: "Drafts", ProjectID: docs.ID, Rule: "contains", Texts: mustJSON(t, []string{"draft"})},
+	}
+	for _, r := range rules {
+		if err := s.Rule.Create(ctx, r); err != nil {
+			t.Fatalf("failed to create rule: %v", err)
+		}
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"report-q1.pdf", "report-draft.pdf", "notes.pdf", "photo.jpg"} {
+		classifyNewFile(t, ctx, c, filepath.Join(dir, name))
+	}
+	unindexed := filepath.Join(dir, "scan.pdf")
+	if err := os.WriteFile(unindexed, []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pdfs := db.Rule{ProjectID: docs.ID, Rule: "extension", Texts: mustJSON(t, []string{"pdf"})}
+	result, err := c.Preview(ctx, pdfs)
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	// report-draft.pdf is captured by Drafts, which comes first
+	if len(result.Shadowed) != 1 || result.Shadowed[0].Name != "report-draft.pdf" || result.Shadowed[0].RuleID != rules[1].ID {
+		t.Errorf("Shadowed = %+v, want report-draft.pdf via the Drafts rule", result.Shadowed)
+	}
+	if result.Matched != 2 {
+		t.Errorf("Matched = %d, want 2", result.Matched)
+	}
+	for _, g := range result.Groups {
+		switch g.ProjectID {
+		case reports.ID:
+			if !g.Stolen || len(g.Files) != 1 || g.Files[0].Name != "report-q1.pdf" {
+				t.Errorf("Reports group = %+v, want report-q1.pdf stolen", g)
+			}
+		case c.incomingProjectID:
+			if g.Stolen || len(g.Files) != 1 || g.Files[0].Name != "notes.pdf" {
+				t.Errorf("Incoming group = %+v, want notes.pdf not stolen", g)
+			}
+		default:
+			t.Errorf("unexpected group %+v", g)
+		}
+	}
+
+	result, err = c.PreviewDir(ctx, pdfs, dir)
+	if err != nil {
+		t.Fatalf("PreviewDir() error = %v", err)
+	}
+	if result.Matched != 3 {
+		t.Errorf("PreviewDir() Matched = %d, want 3", result.Matched)
+	}
+	var found bool
+	for _, g := range result.Groups {
+		if g.ProjectID == "" {
+			found = len(g.Files) == 1 && g.Files[0].Path == unindexed && g.Files[0].FileID == ""
+		}
+	}
+	if !found {
+		t.Errorf("PreviewDir() groups = %+v, want scan.pdf as not indexed", result.Groups)
+	}
+
+	// Editing the Drafts rule keeps its position, so nothing is shadowed
+	edited := *rules[1]
+	edited.Texts = mustJSON(t, []string{"report"})
+	result, err = c.Preview(ctx, edited)
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if result.Matched != 2 || len(result.Shadowed) != 0 {
+		t.Errorf("Preview() of edited rule = %+v, want 2 matches and none shadowed", result)
+	}
+}
+
+func TestPreview_LeavesIndexUntouched(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+	project := &db.Project{Name: "Photos", IsActive: true}
+	if err := s.Project.Create(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "IMG_0001.jpg")
+	classifyNewFile(t, ctx, c, path)
+	f, err := s.File.GetByPath(ctx, path)
+	if err != nil || f == nil {
+		t.Fatalf("no row for indexed file: %v", err)
+	}
+	// As if the file was indexed before content types and metadata were recorded
+	if err := s.File.SetMime(ctx, f.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	c.SetMetadataExtractors(pathExtractor{path: {"gps": "true"}})
+
+	rule := db.Rule{ProjectID: project.ID, Rule: KindMetadata, Texts: mustJSON(t, []string{"gps = true"})}
+	result, err := c.Preview(ctx, rule)
+	if err != nil || result.Matched != 1 {
+		t.Fatalf("Preview() = %+v, %v; want the photo matched", result, err)
+	}
+	after, err := s.File.GetByID(ctx, f.ID)
+	if err != nil || after.Mime != "" {
+		t.Errorf("content type after preview = %q, %v; want none stored", after.Mime, err)
+	}
+	if fields, err := s.Metadata.Get(ctx, f.ID); err != nil || fields != nil {
+		t.Errorf("metadata after preview = %v, %v; want none stored", fields, err)
+	}
+}
@@ -0,0 +1,180 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"kalycs/db"
+	"kalycs/internal/logging"
+	"kalycs/internal/store"
+	"time"
+)
+
+// Which files a reclassification covers
+const (
+	ScopeAll      = "all"      // every indexed file
+	ScopeProject  = "project"  // the files of one project
+	ScopeIncoming = "incoming" // files no rule matched so far
+)
+
+// ReclassifyScope selects the files to reclassify
+type ReclassifyScope struct {
+	Kind      string `json:"kind"`       // all, project or incoming
+	ProjectID string `json:"project_id"` // for the project scope
+}
+
+// Reassignment is a file that changed, or with a dry run would change, project
+type Reassignment struct {
+	FileID        string `json:"file_id"`
+	Path          string `json:"path"`
+	FromProjectID string `json:"from_project_id"`
+	ToProjectID   string `json:"to_project_id"`
+	RuleID        string `json:"rule_id"` // empty when the file goes back to the default project
+}
+
+// ReclassifyResult summarises a reclassification
+type ReclassifyResult struct {
+	DryRun  bool           `json:"dry_run"`
+	Scanned int            `json:"scanned"`
+	Pinned  int            `json:"pinned"` // manually assigned files that were left alone
+	Moves   []Reassignment `json:"moves"`
+}
+
+// Reclassify runs the current rules against files that are already indexed
+// and moves them to the project the rules now choose. Manually assigned files
+// and files in inactive projects keep their project. Only the assignment
+// changes: project actions are not applied to files already on disk. With
+// dryRun the moves are reported without being made.
+func (c *Classifier) Reclassify(ctx context.Context, scope ReclassifyScope, dryRun bool) (ReclassifyResult, error) {
+	c.reclassifyMu.Lock()
+	defer c.reclassifyMu.Unlock()
+
+	var files []db.File
+	var err error
+	switch scope.Kind {
+	case ScopeAll, "":
+		files, err = c.store.File.ListPresent(ctx)
+	case ScopeProject:
+		if scope.ProjectID == "" {
+			return ReclassifyResult{}, fmt.Errorf("project ID cannot be empty")
+		}
+		files, err = c.store.File.ByProject(ctx, scope.ProjectID)
+	case ScopeIncoming:
+		files, err = c.store.File.ByProject(ctx, c.incomingProjectID)
+	default:
+		return ReclassifyResult{}, fmt.Errorf("unknown reclassify scope %q", scope.Kind)
+	}
+	if err != nil {
+		return ReclassifyResult{}, err
+	}
+
+	projects, err := c.store.Project.GetAll(ctx)
+	if err != nil {
+		return ReclassifyResult{}, err
+	}
+	inactive := make(map[string]bool)
+	for _, p := range projects {
+		if !p.IsActive {
+			inactive[p.ID] = true
+		}
+	}
+
+	roots, err := c.watchRoots(ctx)
+	if err != nil {
+		return ReclassifyResult{}, err
+	}
+	c.mu.RLock()
+	rules := c.set
+	c.mu.RUnlock()
+
+	// Metadata is only read from files when a rule may test it
+	needMetadata := usesMetadata(rules)
+	var stored map[string]map[string]string
+	if needMetadata {
+		if stored, err = c.store.Metadata.ListAll(ctx); err != nil {
+			return ReclassifyResult{}, err
+		}
+	}
+
+	result := ReclassifyResult{DryRun: dryRun, Moves: []Reassignment{}}
+	now := time.Now()
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		result.Scanned++
+		if f.AssignmentSource == db.AssignedManually {
+			result.Pinned++
+			continue
+		}
+		if inactive[f.ProjectID.String] {
+			continue
+		}
+		// A dry run leaves the index as it is
+		c.ensureMime(ctx, &f, !dryRun)
+		c.ensureSource(ctx, &f, !dryRun)
+		var fields map[string]string
+		if needMetadata {
+			fields = c.ensureMetadata(ctx, f, stored, !dryRun)
+		}
+
+		cand, _ := c.storedCandidate(ctx, f, fields, roots, now)
+		to := c.assignment(rules, cand)
+		move := Reassignment{FileID: f.ID, Path: f.Path, FromProjectID: f.ProjectID.String, ToProjectID: to.ProjectID, RuleID: to.RuleID}
+		if move.ToProjectID == move.FromProjectID {
+			// Another rule of the same project, or another of its texts, may now be the one that matches
+			if !dryRun && (to.RuleID != f.RuleID.String || to.MatchText != f.MatchText) {
+				moved, err := c.store.File.Reassign(ctx, f.ID, move.FromProjectID, to)
+				if err != nil {
+					return result, err
+				}
+				if moved && to.RuleID != "" && capturedAnew(&f, to.RuleID) {
+					c.recordMatch(ctx, to.RuleID, f.Path, now)
+				}
+			}
+			continue
+		}
+
+		if !dryRun {
+			moved, err := c.store.File.Reassign(ctx, f.ID, move.FromProjectID, to)
+			if err != nil {
+				return result, err
+			}
+			if !moved {
+				continue
+			}
+			if to.RuleID != "" {
+				c.recordMatch(ctx, to.RuleID, f.Path, now)
+			}
+		}
+		result.Moves = append(result.Moves, move)
+	}
+
+	logging.L().Infow("Files reclassified", "scope", scope.Kind, "project_id", scope.ProjectID, "dry_run", dryRun,
+		"scanned", result.Scanned, "pinned", result.Pinned, "moved", len(result.Moves))
+	return result, nil
+}
+
+// assignment returns the project the rules choose for a file and how it was chosen
+func (c *Classifier) assignment(rules []CompiledRule, cand candidate) store.Assignment {
+	if r, text, ok := firstMatch(rules, cand); ok {
+		return store.Assignment{ProjectID: r.ProjectID, Source: db.AssignedByRule, RuleID: r.RuleID, MatchKind: r.Kind, MatchText: text}
+	}
+	return store.Assignment{ProjectID: c.incomingProjectID, Source: db.AssignedIncoming}
+}
+
+// fileCandidate evaluates rules against an indexed file as it was last seen
+func fileCandidate(ctx context.Context, f db.File, fields map[string]string, roots []string, now time.Time) candidate {
+	return candidate{
+		name:    f.Name,
+		ext:     f.Ext,
+		rel:     relativePath(roots, f.Path),
+		parent:  parentName(f.Path),
+		mime:    f.Mime,
+		content: newContentText(ctx, f.Path, f.Mime),
+		fields:  fields,
+		sources: fileSource(f).Hosts(),
+		size:    f.Size,
+		mtime:   f.Mtime,
+		now:     now,
+	}
+}
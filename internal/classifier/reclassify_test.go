@@ -0,0 +1,127 @@
+package classifier
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kalycs/db"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+func TestReclassify_FollowsRuleChanges(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	invoices := &db.Project{Name: "Invoices", IsActive: true}
+	archive := &db.Project{Name: "Archive", IsActive: true}
+	for _, p := range []*db.Project{invoices, archive} {
+		if err := s.Project.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create project: %v", err)
+		}
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"invoice-1.pdf", "invoice-2.pdf", "photo.jpg"} {
+		classifyNewFile(t, ctx, c, filepath.Join(dir, name))
+	}
+	pinned, err := s.File.GetByPath(ctx, filepath.Join(dir, "invoice-2.pdf"))
+	if err != nil || pinned == nil {
+		t.Fatalf("GetByPath() = %v, %v", pinned, err)
+	}
+	if pinned.AssignmentSource != db.AssignedIncoming {
+		t.Errorf("AssignmentSource = %q, want %q", pinned.AssignmentSource, db.AssignedIncoming)
+	}
+	if err := s.File.SetProject(ctx, pinned.ID, archive.ID); err != nil {
+		t.Fatalf("SetProject() error = %v", err)
+	}
+
+	rule := &db.Rule{Name: "Invoices", ProjectID: invoices.ID, Rule: "starts_with", Texts: mustJSON(t, []string{"invoice"})}
+	if err := s.Rule.Create(ctx, rule); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+
+	// As if the files were indexed before content types were recorded
+	indexed, err := s.File.ListPresent(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range indexed {
+		if err := s.File.SetMime(ctx, f.ID, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dry, err := c.Reclassify(ctx, ReclassifyScope{Kind: ScopeIncoming}, true)
+	if err != nil {
+		t.Fatalf("Reclassify() error = %v", err)
+	}
+	if len(dry.Moves) != 1 || dry.Moves[0].ToProjectID != invoices.ID || dry.Moves[0].RuleID != rule.ID {
+		t.Fatalf("dry run moves = %+v, want invoice-1.pdf to Invoices", dry.Moves)
+	}
+	if f, _ := s.File.GetByID(ctx, dry.Moves[0].FileID); f.ProjectID.String != c.incomingProjectID || f.Mime != "" {
+		t.Errorf("dry run changed the file: %+v", f)
+	}
+
+	result, err := c.Reclassify(ctx, ReclassifyScope{Kind: ScopeAll}, false)
+	if err != nil {
+		t.Fatalf("Reclassify() error = %v", err)
+	}
+	if result.Scanned != 3 || result.Pinned != 1 || len(result.Moves) != 1 {
+		t.Fatalf("Reclassify() = %+v, want 3 scanned, 1 pinned, 1 moved", result)
+	}
+	if n := totalMatches(t, s, rule.ID); n != 1 {
+		t.Errorf("TotalMatches after reclassifying = %d, want 1", n)
+	}
+	moved, _ := s.File.GetByID(ctx, result.Moves[0].FileID)
+	if moved.ProjectID.String != invoices.ID || moved.AssignmentSource != db.AssignedByRule || moved.Mime == "" {
+		t.Errorf("moved file = %+v, want it in Invoices by rule with its content type cached", moved)
+	}
+	if f, _ := s.File.GetByID(ctx, pinned.ID); f.ProjectID.String != archive.ID {
+		t.Error("manually assigned file was reclassified")
+	}
+
+	// Deleting the rule sends the file back to Incoming
+	if err := s.Rule.Delete(ctx, rule.ID); err != nil {
+		t.Fatalf("failed to delete rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	result, err = c.Reclassify(ctx, ReclassifyScope{Kind: ScopeProject, ProjectID: invoices.ID}, false)
+	if err != nil {
+		t.Fatalf("Reclassify() error = %v", err)
+	}
+	if len(result.Moves) != 1 || result.Moves[0].ToProjectID != c.incomingProjectID || result.Moves[0].RuleID != "" {
+		t.Errorf("Reclassify() after delete = %+v, want the file back in Incoming", result.Moves)
+	}
+
+	if _, err := c.Reclassify(ctx, ReclassifyScope{Kind: "everything"}, true); err == nil {
+		t.Error("Reclassify() accepted an unknown scope")
+	}
+}
+
+// totalMatches returns how many files ruleID has captured
+func totalMatches(t *testing.T, s *store.Store, ruleID string) int64 {
+	t.Helper()
+	stats, err := s.Stats.List(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("Stats.List() error = %v", err)
+	}
+	for _, st := range stats {
+		if st.RuleID == ruleID {
+			return st.TotalMatches
+		}
+	}
+	return 0
+}
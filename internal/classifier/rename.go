@@ -0,0 +1,129 @@
+package classifier
+
+import (
+	"context"
+	"errors"
+	"kalycs/db"
+	"kalycs/internal/fileops"
+	"kalycs/internal/logging"
+	"kalycs/internal/rename"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// renamedFrom returns the path a file had before the classifier last renamed
+// it, or "" when it was not renamed or the rename was undone
+func (c *Classifier) renamedFrom(ctx context.Context, fileID string) string {
+	from, err := c.store.Action.RenamedFrom(ctx, fileID)
+	if err != nil {
+		logging.L().Warnw("Failed to look up file rename", "file_id", fileID, "error", err)
+		return ""
+	}
+	return from
+}
+
+// withName returns cand evaluated under name in the directory of path
+func (cand candidate) withName(name, path string, roots []string) candidate {
+	cand.name = name
+	cand.ext = extOf(name)
+	cand.rel = relativePath(roots, filepath.Join(filepath.Dir(path), name))
+	return cand
+}
+
+// storedCandidate evaluates rules against an indexed file like fileCandidate.
+// A file the classifier renamed is matched under the name it had before, so
+// the rule that renamed it keeps holding it; renamed reports whether it was.
+func (c *Classifier) storedCandidate(ctx context.Context, f db.File, fields map[string]string, roots []string, now time.Time) (cand candidate, renamed bool) {
+	cand = fileCandidate(ctx, f, fields, roots, now)
+	if from := c.renamedFrom(ctx, f.ID); from != "" {
+		return cand.withName(filepath.Base(from), f.Path, roots), true
+	}
+	return cand, false
+}
+
+// newName fills in the rule's rename template for a file currently called
+// name. Capture groups come from the rule's pattern that matched matchName,
+// the name the rule was evaluated against.
+func newName(r CompiledRule, text, matchName, name, project string, mtime, now time.Time) (string, error) {
+	ext := filepath.Ext(name)
+	return r.Rename.Execute(rename.Vars{
+		Name:     name[:len(name)-len(ext)],
+		Ext:      ext,
+		Project:  project,
+		Modified: mtime,
+		Now:      now,
+		Groups:   captureGroups(r, text, matchName),
+	})
+}
+
+// captureGroups returns the groups of the regex pattern text of r matched
+// against name, by number and by name
+func captureGroups(r CompiledRule, text, name string) map[string]string {
+	if r.Kind != "regex" {
+		return nil
+	}
+	for i, re := range r.Patterns {
+		if r.Texts[i] != text {
+			continue
+		}
+		match := re.FindStringSubmatch(name)
+		if match == nil {
+			return nil
+		}
+		groups := make(map[string]string, len(match))
+		for j, sub := range match {
+			groups[strconv.Itoa(j)] = sub
+			if n := re.SubexpNames()[j]; n != "" {
+				groups[n] = sub
+			}
+		}
+		return groups
+	}
+	return nil
+}
+
+// renameFile gives the file at absPath the name the rule's template yields
+// for it. The file keeps its name when the template yields it already, the
+// file no longer matches expected, or the user undid an earlier rename; the
+// returned path is where the file now lives.
+func (c *Classifier) renameFile(ctx context.Context, r CompiledRule, text, absPath string, expected os.FileInfo) (string, error) {
+	project, err := c.store.Project.GetByID(ctx, r.ProjectID)
+	if err != nil {
+		logging.L().Warnw("Failed to load project for file rename", "project_id", r.ProjectID, "error", err)
+		return absPath, nil
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return absPath, err
+	}
+	name := filepath.Base(absPath)
+	target, err := newName(r, text, name, name, project.Name, info.ModTime(), time.Now())
+	if err != nil {
+		logging.L().Warnw("Rename template produced no file name", "file_path", absPath, "rule_id", r.RuleID, "error", err)
+		return absPath, nil
+	}
+	if target == name {
+		return absPath, nil
+	}
+
+	undone, err := c.store.Action.WasUndone(ctx, absPath, info.ModTime())
+	if err != nil {
+		logging.L().Warnw("Failed to check undo journal", "file_path", absPath, "error", err)
+	} else if undone {
+		logging.L().Infow("File action was undone earlier, keeping name", "file_path", absPath, "rule_id", r.RuleID)
+		return absPath, nil
+	}
+
+	newPath, err := fileops.Rename(absPath, target, expected)
+	if err != nil {
+		if errors.Is(err, fileops.ErrSourceChanged) {
+			logging.L().Infow("File still changing, keeping name", "file_path", absPath, "rule_id", r.RuleID)
+			return absPath, nil
+		}
+		logging.L().Errorw("Failed to rename file", "file_path", absPath, "rule_id", r.RuleID, "new_name", target, "error", err)
+		return absPath, err
+	}
+	return newPath, nil
+}
@@ -0,0 +1,177 @@
+package classifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kalycs/db"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+// setupRenameProject creates a classifier with a project whose regex rule
+// renames "scan_<n>" files to "<project>_<n>"
+func setupRenameProject(t *testing.T) (*Classifier, *store.Store, *db.Rule) {
+	t.Helper()
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	project := &db.Project{Name: "Scans", IsActive: true}
+	if err := s.Project.Create(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	rule := &db.Rule{Name: "Scans", ProjectID: project.ID, Rule: "regex", Texts: mustJSON(t, []string{`^scan_(\d+)`}),
+		RenameTemplate: "{project}_${1}{ext}"}
+	if err := s.Rule.Create(ctx, rule); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	return c, s, rule
+}
+
+func TestClassify_RenamesWithTemplate(t *testing.T) {
+	c, s, rule := setupRenameProject(t)
+	dir := t.TempDir()
+	ctx := WithBatchID(context.Background(), "batch-1")
+
+	original := filepath.Join(dir, "scan_42.pdf")
+	classifyNewFile(t, ctx, c, original)
+
+	renamed := filepath.Join(dir, "Scans_42.pdf")
+	if _, err := os.Stat(renamed); err != nil {
+		t.Fatalf("file was not renamed: %v", err)
+	}
+	f, err := s.File.GetByPath(ctx, renamed)
+	if err != nil || f == nil {
+		t.Fatalf("no row for renamed file: %v", err)
+	}
+	if f.RuleID.String != rule.ID || f.Name != "Scans_42.pdf" || f.Ext != "pdf" {
+		t.Errorf("renamed file row = %+v, want rule %s and the new name", f, rule.ID)
+	}
+
+	// The watcher sees the renamed file appear; it keeps its rule and name
+	info, err := os.Stat(renamed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Classify(ctx, renamed, info); err != nil {
+		t.Fatalf("Classify(renamed) error = %v", err)
+	}
+	if _, err := os.Stat(renamed); err != nil {
+		t.Fatalf("renamed file was renamed again: %v", err)
+	}
+	again, err := s.File.GetByPath(ctx, renamed)
+	if err != nil || again == nil || again.ID != f.ID || again.RuleID.String != rule.ID {
+		t.Fatalf("row after classifying the renamed file = %+v, %v; want %s kept by rule %s", again, err, f.ID, rule.ID)
+	}
+	result, err := c.Reclassify(ctx, ReclassifyScope{Kind: ScopeAll}, true)
+	if err != nil || len(result.Moves) != 0 {
+		t.Errorf("Reclassify() = %+v, %v; want the renamed file kept", result, err)
+	}
+
+	undo, err := c.UndoBatch(context.Background(), "batch-1")
+	if err != nil || len(undo.Undone) != 1 || len(undo.Conflicts) != 0 {
+		t.Fatalf("UndoBatch() = %+v, %v; want the rename undone", undo, err)
+	}
+	if _, err := os.Stat(original); err != nil {
+		t.Fatalf("file did not get its name back: %v", err)
+	}
+
+	// A file whose rename was undone is not renamed again
+	info, err = os.Stat(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Classify(context.Background(), original, info); err != nil {
+		t.Fatalf("Classify(original) error = %v", err)
+	}
+	if _, err := os.Stat(original); err != nil {
+		t.Errorf("file was renamed again after undo: %v", err)
+	}
+}
+
+func TestClassify_RenamesAfterMove(t *testing.T) {
+	c, s, destDir := setupMoveProject(t)
+	ctx := context.Background()
+
+	rules, err := s.Rule.ListActive(ctx)
+	if err != nil || len(rules) != 1 {
+		t.Fatalf("ListActive() = %v, %v", rules, err)
+	}
+	rules[0].RenameTemplate = "{project} {name}{ext}"
+	if err := s.Rule.Update(ctx, &rules[0]); err != nil {
+		t.Fatalf("failed to update rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+
+	srcDir := t.TempDir()
+	original := filepath.Join(srcDir, "invoice-1.pdf")
+	// A file in the destination already has the new name
+	if err := os.WriteFile(filepath.Join(destDir, "Invoices invoice-1.pdf"), []byte("other"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	classifyNewFile(t, WithBatchID(ctx, "batch-1"), c, original)
+
+	renamed := filepath.Join(destDir, "Invoices invoice-1 (1).pdf")
+	if _, err := os.Stat(renamed); err != nil {
+		t.Fatalf("file was not moved and renamed: %v", err)
+	}
+
+	undo, err := c.UndoBatch(ctx, "batch-1")
+	if err != nil || len(undo.Undone) != 2 || len(undo.Conflicts) != 0 {
+		t.Fatalf("UndoBatch() = %+v, %v; want the move and the rename undone", undo, err)
+	}
+	if _, err := os.Stat(original); err != nil {
+		t.Errorf("file was not restored: %v", err)
+	}
+}
+
+func TestPreview_NewName(t *testing.T) {
+	c, s, rule := setupRenameProject(t)
+	ctx := context.Background()
+
+	// Index the files while the rule keeps names
+	rule.RenameTemplate = ""
+	if err := s.Rule.Update(ctx, rule); err != nil {
+		t.Fatalf("failed to update rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"scan_1.pdf", "scan_2.pdf", "Scans_2.pdf"} {
+		classifyNewFile(t, ctx, c, filepath.Join(dir, name))
+	}
+
+	rule.RenameTemplate = "{project}_${1}{ext}"
+	result, err := c.Preview(ctx, *rule)
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	got := make(map[string]string)
+	for _, g := range result.Groups {
+		for _, f := range g.Files {
+			got[f.Name] = f.NewName
+		}
+	}
+	want := map[string]string{"scan_1.pdf": "Scans_1.pdf", "scan_2.pdf": "Scans_2 (1).pdf"}
+	if len(got) != len(want) {
+		t.Fatalf("Preview() captured %v, want %v", got, want)
+	}
+	for name, newName := range want {
+		if got[name] != newName {
+			t.Errorf("NewName of %s = %q, want %q", name, got[name], newName)
+		}
+	}
+}
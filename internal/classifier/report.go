@@ -0,0 +1,271 @@
+package classifier
+
+import (
+	"context"
+	"database/sql"
+	"kalycs/db"
+	"kalycs/internal/condition"
+	"kalycs/internal/metadata"
+	"kalycs/internal/provenance"
+	"kalycs/internal/ruleexpr"
+	"strings"
+	"time"
+)
+
+// RuleRef names a rule and its project
+type RuleRef struct {
+	RuleID      string `json:"rule_id"`
+	RuleName    string `json:"rule_name"`
+	ProjectID   string `json:"project_id"`
+	ProjectName string `json:"project_name"`
+}
+
+// RuleStat is a rule with its match counters
+type RuleStat struct {
+	RuleRef
+	Kind          string       `json:"kind"`
+	TotalMatches  int64        `json:"total_matches"`
+	LastMatchedAt sql.NullTime `json:"last_matched_at"`
+	RecentMatches int64        `json:"recent_matches"` // matches in the last db.RecentMatchDays days
+}
+
+// ShadowedRule is a rule that can never capture a file because every one of
+// its texts is already covered by rules tried before it
+type ShadowedRule struct {
+	RuleRef
+	ShadowedBy []RuleRef `json:"shadowed_by"`
+}
+
+// RuleOverlap is a pair of rules in different projects that can match the
+// same file. First is tried before Second and wins such files.
+type RuleOverlap struct {
+	First      RuleRef `json:"first"`
+	Second     RuleRef `json:"second"`
+	FirstText  string  `json:"first_text"`
+	SecondText string  `json:"second_text"`
+}
+
+// RuleReport summarises how the active rules perform
+type RuleReport struct {
+	Rules        []RuleStat     `json:"rules"` // in the order they are tried
+	NeverMatched []RuleRef      `json:"never_matched"`
+	Shadowed     []ShadowedRule `json:"shadowed"`
+	Overlaps     []RuleOverlap  `json:"overlaps"`
+}
+
+// Report returns the match counters of the loaded rules along with the rules
+// that never matched, that are shadowed, or that overlap with another project.
+// Shadowing and overlaps are found from the rule texts alone; composite rules
+// are not analysed.
+func (c *Classifier) Report(ctx context.Context) (RuleReport, error) {
+	rules, err := c.store.Rule.ListActive(ctx)
+	if err != nil {
+		return RuleReport{}, err
+	}
+	projects, err := c.store.Project.GetAll(ctx)
+	if err != nil {
+		return RuleReport{}, err
+	}
+	stats, err := c.store.Stats.List(ctx, time.Now())
+	if err != nil {
+		return RuleReport{}, err
+	}
+
+	projectNames := make(map[string]string, len(projects))
+	for _, p := range projects {
+		projectNames[p.ID] = p.Name
+	}
+	refs := make(map[string]RuleRef, len(rules))
+	for _, r := range rules {
+		refs[r.ID] = RuleRef{RuleID: r.ID, RuleName: r.Name, ProjectID: r.ProjectID, ProjectName: projectNames[r.ProjectID]}
+	}
+	counters := make(map[string]db.RuleStats, len(stats))
+	for _, s := range stats {
+		counters[s.RuleID] = s
+	}
+
+	c.mu.RLock()
+	set := c.set
+	c.mu.RUnlock()
+
+	report := RuleReport{Rules: []RuleStat{}, NeverMatched: []RuleRef{}, Shadowed: []ShadowedRule{}, Overlaps: []RuleOverlap{}}
+	for _, cr := range set {
+		ref, ok := refs[cr.RuleID]
+		if !ok {
+			continue
+		}
+		s := counters[cr.RuleID]
+		report.Rules = append(report.Rules, RuleStat{
+			RuleRef:       ref,
+			Kind:          cr.Kind,
+			TotalMatches:  s.TotalMatches,
+			LastMatchedAt: s.LastMatchedAt,
+			RecentMatches: s.RecentMatches,
+		})
+		if s.TotalMatches == 0 {
+			report.NeverMatched = append(report.NeverMatched, ref)
+		}
+	}
+
+	for j, later := range set {
+		if _, ok := refs[later.RuleID]; !ok || !analysable(later) {
+			continue
+		}
+		var by []RuleRef
+		covered := make([]bool, len(later.Texts))
+		for _, earlier := range set[:j] {
+			if !analysable(earlier) {
+				continue
+			}
+			shadows := false
+			for bi := range later.Texts {
+				for ai := range earlier.Texts {
+					if covers(earlier, ai, later, bi) {
+						covered[bi], shadows = true, true
+					}
+				}
+			}
+			if shadows {
+				by = append(by, refs[earlier.RuleID])
+			}
+
+			if earlier.ProjectID != later.ProjectID {
+				if ai, bi, ok := overlap(earlier, later); ok {
+					report.Overlaps = append(report.Overlaps, RuleOverlap{
+						First:      refs[earlier.RuleID],
+						Second:     refs[later.RuleID],
+						FirstText:  earlier.Texts[ai],
+						SecondText: later.Texts[bi],
+					})
+				}
+			}
+		}
+		if allTrue(covered) {
+			report.Shadowed = append(report.Shadowed, ShadowedRule{RuleRef: refs[later.RuleID], ShadowedBy: by})
+		}
+	}
+	return report, nil
+}
+
+// analysable reports whether a rule's texts can be compared with other rules
+func analysable(r CompiledRule) bool {
+	return r.Kind != condition.KindComposite && len(r.Texts) > 0
+}
+
+func allTrue(bs []bool) bool {
+	for _, b := range bs {
+		if !b {
+			return false
+		}
+	}
+	return len(bs) > 0
+}
+
+// overlap finds a text of a and a text of b that can match the same file:
+// one covers the other, or for expression rules the ranges intersect
+func overlap(a, b CompiledRule) (int, int, bool) {
+	for ai := range a.Texts {
+		for bi := range b.Texts {
+			if covers(a, ai, b, bi) || covers(b, bi, a, ai) {
+				return ai, bi, true
+			}
+			if ruleexpr.IsKind(a.Kind) && a.Kind == b.Kind && intersects(a.Ranges[ai], b.Ranges[bi]) {
+				return ai, bi, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// covers reports whether every file matched by text bi of rule b is also
+// matched by text ai of rule a. It errs on the side of false.
+func covers(a CompiledRule, ai int, b CompiledRule, bi int) bool {
+	if ruleexpr.IsKind(a.Kind) || ruleexpr.IsKind(b.Kind) {
+		return a.Kind == b.Kind && containsRange(a.Ranges[ai], b.Ranges[bi])
+	}
+
+	// A case-sensitive text only covers texts that are case-sensitive too
+	if a.CaseSensitive && !b.CaseSensitive {
+		return false
+	}
+	at, bt := a.Texts[ai], b.Texts[bi]
+	if !a.CaseSensitive && b.Kind != "regex" && b.Kind != "glob" && b.Kind != KindPath && b.Kind != KindContentRegex {
+		bt = strings.ToLower(bt)
+	}
+
+	switch a.Kind {
+	case "starts_with":
+		return b.Kind == "starts_with" && strings.HasPrefix(bt, at)
+	case "ends_with":
+		switch b.Kind {
+		case "ends_with":
+			return strings.HasSuffix(bt, at)
+		case "extension":
+			return strings.HasSuffix("."+bt, at)
+		}
+	case "contains":
+		switch b.Kind {
+		case "starts_with", "contains", "ends_with":
+			return strings.Contains(bt, at)
+		case "extension":
+			return strings.Contains("."+bt, at)
+		}
+	case "extension":
+		switch b.Kind {
+		case "extension":
+			return bt == at
+		case "ends_with":
+			// The name ends in "."+at with no dot after it, so that is its extension
+			return strings.HasSuffix(bt, "."+at)
+		}
+	case KindParent:
+		return b.Kind == KindParent && bt == at
+	case KindContent:
+		return b.Kind == KindContent && strings.Contains(bt, at)
+	case KindMime:
+		if b.Kind != KindMime {
+			return false
+		}
+		major, wildcard := strings.CutSuffix(at, "/*")
+		return at == bt || at == "*/*" || (wildcard && strings.HasPrefix(bt, major+"/"))
+	case KindSourceDomain:
+		return b.Kind == KindSourceDomain && provenance.MatchDomain(bt, at)
+	case KindMetadata:
+		if b.Kind != KindMetadata {
+			return false
+		}
+		// A field merely being present covers every condition on it
+		if a.Fields[ai].Op == metadata.OpExists {
+			return a.Fields[ai].Key == b.Fields[bi].Key
+		}
+		if !a.CaseSensitive {
+			at = strings.ToLower(at)
+		}
+		return at == bt
+	case "regex", "glob", KindPath, KindContentRegex:
+		return b.Kind == a.Kind && a.Texts[ai] == b.Texts[bi]
+	}
+	return false
+}
+
+// containsRange reports whether b lies entirely within a
+func containsRange(a, b ruleexpr.Range) bool {
+	if a.HasMin && (!b.HasMin || b.Min < a.Min) {
+		return false
+	}
+	if a.HasMax && (!b.HasMax || b.Max > a.Max) {
+		return false
+	}
+	return true
+}
+
+// intersects reports whether a value exists that lies within both ranges
+func intersects(a, b ruleexpr.Range) bool {
+	if a.HasMin && b.HasMax && b.Max < a.Min {
+		return false
+	}
+	if b.HasMin && a.HasMax && a.Max < b.Min {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package classifier
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"kalycs/db"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+func TestReport(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	// Invoices is created last, so its rules are tried first
+	docs := &db.Project{Name: "Documents", IsActive: true}
+	invoices := &db.Project{Name: "Invoices", IsActive: true}
+	for _, p := range []*db.Project{docs, invoices} {
+		if err := s.Project.Create(ctx, p); err != nil {
+			t.Fatalf("failed to create project: %v", err)
+		}
+	}
+	rules := []*db.Rule{
+		{Name: "Invoices", ProjectID: invoices.ID, Rule: "contains", Texts: mustJSON(t, []string{"invoice"})},
+		{Name: "PDFs", ProjectID: docs.ID, Rule: "extension", Texts: mustJSON(t, []string{"pdf"})},
+		{Name: "Reports", ProjectID: docs.ID, Rule: "ends_with", Texts: mustJSON(t, []string{"-report.pdf"})},
+		{Name: "Old invoices", ProjectID: docs.ID, Rule: "starts_with", Texts: mustJSON(t, []string{"Invoice_"})},
+		{Name: "Scans", ProjectID: docs.ID, Rule: "starts_with", Texts: mustJSON(t, []string{"scan", "invoice"})},
+	}
+	for _, r := range rules {
+		if err := s.Rule.Create(ctx, r); err != nil {
+			t.Fatalf("failed to create rule: %v", err)
+		}
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"scan1.jpg", "a.pdf", "invoice.txt"} {
+		classifyNewFile(t, ctx, c, filepath.Join(dir, name))
+	}
+	// Rescanning a file its rule already holds is not another match
+	classifyNewFile(t, ctx, c, filepath.Join(dir, "a.pdf"))
+
+	report, err := c.Report(ctx)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	totals := map[string]int64{}
+	for _, r := range report.Rules {
+		totals[r.RuleName] = r.TotalMatches
+	}
+	want := map[string]int64{"Invoices": 1, "PDFs": 1, "Reports": 0, "Old invoices": 0, "Scans": 1}
+	for name, n := range want {
+		if totals[name] != n {
+			t.Errorf("TotalMatches[%s] = %d, want %d", name, totals[name], n)
+		}
+	}
+
+	if got := refNames(report.NeverMatched); !slices.Equal(got, []string{"Reports", "Old invoices"}) {
+		t.Errorf("NeverMatched = %v, want [Reports Old invoices]", got)
+	}
+
+	if len(report.Shadowed) != 2 {
+		t.Fatalf("Shadowed = %+v, want Reports and Old invoices", report.Shadowed)
+	}
+	if sh := report.Shadowed[0]; sh.RuleName != "Reports" || !slices.Equal(refNames(sh.ShadowedBy), []string{"PDFs"}) {
+		t.Errorf("Shadowed[0] = %+v, want Reports shadowed by PDFs", sh)
+	}
+	if sh := report.Shadowed[1]; sh.RuleName != "Old invoices" || !slices.Equal(refNames(sh.ShadowedBy), []string{"Invoices"}) {
+		t.Errorf("Shadowed[1] = %+v, want Old invoices shadowed by Invoices", sh)
+	}
+
+	// Scans is only partly covered: its "scan" text still captures files
+	if len(report.Overlaps) != 2 {
+		t.Fatalf("Overlaps = %+v, want Old invoices and Scans against Invoices", report.Overlaps)
+	}
+	for i, second := range []string{"Old invoices", "Scans"} {
+		o := report.Overlaps[i]
+		if o.First.RuleName != "Invoices" || o.Second.RuleName != second || o.FirstText != "invoice" {
+			t.Errorf("Overlaps[%d] = %+v, want Invoices before %s", i, o, second)
+		}
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		a, b     db.Rule
+		expected bool
+	}{
+		{db.Rule{Rule: "extension", Texts: `["pdf"]`}, db.Rule{Rule: "ends_with", Texts: `["x.PDF"]`}, true},
+		{db.Rule{Rule: "extension", Texts: `["pdf"]`}, db.Rule{Rule: "ends_with", Texts: `["pdf"]`}, false},
+		{db.Rule{Rule: "ends_with", Texts: `[".tar.gz"]`}, db.Rule{Rule: "extension", Texts: `["gz"]`}, false},
+		{db.Rule{Rule: "ends_with", Texts: `["z"]`}, db.Rule{Rule: "extension", Texts: `["gz"]`}, true},
+		{db.Rule{Rule: "contains", Texts: `["."]`}, db.Rule{Rule: "extension", Texts: `["gz"]`}, true},
+		{db.Rule{Rule: "starts_with", Texts: `["IMG"]`, CaseSensitive: true}, db.Rule{Rule: "starts_with", Texts: `["IMG_"]`}, false},
+		{db.Rule{Rule: "starts_with", Texts: `["IMG"]`, CaseSensitive: true}, db.Rule{Rule: "starts_with", Texts: `["IMG_"]`, CaseSensitive: true}, true},
+		{db.Rule{Rule: "size", Texts: `[">1MB"]`}, db.Rule{Rule: "size", Texts: `["2MB..3MB"]`}, true},
+		{db.Rule{Rule: "size", Texts: `[">1MB"]`}, db.Rule{Rule: "size", Texts: `["<3MB"]`}, false},
+		{db.Rule{Rule: "regex", Texts: `["^a"]`}, db.Rule{Rule: "regex", Texts: `["^a"]`}, true},
+		{db.Rule{Rule: "regex", Texts: `["^a"]`}, db.Rule{Rule: "starts_with", Texts: `["a"]`}, false},
+		{db.Rule{Rule: "metadata", Texts: `["gps exists"]`}, db.Rule{Rule: "metadata", Texts: `["gps = true"]`}, true},
+		{db.Rule{Rule: "metadata", Texts: `["camera_model = x100v"]`}, db.Rule{Rule: "metadata", Texts: `["camera_model = X100V"]`}, true},
+		{db.Rule{Rule: "metadata", Texts: `["gps = true"]`}, db.Rule{Rule: "metadata", Texts: `["gps exists"]`}, false},
+	}
+	for _, tt := range tests {
+		a, err := compileRule(tt.a)
+		if err != nil {
+			t.Fatalf("compileRule(%+v) error = %v", tt.a, err)
+		}
+		b, err := compileRule(tt.b)
+		if err != nil {
+			t.Fatalf("compileRule(%+v) error = %v", tt.b, err)
+		}
+		if got := covers(a, 0, b, 0); got != tt.expected {
+			t.Errorf("covers(%s %s, %s %s) = %v, want %v", tt.a.Rule, tt.a.Texts, tt.b.Rule, tt.b.Texts, got, tt.expected)
+		}
+	}
+}
+
+func refNames(refs []RuleRef) []string {
+	names := make([]string, len(refs))
+	for i, r := range refs {
+		names[i] = r.RuleName
+	}
+	return names
+}
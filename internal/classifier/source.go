@@ -0,0 +1,64 @@
+package classifier
+
+import (
+	"context"
+	"kalycs/db"
+	"kalycs/internal/logging"
+	"kalycs/internal/provenance"
+)
+
+// KindSourceDomain is the rule kind matching the site a file was downloaded
+// from, e.g. "github.com", which covers its subdomains too. Both the download
+// URL and the page linking to it are tried, as downloads are often served
+// from another host than the page they are offered on.
+const KindSourceDomain = "source_domain"
+
+// detectSource returns where the file at path was downloaded from. The
+// source recorded on the file's row stands in when the file no longer
+// carries one.
+func detectSource(existing *db.File, path string) provenance.Source {
+	s, err := provenance.Read(path)
+	if err != nil {
+		logging.L().Warnw("Failed to read file download source", "file_path", path, "error", err)
+	}
+	if s == (provenance.Source{}) && existing != nil {
+		s = fileSource(*existing)
+	}
+	return s
+}
+
+// ensureSource reads the source of an indexed file that has none recorded,
+// such as one indexed before sources were recorded, recording it when
+// persist is set
+func (c *Classifier) ensureSource(ctx context.Context, f *db.File, persist bool) {
+	if f.OriginURL != "" || f.ReferrerURL != "" {
+		return
+	}
+	s, err := provenance.Read(f.Path)
+	if err != nil || s == (provenance.Source{}) {
+		return
+	}
+	f.OriginURL, f.ReferrerURL = s.OriginURL, s.ReferrerURL
+	if !persist {
+		return
+	}
+	if err := c.store.File.SetSource(ctx, f.ID, s.OriginURL, s.ReferrerURL); err != nil {
+		logging.L().Warnw("Failed to record file download source", "file_id", f.ID, "error", err)
+	}
+}
+
+func fileSource(f db.File) provenance.Source {
+	return provenance.Source{OriginURL: f.OriginURL, ReferrerURL: f.ReferrerURL}
+}
+
+// matchedSourceDomain returns the domain that one of hosts belongs to
+func matchedSourceDomain(r CompiledRule, hosts []string) (string, bool) {
+	for _, t := range r.Texts {
+		for _, h := range hosts {
+			if provenance.MatchDomain(h, t) {
+				return t, true
+			}
+		}
+	}
+	return "", false
+}
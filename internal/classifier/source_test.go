@@ -0,0 +1,85 @@
+package classifier
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"kalycs/db"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+func TestMatchedSourceDomain(t *testing.T) {
+	cr, err := compileRule(db.Rule{ID: "r", ProjectID: "p", Rule: KindSourceDomain, Texts: mustJSON(t, []string{"github.com", "slack.com"})})
+	if err != nil {
+		t.Fatalf("compileRule error: %v", err)
+	}
+	tests := []struct {
+		hosts []string
+		want  string
+	}{
+		{[]string{"github.com"}, "github.com"},
+		{[]string{"objects.githubusercontent.com", "github.com"}, "github.com"}, // matched by the referring page
+		{[]string{"files.slack.com"}, "slack.com"},
+		{[]string{"notgithub.com"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		got, ok := matchedText(cr, candidate{sources: tt.hosts})
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("matchedText(%v) = %q, %v; want %q", tt.hosts, got, ok, tt.want)
+		}
+	}
+}
+
+func TestReclassify_SourceDomainRule(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	dev := &db.Project{Name: "Dev", IsActive: true}
+	if err := s.Project.Create(ctx, dev); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	rule := &db.Rule{Name: "GitHub", ProjectID: dev.ID, Rule: KindSourceDomain, Texts: mustJSON(t, []string{"github.com"})}
+	if err := s.Rule.Create(ctx, rule); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+
+	dir := t.TempDir()
+	zip := filepath.Join(dir, "main.zip")
+	other := filepath.Join(dir, "setup.exe")
+	for _, path := range []string{zip, other} {
+		classifyNewFile(t, ctx, c, path)
+	}
+
+	// The source recorded on the row decides, whatever the file carries now
+	f, _ := s.File.GetByPath(ctx, zip)
+	if err := s.File.SetSource(ctx, f.ID, "https://codeload.github.com/owner/repo/zip/main", "https://github.com/owner/repo"); err != nil {
+		t.Fatalf("SetSource() error = %v", err)
+	}
+	g, _ := s.File.GetByPath(ctx, other)
+	if err := s.File.SetSource(ctx, g.ID, "https://example.com/setup.exe", ""); err != nil {
+		t.Fatalf("SetSource() error = %v", err)
+	}
+
+	result, err := c.Reclassify(ctx, ReclassifyScope{Kind: ScopeAll}, false)
+	if err != nil {
+		t.Fatalf("Reclassify() error = %v", err)
+	}
+	if len(result.Moves) != 1 || result.Moves[0].FileID != f.ID || result.Moves[0].ToProjectID != dev.ID {
+		t.Fatalf("Reclassify() moves = %+v, want main.zip to Dev", result.Moves)
+	}
+	f, _ = s.File.GetByID(ctx, f.ID)
+	if f.MatchKind != KindSourceDomain || f.MatchText != "github.com" {
+		t.Errorf("match = %s %q, want source_domain \"github.com\"", f.MatchKind, f.MatchText)
+	}
+}
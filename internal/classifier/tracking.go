@@ -0,0 +1,45 @@
+package classifier
+
+import (
+	"context"
+	"kalycs/db"
+	"kalycs/internal/logging"
+)
+
+// TrackedUnder returns the present files recorded at path or beneath it
+func (c *Classifier) TrackedUnder(ctx context.Context, path string) ([]db.File, error) {
+	return c.store.File.ListUnderPath(ctx, path)
+}
+
+// TrackedFile returns the tracked file with the given ID, or nil if there is none
+func (c *Classifier) TrackedFile(ctx context.Context, fileID string) (*db.File, error) {
+	return c.store.File.GetByID(ctx, fileID)
+}
+
+// IsActionSource reports whether path was left in place as the source of a copy
+// or link, meaning it has already been classified
+func (c *Classifier) IsActionSource(ctx context.Context, path string) (bool, error) {
+	return c.store.Action.IsLiveSource(ctx, path)
+}
+
+// MarkMissing records that a tracked file no longer exists on disk
+func (c *Classifier) MarkMissing(ctx context.Context, fileID string) error {
+	return c.store.File.MarkDeleted(ctx, fileID)
+}
+
+// Relocate points a tracked file at the path it was renamed or moved to, so the
+// row keeps its ID, assignment and history instead of a duplicate being created.
+// A row already tracking newPath belongs to a file that was replaced and is removed.
+func (c *Classifier) Relocate(ctx context.Context, fileID, newPath string) error {
+	existing, err := c.store.File.GetByPath(ctx, newPath)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != fileID {
+		logging.L().Infow("Dropping row of replaced file", "file_id", existing.ID, "path", newPath)
+		if err := c.store.File.Delete(ctx, existing.ID); err != nil {
+			return err
+		}
+	}
+	return c.store.File.UpdatePath(ctx, fileID, newPath)
+}
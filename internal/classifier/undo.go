@@ -0,0 +1,201 @@
+package classifier
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"kalycs/db"
+	"kalycs/internal/fileops"
+	"kalycs/internal/logging"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type batchIDKey struct{}
+
+// WithBatchID returns a context whose classifications are journaled under batchID,
+// so they can be undone together.
+func WithBatchID(ctx context.Context, batchID string) context.Context {
+	return context.WithValue(ctx, batchIDKey{}, batchID)
+}
+
+func batchIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(batchIDKey{}).(string)
+	return id
+}
+
+// UndoConflict describes a journaled action that could not be undone
+type UndoConflict struct {
+	ActionID string `json:"action_id"`
+	Path     string `json:"path"`
+	Reason   string `json:"reason"`
+}
+
+// UndoResult summarises an undo request
+type UndoResult struct {
+	Undone    []string       `json:"undone"`
+	Conflicts []UndoConflict `json:"conflicts"`
+}
+
+// recordAction appends a performed file action to the journal. Journal failures
+// are logged rather than returned since the file itself was handled correctly.
+func (c *Classifier) recordAction(ctx context.Context, fileID, action, oldPath, newPath, ruleID string) {
+	a := &db.FileAction{
+		FileID:  fileID,
+		Action:  action,
+		OldPath: oldPath,
+		NewPath: newPath,
+	}
+	if ruleID != "" {
+		a.RuleID = sql.NullString{String: ruleID, Valid: true}
+	}
+	if batchID := batchIDFrom(ctx); batchID != "" {
+		a.BatchID = sql.NullString{String: batchID, Valid: true}
+	}
+	if info, err := os.Lstat(newPath); err == nil {
+		a.Size = info.Size()
+		a.Mtime = sql.NullTime{Time: info.ModTime(), Valid: true}
+	}
+	if err := c.store.Action.Record(ctx, a); err != nil {
+		logging.L().Errorw("Failed to journal file action", "file_id", fileID, "action", action, "error", err)
+	}
+}
+
+// UndoAction reverts a single journaled action
+func (c *Classifier) UndoAction(ctx context.Context, actionID string) (UndoResult, error) {
+	a, err := c.store.Action.GetByID(ctx, actionID)
+	if err != nil {
+		return UndoResult{}, err
+	}
+	if a == nil {
+		return UndoResult{}, fmt.Errorf("file action with ID '%s' not found", actionID)
+	}
+	return c.undoAll(ctx, []db.FileAction{*a})
+}
+
+// UndoBatch reverts every action recorded under batchID, newest first
+func (c *Classifier) UndoBatch(ctx context.Context, batchID string) (UndoResult, error) {
+	actions, err := c.store.Action.ListByBatch(ctx, batchID)
+	if err != nil {
+		return UndoResult{}, err
+	}
+	return c.undoAll(ctx, actions)
+}
+
+// UndoSince reverts every action recorded at or after since, newest first
+func (c *Classifier) UndoSince(ctx context.Context, since time.Time) (UndoResult, error) {
+	actions, err := c.store.Action.ListSince(ctx, since)
+	if err != nil {
+		return UndoResult{}, err
+	}
+	return c.undoAll(ctx, actions)
+}
+
+// undoAll reverts actions in the given order, collecting conflicts instead of stopping at them
+func (c *Classifier) undoAll(ctx context.Context, actions []db.FileAction) (UndoResult, error) {
+	result := UndoResult{Undone: []string{}, Conflicts: []UndoConflict{}}
+	for _, a := range actions {
+		if a.UndoneAt.Valid {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		reason, err := c.undoOne(ctx, a)
+		if err != nil {
+			return result, err
+		}
+		if reason != "" {
+			logging.L().Warnw("File action could not be undone", "action_id", a.ID, "reason", reason)
+			result.Conflicts = append(result.Conflicts, UndoConflict{ActionID: a.ID, Path: a.OldPath, Reason: reason})
+			continue
+		}
+		result.Undone = append(result.Undone, a.ID)
+	}
+
+	logging.L().Infow("Undo completed", "undone", len(result.Undone), "conflicts", len(result.Conflicts))
+	return result, nil
+}
+
+// undoOne reverts a single action. A non-empty reason reports a conflict that
+// left the filesystem untouched; an error reports an unexpected failure.
+func (c *Classifier) undoOne(ctx context.Context, a db.FileAction) (string, error) {
+	if _, err := os.Lstat(a.NewPath); err != nil {
+		if os.IsNotExist(err) {
+			return "file is no longer at " + a.NewPath, nil
+		}
+		return "", err
+	}
+
+	switch a.Action {
+	case fileops.ActionMove, fileops.ActionRename:
+		if _, err := os.Lstat(a.OldPath); err == nil {
+			return "original path is occupied", nil
+		}
+		// The original directory may have been removed since
+		if err := os.MkdirAll(filepath.Dir(a.OldPath), 0755); err != nil {
+			return "original directory cannot be recreated: " + err.Error(), nil
+		}
+		if err := fileops.Move(a.NewPath, a.OldPath); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return "original path is occupied", nil
+			}
+			return "failed to move file back: " + err.Error(), nil
+		}
+		fileops.ForgetTrashInfo(a.NewPath)
+	case fileops.ActionCopy, fileops.ActionHardlink, fileops.ActionSymlink:
+		// The original stays in place for these actions; removing the
+		// result is only safe while the original still exists.
+		if _, err := os.Stat(a.OldPath); err != nil {
+			return "original file no longer exists", nil
+		}
+		// Edits made to a copy exist nowhere else
+		if a.Action == fileops.ActionCopy && a.Mtime.Valid {
+			info, err := os.Lstat(a.NewPath)
+			if err != nil {
+				return "", err
+			}
+			if info.Size() != a.Size || !info.ModTime().Equal(a.Mtime.Time) {
+				return "copy was modified", nil
+			}
+		}
+		if err := os.Remove(a.NewPath); err != nil {
+			return "", fmt.Errorf("failed to remove %s: %w", a.NewPath, err)
+		}
+	default:
+		return "unknown action " + a.Action, nil
+	}
+
+	if err := c.restoreFileRow(ctx, a); err != nil {
+		return "", err
+	}
+	if err := c.store.Action.MarkUndone(ctx, a.ID); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+// restoreFileRow points the journaled file back at its original path
+func (c *Classifier) restoreFileRow(ctx context.Context, a db.FileAction) error {
+	f, err := c.store.File.GetByID(ctx, a.FileID)
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return nil
+	}
+
+	existing, err := c.store.File.GetByPath(ctx, a.OldPath)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != a.FileID {
+		// The original path is already tracked; drop the row for the undone result.
+		return c.store.File.Delete(ctx, a.FileID)
+	}
+	return c.store.File.UpdatePath(ctx, a.FileID, a.OldPath)
+}
@@ -0,0 +1,241 @@
+package classifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kalycs/db"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+// setupMoveProject creates a classifier with a project that moves files matching "invoice"
+func setupMoveProject(t *testing.T) (*Classifier, *store.Store, string) {
+	t.Helper()
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	destDir := t.TempDir()
+	project := &db.Project{Name: "Invoices", IsActive: true, Action: "move", DestinationDir: destDir}
+	if err := s.Project.Create(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	rule := &db.Rule{Name: "Invoices", ProjectID: project.ID, Rule: "starts_with", Texts: mustJSON(t, []string{"invoice"})}
+	if err := s.Rule.Create(ctx, rule); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	return c, s, destDir
+}
+
+func classifyNewFile(t *testing.T, ctx context.Context, c *Classifier, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("content"), 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat %s: %v", path, err)
+	}
+	if err := c.Classify(ctx, path, info); err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+}
+
+func TestUndoBatch_RestoresMovedFiles(t *testing.T) {
+	c, s, destDir := setupMoveProject(t)
+	srcDir := t.TempDir()
+	ctx := WithBatchID(context.Background(), "batch-1")
+
+	original := filepath.Join(srcDir, "invoice-1.pdf")
+	classifyNewFile(t, ctx, c, original)
+
+	moved := filepath.Join(destDir, "invoice-1.pdf")
+	if _, err := os.Stat(moved); err != nil {
+		t.Fatalf("file was not moved: %v", err)
+	}
+
+	result, err := c.UndoBatch(context.Background(), "batch-1")
+	if err != nil {
+		t.Fatalf("UndoBatch() error = %v", err)
+	}
+	if len(result.Undone) != 1 || len(result.Conflicts) != 0 {
+		t.Fatalf("UndoBatch() = %+v, want one undone action", result)
+	}
+	if _, err := os.Stat(original); err != nil {
+		t.Errorf("file was not moved back: %v", err)
+	}
+
+	f, err := s.File.GetByPath(context.Background(), original)
+	if err != nil || f == nil {
+		t.Fatalf("file row not restored to original path: %v", err)
+	}
+
+	// A second undo is a no-op
+	result, err = c.UndoBatch(context.Background(), "batch-1")
+	if err != nil {
+		t.Fatalf("second UndoBatch() error = %v", err)
+	}
+	if len(result.Undone) != 0 {
+		t.Errorf("second UndoBatch() undid %d actions, want 0", len(result.Undone))
+	}
+
+	// Reclassifying the restored file leaves it where the user put it
+	info, _ := os.Stat(original)
+	if err := c.Classify(context.Background(), original, info); err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if _, err := os.Stat(original); err != nil {
+		t.Errorf("restored file was moved again: %v", err)
+	}
+}
+
+func TestUndoAction_ConflictWhenOriginalOccupied(t *testing.T) {
+	c, s, _ := setupMoveProject(t)
+	srcDir := t.TempDir()
+	ctx := context.Background()
+
+	original := filepath.Join(srcDir, "invoice-2.pdf")
+	classifyNewFile(t, ctx, c, original)
+
+	actions, err := s.Action.ListRecent(ctx, 10)
+	if err != nil || len(actions) != 1 {
+		t.Fatalf("expected one journaled action, got %d (err %v)", len(actions), err)
+	}
+
+	// Something new now lives at the original path
+	if err := os.WriteFile(original, []byte("other"), 0600); err != nil {
+		t.Fatalf("failed to occupy original path: %v", err)
+	}
+
+	result, err := c.UndoAction(ctx, actions[0].ID)
+	if err != nil {
+		t.Fatalf("UndoAction() error = %v", err)
+	}
+	if len(result.Conflicts) != 1 || len(result.Undone) != 0 {
+		t.Fatalf("UndoAction() = %+v, want one conflict", result)
+	}
+	if b, _ := os.ReadFile(original); string(b) != "other" {
+		t.Error("file at original path was overwritten")
+	}
+	if _, err := os.Stat(actions[0].NewPath); err != nil {
+		t.Errorf("moved file should stay in place on conflict: %v", err)
+	}
+}
+
+func TestClassify_CopySourceNotCopiedAgain(t *testing.T) {
+	c, s, destDir := setupMoveProject(t)
+	ctx := context.Background()
+	setProjectAction(t, s, "copy")
+
+	original := filepath.Join(t.TempDir(), "invoice-1.pdf")
+	classifyNewFile(t, ctx, c, original)
+
+	// The watcher reports the source again, e.g. after a write that kept its size and mtime
+	info, err := os.Stat(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Classify(ctx, original, info); err != nil {
+		t.Fatalf("Classify(source) error = %v", err)
+	}
+
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("destination holds %d files, want a single copy", len(entries))
+	}
+	if f, err := s.File.GetByPath(ctx, original); err != nil || f != nil {
+		t.Errorf("source of the copy got a row: %+v, %v", f, err)
+	}
+}
+
+// setProjectAction switches the project of setupMoveProject to action
+func setProjectAction(t *testing.T, s *store.Store, action string) {
+	t.Helper()
+	ctx := context.Background()
+	projects, err := s.Project.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range projects {
+		if p.Name == "Invoices" {
+			p.Action = action
+			if err := s.Project.Update(ctx, &p); err != nil {
+				t.Fatalf("failed to update project: %v", err)
+			}
+		}
+	}
+}
+
+func TestUndoAction_KeepsModifiedCopy(t *testing.T) {
+	c, s, _ := setupMoveProject(t)
+	ctx := context.Background()
+	setProjectAction(t, s, "copy")
+
+	srcDir := t.TempDir()
+	for _, name := range []string{"invoice-1.pdf", "invoice-2.pdf"} {
+		classifyNewFile(t, ctx, c, filepath.Join(srcDir, name))
+	}
+	actions, err := s.Action.ListRecent(ctx, 10)
+	if err != nil || len(actions) != 2 {
+		t.Fatalf("expected two journaled copies, got %d (err %v)", len(actions), err)
+	}
+	edited, untouched := actions[0], actions[1]
+	if err := os.WriteFile(edited.NewPath, []byte("edited copy"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.UndoAction(ctx, edited.ID)
+	if err != nil || len(result.Conflicts) != 1 || result.Conflicts[0].Reason != "copy was modified" {
+		t.Fatalf("UndoAction(edited) = %+v, %v; want a conflict", result, err)
+	}
+	if b, _ := os.ReadFile(edited.NewPath); string(b) != "edited copy" {
+		t.Error("edited copy was removed")
+	}
+
+	result, err = c.UndoAction(ctx, untouched.ID)
+	if err != nil || len(result.Undone) != 1 {
+		t.Fatalf("UndoAction(untouched) = %+v, %v; want it undone", result, err)
+	}
+	if _, err := os.Stat(untouched.NewPath); !os.IsNotExist(err) {
+		t.Errorf("untouched copy still exists: %v", err)
+	}
+}
+
+func TestUndoBatch_RecreatesOriginalDirectory(t *testing.T) {
+	c, s, _ := setupMoveProject(t)
+	ctx := WithBatchID(context.Background(), "batch-1")
+
+	srcDir := filepath.Join(t.TempDir(), "downloads")
+	if err := os.Mkdir(srcDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	original := filepath.Join(srcDir, "invoice-1.pdf")
+	classifyNewFile(t, ctx, c, original)
+	if err := os.Remove(srcDir); err != nil {
+		t.Fatalf("failed to remove the emptied directory: %v", err)
+	}
+
+	result, err := c.UndoBatch(context.Background(), "batch-1")
+	if err != nil || len(result.Undone) != 1 || len(result.Conflicts) != 0 {
+		t.Fatalf("UndoBatch() = %+v, %v; want the move undone", result, err)
+	}
+	if _, err := os.Stat(original); err != nil {
+		t.Errorf("file was not moved back: %v", err)
+	}
+	if f, err := s.File.GetByPath(context.Background(), original); err != nil || f == nil {
+		t.Errorf("file row not restored: %+v, %v", f, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Package condition defines the condition trees of composite rules.
+//
+// A tree is stored as JSON on the rule. Inner nodes combine their children
+// with "and", "or" or "not"; leaves ("match") hold a single rule kind with its
+// texts, which are OR-ed exactly like a plain rule's texts:
+//
+//	{"op": "and", "children": [
+//	  {"op": "match", "kind": "extension", "texts": ["pdf"]},
+//	  {"op": "match", "kind": "contains", "texts": ["invoice"]},
+//	  {"op": "not", "children": [{"op": "match", "kind": "starts_with", "texts": ["draft"]}]}
+//	]}
+package condition
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Node operators
+const (
+	OpAnd   = "and"
+	OpOr    = "or"
+	OpNot   = "not"
+	OpMatch = "match"
+)
+
+// KindComposite is the rule kind of rules defined by a condition tree
+const KindComposite = "composite"
+
+// Node is one node of a condition tree
+type Node struct {
+	Op            string   `json:"op"`
+	Children      []Node   `json:"children,omitempty"`       // and, or: at least one; not: exactly one
+	Kind          string   `json:"kind,omitempty"`           // match: rule kind, e.g. extension
+	Texts         []string `json:"texts,omitempty"`          // match: alternatives, any may match
+	CaseSensitive bool     `json:"case_sensitive,omitempty"` // match
+}
+
+// Leaf returns a match node equivalent to a plain rule
+func Leaf(kind string, texts []string, caseSensitive bool) Node {
+	return Node{Op: OpMatch, Kind: kind, Texts: texts, CaseSensitive: caseSensitive}
+}
+
+// Parse decodes a condition tree and checks its structure. Leaf contents
+// (kinds and texts) are not checked here; see validation.RuleValidator.
+func Parse(s string) (Node, error) {
+	var n Node
+	if strings.TrimSpace(s) == "" {
+		return n, fmt.Errorf("conditions are empty")
+	}
+	if err := json.Unmarshal([]byte(s), &n); err != nil {
+		return n, fmt.Errorf("invalid conditions format: %w", err)
+	}
+	if err := n.checkStructure(); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// Encode returns the JSON form of the tree
+func (n Node) Encode() (string, error) {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Depth returns the number of levels in the tree; a single leaf has depth 1
+func (n Node) Depth() int {
+	depth := 0
+	for _, c := range n.Children {
+		if d := c.Depth(); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+// Leaves returns pointers to every match node, so callers can normalise them in place
+func (n *Node) Leaves() []*Node {
+	if n.Op == OpMatch {
+		return []*Node{n}
+	}
+	var leaves []*Node
+	for i := range n.Children {
+		leaves = append(leaves, n.Children[i].Leaves()...)
+	}
+	return leaves
+}
+
+func (n *Node) checkStructure() error {
+	n.Op = strings.ToLower(strings.TrimSpace(n.Op))
+	switch n.Op {
+	case OpAnd, OpOr:
+		if len(n.Children) == 0 {
+			return fmt.Errorf("%q condition needs at least one child", n.Op)
+		}
+	case OpNot:
+		if len(n.Children) != 1 {
+			return fmt.Errorf("\"not\" condition needs exactly one child")
+		}
+	case OpMatch:
+		if len(n.Children) != 0 {
+			return fmt.Errorf("\"match\" condition cannot have children")
+		}
+		if n.Kind == KindComposite {
+			return fmt.Errorf("\"match\" condition cannot use the composite kind")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown condition operator %q", n.Op)
+	}
+	for i := range n.Children {
+		if err := n.Children[i].checkStructure(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package condition
+
+import (
+	"strings"
+	"testing"
+)
+
+const leaf = `{"op": "match", "kind": "contains", "texts": ["a"]}`
+
+// nested wraps leaf in depth-1 "not" nodes
+func nested(depth int) string {
+	return strings.Repeat(`{"op": "not", "children": [`, depth-1) + leaf + strings.Repeat(`]}`, depth-1)
+}
+
+// wide returns an "or" node over n leaves
+func wide(n int) string {
+	return `{"op": "or", "children": [` + strings.TrimSuffix(strings.Repeat(leaf+",", n), ",") + `]}`
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions string
+		depth      int
+		leaves     int
+		errMsg     string
+	}{
+		{name: "leaf", conditions: leaf, depth: 1, leaves: 1},
+		{name: "nested and/or/not", conditions: `{"op": "and", "children": [
+			{"op": "match", "kind": "extension", "texts": ["pdf"]},
+			{"op": "or", "children": [
+				{"op": "match", "kind": "contains", "texts": ["invoice"]},
+				{"op": "not", "children": [{"op": "match", "kind": "size", "texts": [">1MB"]}]}
+			]}
+		]}`, depth: 4, leaves: 3},
+		{name: "operator case and spacing", conditions: `{"op": " OR ", "children": [` + leaf + `]}`, depth: 2, leaves: 1},
+		{name: "deep", conditions: nested(8), depth: 8, leaves: 1},
+		{name: "wide", conditions: wide(20), depth: 2, leaves: 20},
+		{name: "empty", conditions: "  ", errMsg: "conditions are empty"},
+		{name: "malformed json", conditions: `{"op": "and", "children": [`, errMsg: "invalid conditions format"},
+		{name: "wrong json type", conditions: `{"op": "match", "texts": "a"}`, errMsg: "invalid conditions format"},
+		{name: "empty and", conditions: `{"op": "and", "children": []}`, errMsg: `"and" condition needs at least one child`},
+		{name: "empty or", conditions: `{"op": "or"}`, errMsg: `"or" condition needs at least one child`},
+		{name: "not without children", conditions: `{"op": "not"}`, errMsg: "exactly one child"},
+		{name: "not with two children", conditions: `{"op": "not", "children": [` + leaf + `,` + leaf + `]}`, errMsg: "exactly one child"},
+		{name: "match with children", conditions: `{"op": "match", "kind": "contains", "children": [` + leaf + `]}`, errMsg: "cannot have children"},
+		{name: "composite leaf", conditions: `{"op": "match", "kind": "composite", "texts": ["a"]}`, errMsg: "cannot use the composite kind"},
+		{name: "unknown operator", conditions: `{"op": "xor", "children": [` + leaf + `]}`, errMsg: "unknown condition operator"},
+		{name: "invalid nested child", conditions: `{"op": "and", "children": [` + leaf + `, {"op": "not", "children": []}]}`, errMsg: "exactly one child"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := Parse(tt.conditions)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Parse() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if d := n.Depth(); d != tt.depth {
+				t.Errorf("Depth() = %d, want %d", d, tt.depth)
+			}
+			if l := len(n.Leaves()); l != tt.leaves {
+				t.Errorf("len(Leaves()) = %d, want %d", l, tt.leaves)
+			}
+		})
+	}
+}
+
+func TestLeaves_InPlace(t *testing.T) {
+	n, err := Parse(`{"op": "and", "children": [` + leaf + `, {"op": "not", "children": [` + leaf + `]}]}`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	for _, l := range n.Leaves() {
+		l.Texts = []string{"b"}
+	}
+	s, err := n.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if strings.Contains(s, `"a"`) || strings.Count(s, `"b"`) != 2 {
+		t.Errorf("Encode() after changing leaves = %s, want both leaves changed", s)
+	}
+
+	again, err := Parse(s)
+	if err != nil || again.Depth() != 3 || len(again.Leaves()) != 2 {
+		t.Errorf("Parse(Encode()) = %+v, %v; want the same tree", again, err)
+	}
+}
+
+func TestLeaf(t *testing.T) {
+	n := Leaf("extension", []string{"pdf"}, true)
+	if n.Op != OpMatch || n.Depth() != 1 || len(n.Leaves()) != 1 || !n.CaseSensitive {
+		t.Errorf("Leaf() = %+v, want a single case-sensitive match node", n)
+	}
+}
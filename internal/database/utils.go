@@ -1,6 +1,7 @@
 package database
 
 import (
+	"path/filepath"
 	"time"
 
 	"kalycs/db"
@@ -55,6 +56,10 @@ func PrepareRuleForUpdate(rule *db.Rule) {
 func NormalizeProjectData(project *db.Project) {
 	project.Name = normalizeString(project.Name)
 	project.Description = normalizeString(project.Description)
+	project.DestinationDir = normalizeString(project.DestinationDir)
+	if project.Action == "" {
+		project.Action = "none"
+	}
 }
 
 // NormalizeRuleData normalizes rule data by trimming whitespace
@@ -63,6 +68,17 @@ func NormalizeRuleData(rule *db.Rule) {
 	rule.Texts = normalizeString(rule.Texts)
 }
 
+// NormalizeWatchRootData normalizes watch root data by trimming and cleaning the path
+func NormalizeWatchRootData(root *db.WatchRoot) {
+	root.Path = normalizeString(root.Path)
+	if root.Path != "" {
+		root.Path = filepath.Clean(root.Path)
+	}
+	if normalizeString(root.IgnorePatterns) == "" {
+		root.IgnorePatterns = "[]"
+	}
+}
+
 // normalizeString trims whitespace from a string
 func normalizeString(s string) string {
 	if s == "" {
@@ -0,0 +1,210 @@
+package dedupe
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"kalycs/internal/classifier"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+func setupDuplicates(t *testing.T, contents map[string]string) (*store.Store, *classifier.Classifier, map[string]string) {
+	t.Helper()
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := classifier.NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	dir := t.TempDir()
+	paths := make(map[string]string, len(contents))
+	for name, content := range contents {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", name, err)
+		}
+		if err := c.Classify(ctx, p, info); err != nil {
+			t.Fatalf("Classify() error = %v", err)
+		}
+		paths[name] = p
+	}
+
+	h := NewHasher(ctx, s, 2)
+	h.Start()
+	defer h.Stop()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if unhashed, _ := s.File.ListUnhashed(ctx); len(unhashed) == 0 {
+			return s, c, paths
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for files to be hashed")
+	return nil, nil, nil
+}
+
+func TestHashFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(p, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := HashFile(p)
+	if err != nil {
+		t.Fatalf("HashFile() error = %v", err)
+	}
+	if want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"; got != want {
+		t.Errorf("HashFile() = %s, want %s", got, want)
+	}
+}
+
+func TestFindGroups(t *testing.T) {
+	s, _, paths := setupDuplicates(t, map[string]string{
+		"report.pdf":     "quarterly numbers",
+		"report (1).pdf": "quarterly numbers",
+		"report (2).pdf": "quarterly numbers",
+		"other.pdf":      "something else",
+	})
+	ctx := context.Background()
+
+	groups, err := FindGroups(ctx, s)
+	if err != nil {
+		t.Fatalf("FindGroups() error = %v", err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("FindGroups() returned %d groups, want 1", len(groups))
+	}
+	size := int64(len("quarterly numbers"))
+	if g := groups[0]; len(g.Files) != 3 || g.Size != size || g.Reclaimable != 2*size {
+		t.Errorf("group = %d files, size %d, reclaimable %d; want 3, %d, %d", len(g.Files), g.Size, g.Reclaimable, size, 2*size)
+	}
+
+	// Copies that are already hardlinks of each other share storage
+	if err := os.Remove(paths["report (1).pdf"]); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(paths["report.pdf"], paths["report (1).pdf"]); err != nil {
+		t.Skipf("hardlinks not supported: %v", err)
+	}
+	groups, _ = FindGroups(ctx, s)
+	if len(groups) != 1 || groups[0].Reclaimable != size {
+		t.Errorf("reclaimable with a hardlinked copy = %+v, want %d", groups, size)
+	}
+}
+
+func TestResolve_Hardlink(t *testing.T) {
+	s, _, paths := setupDuplicates(t, map[string]string{
+		"a.iso": "disk image",
+		"b.iso": "disk image",
+	})
+	ctx := context.Background()
+
+	groups, err := FindGroups(ctx, s)
+	if err != nil || len(groups) != 1 {
+		t.Fatalf("FindGroups() = %v, %v", groups, err)
+	}
+	keep, _ := s.File.GetByPath(ctx, paths["a.iso"])
+
+	result, err := Resolve(ctx, s, groups[0].Hash, keep.ID, ResolveHardlink)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(result.Resolved) != 1 || len(result.Failures) != 0 || result.Reclaimed != int64(len("disk image")) {
+		t.Fatalf("Resolve() = %+v", result)
+	}
+
+	a, _ := os.Stat(paths["a.iso"])
+	b, _ := os.Stat(paths["b.iso"])
+	if !os.SameFile(a, b) {
+		t.Error("copy was not replaced with a hardlink")
+	}
+	if groups, _ := FindGroups(ctx, s); len(groups) != 0 {
+		t.Errorf("FindGroups() after resolving = %+v, want none", groups)
+	}
+}
+
+func TestResolve_TrashAndUndo(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("trash is not supported on Windows")
+	}
+	s, c, paths := setupDuplicates(t, map[string]string{
+		"photo.jpg":       "pixels",
+		"photo-copy.jpg":  "pixels",
+		"photo-edited.jp": "different pixels",
+	})
+	ctx := context.Background()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	groups, _ := FindGroups(ctx, s)
+	if len(groups) != 1 {
+		t.Fatalf("FindGroups() returned %d groups, want 1", len(groups))
+	}
+	keep, _ := s.File.GetByPath(ctx, paths["photo.jpg"])
+	copyRow, _ := s.File.GetByPath(ctx, paths["photo-copy.jpg"])
+
+	result, err := Resolve(ctx, s, groups[0].Hash, keep.ID, ResolveTrash)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(result.Resolved) != 1 || result.Resolved[0] != copyRow.ID {
+		t.Fatalf("Resolve() = %+v", result)
+	}
+	if _, err := os.Stat(paths["photo-copy.jpg"]); !os.IsNotExist(err) {
+		t.Error("copy is still in place after trashing")
+	}
+	if f, _ := s.File.GetByID(ctx, copyRow.ID); f == nil || !f.DeletedAt.Valid {
+		t.Errorf("trashed copy was not marked missing: %+v", f)
+	}
+
+	undo, err := c.UndoBatch(ctx, result.BatchID)
+	if err != nil || len(undo.Undone) != 1 {
+		t.Fatalf("UndoBatch() = %+v, %v", undo, err)
+	}
+	if _, err := os.Stat(paths["photo-copy.jpg"]); err != nil {
+		t.Errorf("trashed copy was not restored: %v", err)
+	}
+	if f, _ := s.File.GetByID(ctx, copyRow.ID); f == nil || f.DeletedAt.Valid {
+		t.Errorf("restored copy is still marked missing: %+v", f)
+	}
+	info, _ := filepath.Glob(filepath.Join(os.Getenv("XDG_DATA_HOME"), "Trash", "info", "*"))
+	if runtime.GOOS != "darwin" && len(info) != 0 {
+		t.Errorf("trash info files left behind: %v", info)
+	}
+}
+
+func TestResolve_SkipsChangedCopies(t *testing.T) {
+	s, _, paths := setupDuplicates(t, map[string]string{
+		"x.bin": "payload",
+		"y.bin": "payload",
+	})
+	ctx := context.Background()
+	groups, _ := FindGroups(ctx, s)
+	if len(groups) != 1 {
+		t.Fatalf("FindGroups() returned %d groups, want 1", len(groups))
+	}
+	keep, _ := s.File.GetByPath(ctx, paths["x.bin"])
+
+	if err := os.WriteFile(paths["y.bin"], []byte("payload v2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Resolve(ctx, s, groups[0].Hash, keep.ID, ResolveHardlink)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(result.Resolved) != 0 || len(result.Failures) != 1 {
+		t.Errorf("Resolve() = %+v, want the changed copy reported", result)
+	}
+	if _, err := Resolve(ctx, s, groups[0].Hash, keep.ID, "delete"); err == nil {
+		t.Error("Resolve() accepted an unknown mode")
+	}
+}
@@ -0,0 +1,204 @@
+package dedupe
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"kalycs/db"
+	"kalycs/internal/database"
+	"kalycs/internal/fileops"
+	"kalycs/internal/logging"
+	"kalycs/internal/store"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Ways of resolving a duplicate group
+const (
+	ResolveTrash    = "trash"    // move the other copies to the trash
+	ResolveHardlink = "hardlink" // replace the other copies with hardlinks to the kept file
+)
+
+// Group is a set of files with identical content
+type Group struct {
+	Hash  string    `json:"hash"`
+	Size  int64     `json:"size"` // size of one copy in bytes
+	Files []db.File `json:"files"`
+	// Reclaimable is the disk space freed by keeping a single copy. Files that
+	// are already hardlinks of each other share storage and do not count.
+	Reclaimable int64 `json:"reclaimable"`
+}
+
+// ResolveFailure explains why a copy was left in place
+type ResolveFailure struct {
+	FileID string `json:"file_id"`
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ResolveResult summarises a Resolve call
+type ResolveResult struct {
+	BatchID   string           `json:"batch_id"` // journal batch of trashed copies, for UndoBatch
+	Resolved  []string         `json:"resolved"` // IDs of files trashed or replaced by hardlinks
+	Failures  []ResolveFailure `json:"failures"`
+	Reclaimed int64            `json:"reclaimed"`
+}
+
+// FindGroups returns every group of two or more present files with the same
+// content, across all projects. Groups whose copies all share one inode are
+// omitted since there is nothing left to reclaim.
+func FindGroups(ctx context.Context, s *store.Store) ([]Group, error) {
+	files, err := s.File.ListDuplicates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []Group
+	for i := 0; i < len(files); {
+		j := i
+		for j < len(files) && files[j].Hash.String == files[i].Hash.String {
+			j++
+		}
+		g := Group{Hash: files[i].Hash.String, Size: files[i].Size, Files: files[i:j]}
+		g.Reclaimable = g.Size * int64(distinctInodes(g.Files)-1)
+		if g.Reclaimable > 0 {
+			groups = append(groups, g)
+		}
+		i = j
+	}
+	return groups, nil
+}
+
+// distinctInodes counts the files that do not share storage with an earlier file in the list
+func distinctInodes(files []db.File) int {
+	var seen []os.FileInfo
+	for _, f := range files {
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			continue
+		}
+		shared := false
+		for _, s := range seen {
+			if os.SameFile(s, info) {
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			seen = append(seen, info)
+		}
+	}
+	return len(seen)
+}
+
+// Resolve keeps the file keepID of the group with the given hash and trashes
+// or hardlinks every other copy. Each copy is hashed again first so a file
+// that changed since it was indexed is never removed. Trashed copies are
+// journaled as moves under one batch and can be restored with UndoBatch.
+func Resolve(ctx context.Context, s *store.Store, hash, keepID, mode string) (ResolveResult, error) {
+	if mode != ResolveTrash && mode != ResolveHardlink {
+		return ResolveResult{}, fmt.Errorf("unknown resolve mode %q", mode)
+	}
+
+	files, err := s.File.ListDuplicates(ctx)
+	if err != nil {
+		return ResolveResult{}, err
+	}
+	var keep *db.File
+	var copies []db.File
+	for i, f := range files {
+		if f.Hash.String != hash {
+			continue
+		}
+		if f.ID == keepID {
+			keep = &files[i]
+		} else {
+			copies = append(copies, f)
+		}
+	}
+	if keep == nil {
+		return ResolveResult{}, fmt.Errorf("file with ID '%s' is not part of duplicate group %s", keepID, hash)
+	}
+	if sum, err := HashFile(keep.Path); err != nil || sum != hash {
+		return ResolveResult{}, fmt.Errorf("kept file %s no longer matches the group", keep.Path)
+	}
+	keepInfo, err := os.Stat(keep.Path)
+	if err != nil {
+		return ResolveResult{}, err
+	}
+
+	result := ResolveResult{Resolved: []string{}, Failures: []ResolveFailure{}}
+	if mode == ResolveTrash {
+		result.BatchID = database.GenerateID()
+	}
+	for _, f := range copies {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			result.Failures = append(result.Failures, ResolveFailure{f.ID, f.Path, "file is no longer on disk"})
+			continue
+		}
+		if os.SameFile(info, keepInfo) {
+			// Already a hardlink of the kept file
+			continue
+		}
+		if sum, err := HashFile(f.Path); err != nil || sum != hash {
+			result.Failures = append(result.Failures, ResolveFailure{f.ID, f.Path, "content changed since it was indexed"})
+			continue
+		}
+
+		switch mode {
+		case ResolveTrash:
+			err = trashCopy(ctx, s, f, result.BatchID)
+		case ResolveHardlink:
+			err = linkCopy(keep.Path, f.Path)
+		}
+		if err != nil {
+			logging.L().Errorw("Failed to resolve duplicate", "file_id", f.ID, "path", f.Path, "mode", mode, "error", err)
+			result.Failures = append(result.Failures, ResolveFailure{f.ID, f.Path, err.Error()})
+			continue
+		}
+		result.Resolved = append(result.Resolved, f.ID)
+		result.Reclaimed += f.Size
+	}
+
+	logging.L().Infow("Duplicate group resolved", "hash", hash, "kept", keep.Path, "mode", mode,
+		"resolved", len(result.Resolved), "failed", len(result.Failures), "reclaimed_bytes", result.Reclaimed)
+	return result, nil
+}
+
+// trashCopy moves f to the trash, journals the move and marks the row missing
+func trashCopy(ctx context.Context, s *store.Store, f db.File, batchID string) error {
+	trashed, err := fileops.Trash(f.Path)
+	if err != nil {
+		return err
+	}
+	a := &db.FileAction{
+		FileID:  f.ID,
+		Action:  fileops.ActionMove,
+		OldPath: f.Path,
+		NewPath: trashed,
+		BatchID: sql.NullString{String: batchID, Valid: true},
+	}
+	if err := s.Action.Record(ctx, a); err != nil {
+		logging.L().Errorw("Failed to journal trashed duplicate", "file_id", f.ID, "error", err)
+	}
+	return s.File.MarkDeleted(ctx, f.ID)
+}
+
+// linkCopy atomically replaces path with a hardlink to keep. The link is made
+// under a temporary name in the same directory and renamed over the copy.
+func linkCopy(keep, path string) error {
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf("%s%d", fileops.TempPrefix, time.Now().UnixNano()))
+	if err := os.Link(keep, tmp); err != nil {
+		return fmt.Errorf("failed to create hardlink: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace copy with hardlink: %w", err)
+	}
+	return nil
+}
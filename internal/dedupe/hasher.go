@@ -0,0 +1,154 @@
+package dedupe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"kalycs/db"
+	"kalycs/internal/logging"
+	"kalycs/internal/store"
+	"os"
+	"sync"
+)
+
+const (
+	// DefaultWorkers is the number of files hashed concurrently. Hashing is
+	// disk bound, so a small pool keeps the machine responsive.
+	DefaultWorkers = 2
+	// queueSize bounds the files waiting to be hashed; files dropped when it is
+	// full are picked up by the backfill on the next start
+	queueSize = 1024
+)
+
+// HashFile returns the hex-encoded SHA-256 of the file's content, read in a streaming fashion
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Hasher computes content hashes for stored files on a pool of background workers
+type Hasher struct {
+	store   *store.Store
+	workers int
+	queue   chan db.File
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewHasher(ctx context.Context, s *store.Store, workers int) *Hasher {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Hasher{
+		store:   s,
+		workers: workers,
+		queue:   make(chan db.File, queueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start launches the workers and queues every file that has not been hashed yet
+func (h *Hasher) Start() {
+	logging.L().Infow("Starting hasher", "workers", h.workers)
+	for i := 0; i < h.workers; i++ {
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			for {
+				select {
+				case f := <-h.queue:
+					h.hash(f)
+				case <-h.ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		h.backfill()
+	}()
+}
+
+// Stop cancels pending work and waits for the workers to exit
+func (h *Hasher) Stop() {
+	h.cancel()
+	h.wg.Wait()
+}
+
+// Enqueue schedules f for hashing without blocking the caller
+func (h *Hasher) Enqueue(f db.File) {
+	if f.Size == 0 {
+		return
+	}
+	select {
+	case h.queue <- f:
+	default:
+		logging.L().Debugw("hash queue full, deferring file", "file_id", f.ID, "path", f.Path)
+	}
+}
+
+func (h *Hasher) backfill() {
+	files, err := h.store.File.ListUnhashed(h.ctx)
+	if err != nil {
+		logging.L().Errorw("Failed to list files without a hash", "error", err)
+		return
+	}
+	logging.L().Infow("Queueing files without a hash", "count", len(files))
+	for _, f := range files {
+		select {
+		case h.queue <- f:
+		case <-h.ctx.Done():
+			return
+		}
+	}
+}
+
+// hash stores the content hash of f. The file is skipped when its row already
+// has a hash, and the result is discarded when the file changed on disk since
+// it was stored or while it was read; the next upsert queues it again.
+func (h *Hasher) hash(f db.File) {
+	current, err := h.store.File.GetByID(h.ctx, f.ID)
+	if err != nil || current == nil || current.Hash.Valid || current.DeletedAt.Valid {
+		return
+	}
+
+	before, err := os.Stat(current.Path)
+	if err != nil || !unchanged(current, before) {
+		return
+	}
+	sum, err := HashFile(current.Path)
+	if err != nil {
+		logging.L().Warnw("failed to hash file", "path", current.Path, "error", err)
+		return
+	}
+	after, err := os.Stat(current.Path)
+	if err != nil || !unchanged(current, after) {
+		return
+	}
+
+	if err := h.store.File.SetHash(h.ctx, current.ID, sum); err != nil {
+		logging.L().Errorw("failed to store file hash", "file_id", current.ID, "error", err)
+		return
+	}
+	logging.L().Debugw("file hashed", "path", current.Path, "hash", sum)
+}
+
+func unchanged(f *db.File, info os.FileInfo) bool {
+	return info.Size() == f.Size && info.ModTime().Equal(f.Mtime)
+}
@@ -0,0 +1,144 @@
+// Package extract pulls searchable text out of files for content rules.
+//
+// Plain text, CSV, Markdown and JSON files are read as they are; PDFs have
+// the text drawn by their content streams extracted, which works for
+// documents produced by office software and printers but not for scans or
+// files with unusual font encodings. Every extraction is bounded by the
+// sizes and the timeout in Limits, so a large or malformed file cannot hold
+// up the caller.
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Limits bounds the work done for one file
+type Limits struct {
+	MaxFileSize int64         // PDFs larger than this are skipped; text files are read up to MaxTextSize
+	MaxTextSize int           // extracted text is cut off at this many bytes
+	Timeout     time.Duration // extraction is abandoned after this long
+}
+
+// DefaultLimits are used by the classifier
+var DefaultLimits = Limits{
+	MaxFileSize: 20 << 20,
+	MaxTextSize: 1 << 20,
+	Timeout:     3 * time.Second,
+}
+
+var (
+	// ErrUnsupported is returned for files whose text cannot be extracted
+	ErrUnsupported = errors.New("file type does not support text extraction")
+	// ErrTooLarge is returned for files above Limits.MaxFileSize
+	ErrTooLarge = errors.New("file is too large for text extraction")
+)
+
+// textExtensions are read as plain text whatever their sniffed type
+var textExtensions = map[string]bool{
+	".txt": true, ".text": true, ".csv": true, ".tsv": true, ".md": true, ".markdown": true, ".json": true,
+}
+
+// Supported reports whether text can be extracted from a file with the given
+// path and sniffed content type
+func Supported(path, mime string) bool {
+	return isPDF(path, mime) || isText(path, mime)
+}
+
+func isPDF(path, mime string) bool {
+	return mime == "application/pdf" || (mime == "" && strings.EqualFold(filepath.Ext(path), ".pdf"))
+}
+
+func isText(path, mime string) bool {
+	if strings.HasPrefix(mime, "text/") || mime == "application/json" {
+		return true
+	}
+	// Sniffing cannot tell CSV or JSON from other text, but binary content is never text
+	return (mime == "" || mime == "text/plain") && textExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// Text returns the text of the file at path, whose content type was sniffed as mime
+func Text(ctx context.Context, path, mime string, l Limits) (string, error) {
+	var extract func(context.Context, string, Limits) (string, error)
+	switch {
+	case isPDF(path, mime):
+		extract = pdfText
+	case isText(path, mime):
+		extract = plainText
+	default:
+		return "", ErrUnsupported
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("text extraction stopped: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.Timeout)
+	defer cancel()
+
+	type result struct {
+		text string
+		err  error
+	}
+	done := make(chan result, 1)
+	// The readers stop once ctx is done; the caller does not wait for them
+	go func() {
+		text, err := extract(ctx, path, l)
+		done <- result{text, err}
+	}()
+	select {
+	case r := <-done:
+		return r.text, r.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("text extraction stopped: %w", ctx.Err())
+	}
+}
+
+// plainText reads the start of a text file
+func plainText(ctx context.Context, path string, l Limits) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	b, err := ReadAll(ctx, f, int64(l.MaxTextSize))
+	if err != nil {
+		return "", err
+	}
+	return clean(b), nil
+}
+
+// readChunk is how much ReadAll reads between checks of its context
+const readChunk = 64 << 10
+
+// ReadAll reads r up to limit bytes like io.ReadAll, giving up with the
+// context's error once ctx is done. The data read before an error is
+// returned along with it.
+func ReadAll(ctx context.Context, r io.Reader, limit int64) ([]byte, error) {
+	var buf bytes.Buffer
+	lr := io.LimitReader(r, limit)
+	for {
+		if err := ctx.Err(); err != nil {
+			return buf.Bytes(), err
+		}
+		_, err := io.CopyN(&buf, lr, readChunk)
+		if err == io.EOF {
+			return buf.Bytes(), nil
+		}
+		if err != nil {
+			return buf.Bytes(), err
+		}
+	}
+}
+
+// clean drops invalid UTF-8, including a character cut in half by the size cap
+func clean(b []byte) string {
+	return strings.ToValidUTF8(string(b), "")
+}
@@ -0,0 +1,137 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildPDF returns a minimal PDF with one page drawing content
+func buildPDF(t *testing.T, content string, compress bool) []byte {
+	t.Helper()
+	stream, dict := []byte(content), fmt.Sprintf("<< /Length %d >>", len(content))
+	if compress {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		w.Write([]byte(content))
+		w.Close()
+		stream = buf.Bytes()
+		dict = fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", len(stream))
+	}
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>\nendobj\n")
+	pdf.WriteString("4 0 obj\n" + dict + "\nstream\n")
+	pdf.Write(stream)
+	pdf.WriteString("\nendstream\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestText_PDF(t *testing.T) {
+	content := `BT /F1 12 Tf 72 700 Td (INVOICE No. 42) Tj 0 -14 Td [(Total due: ) -250 (\(EUR\) 1\061 0)] TJ ET
+/Span <</MCID 0>> BDC BT <FEFF00C400DF> Tj ET EMC`
+	for _, compress := range []bool{false, true} {
+		path := writeFile(t, "download (3).pdf", buildPDF(t, content, compress))
+		text, err := Text(context.Background(), path, "application/pdf", DefaultLimits)
+		if err != nil {
+			t.Fatalf("Text() error = %v", err)
+		}
+		for _, want := range []string{"INVOICE No. 42", "Total due: (EUR) 11 0", "Äß"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("Text(compress=%v) = %q, want it to contain %q", compress, text, want)
+			}
+		}
+	}
+}
+
+func TestText_Plain(t *testing.T) {
+	path := writeFile(t, "data.csv", []byte("date,amount\n2024-01-01,42\n"))
+	text, err := Text(context.Background(), path, "text/plain", DefaultLimits)
+	if err != nil || !strings.Contains(text, "2024-01-01,42") {
+		t.Errorf("Text() = %q, %v", text, err)
+	}
+
+	// The size cap does not leave half a character behind
+	path = writeFile(t, "notes.md", []byte("abécd"))
+	text, err = Text(context.Background(), path, "text/plain", Limits{MaxFileSize: 1 << 20, MaxTextSize: 3, Timeout: time.Second})
+	if err != nil || text != "ab" {
+		t.Errorf("Text() = %q, %v; want \"ab\"", text, err)
+	}
+}
+
+func TestText_Limits(t *testing.T) {
+	path := writeFile(t, "photo.jpg", []byte("\xFF\xD8\xFF\xE0"))
+	if _, err := Text(context.Background(), path, "image/jpeg", DefaultLimits); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Text() of an image error = %v, want ErrUnsupported", err)
+	}
+
+	path = writeFile(t, "big.pdf", buildPDF(t, "BT (x) Tj ET", false))
+	small := Limits{MaxFileSize: 10, MaxTextSize: 1 << 20, Timeout: time.Second}
+	if _, err := Text(context.Background(), path, "application/pdf", small); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Text() of a large PDF error = %v, want ErrTooLarge", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Text(ctx, path, "application/pdf", DefaultLimits); !errors.Is(err, context.Canceled) {
+		t.Errorf("Text() with a cancelled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestSupported(t *testing.T) {
+	tests := []struct {
+		path, mime string
+		want       bool
+	}{
+		{"a.pdf", "application/pdf", true},
+		{"a", "application/pdf", true},
+		{"a.pdf", "text/plain", true},
+		{"a.json", "text/plain", true},
+		{"a.json", "application/json", true},
+		{"a.md", "", true},
+		{"a.csv", "application/zip", false},
+		{"a.jpg", "image/jpeg", false},
+		{"a.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", false},
+	}
+	for _, tt := range tests {
+		if got := Supported(tt.path, tt.mime); got != tt.want {
+			t.Errorf("Supported(%q, %q) = %v, want %v", tt.path, tt.mime, got, tt.want)
+		}
+	}
+}
+
+func TestText_StopsReading(t *testing.T) {
+	path := writeFile(t, "notes.txt", []byte(strings.Repeat("a", 3*readChunk)))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := plainText(ctx, path, DefaultLimits); !errors.Is(err, context.Canceled) {
+		t.Errorf("plainText() with a cancelled context error = %v, want context.Canceled", err)
+	}
+	path = writeFile(t, "doc.pdf", buildPDF(t, "BT (x) Tj ET", true))
+	if _, err := pdfText(ctx, path, DefaultLimits); !errors.Is(err, context.Canceled) {
+		t.Errorf("pdfText() with a cancelled context error = %v, want context.Canceled", err)
+	}
+
+	b, err := ReadAll(context.Background(), strings.NewReader(strings.Repeat("a", 3*readChunk)), readChunk+1)
+	if err != nil || len(b) != readChunk+1 {
+		t.Errorf("ReadAll() = %d bytes, %v; want %d", len(b), err, readChunk+1)
+	}
+}
@@ -0,0 +1,298 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxStreamSize bounds a single decompressed stream, so a small file cannot
+// expand into an unbounded amount of memory
+const maxStreamSize = 16 << 20
+
+// pdfText extracts the text shown by the content streams of a PDF. Streams are
+// found by scanning the file rather than by following its cross-reference
+// table, which also copes with files whose table is damaged.
+func pdfText(ctx context.Context, path string, l Limits) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > l.MaxFileSize {
+		return "", ErrTooLarge
+	}
+	data, err := ReadAll(ctx, f, l.MaxFileSize)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for rest := data; out.Len() < l.MaxTextSize; {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		dict, stream, next, ok := nextStream(rest)
+		if !ok {
+			break
+		}
+		rest = next
+		if content, ok := decodeStream(ctx, dict, stream); ok {
+			showText(content, &out)
+		}
+	}
+
+	text := out.String()
+	if len(text) > l.MaxTextSize {
+		text = text[:l.MaxTextSize]
+	}
+	return clean([]byte(text)), nil
+}
+
+// nextStream finds the next stream in data and returns its dictionary, its
+// raw bytes and the data following it
+func nextStream(data []byte) ([]byte, []byte, []byte, bool) {
+	for {
+		i := bytes.Index(data, []byte("stream"))
+		if i < 0 {
+			return nil, nil, nil, false
+		}
+		// Skip "endstream" and anything else that merely ends in "stream"
+		if i >= 3 && string(data[i-3:i]) == "end" {
+			data = data[i+len("stream"):]
+			continue
+		}
+		start := i + len("stream")
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			return nil, nil, nil, false
+		}
+
+		dictStart := bytes.LastIndex(data[:i], []byte("obj"))
+		if dictStart < 0 {
+			dictStart = 0
+		}
+		return data[dictStart:i], data[start : start+end], data[start+end+len("endstream"):], true
+	}
+}
+
+// decodeStream returns the content of a stream that may hold page text
+func decodeStream(ctx context.Context, dict, stream []byte) ([]byte, bool) {
+	for _, skip := range []string{"/Image", "/XRef", "/ObjStm", "/Metadata", "/FontFile", "/EmbeddedFile"} {
+		if bytes.Contains(dict, []byte(skip)) {
+			return nil, false
+		}
+	}
+	if !bytes.Contains(dict, []byte("/Filter")) {
+		return stream, true
+	}
+	// Flate is the only filter text streams use in practice
+	if !bytes.Contains(dict, []byte("/FlateDecode")) || bytes.Contains(dict, []byte("/DCTDecode")) || bytes.Contains(dict, []byte("/ASCII")) {
+		return nil, false
+	}
+	r, err := zlib.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	content, err := ReadAll(ctx, r, maxStreamSize)
+	if err != nil && len(content) == 0 {
+		return nil, false
+	}
+	return content, true
+}
+
+// showText appends the strings drawn by the text operators of a content
+// stream to out, separating lines and text objects with newlines
+func showText(content []byte, out *strings.Builder) {
+	var pending []string // strings seen since the last operator
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, next := literalString(content, i)
+			pending = append(pending, s)
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			// Dictionary of a marked-content operator
+			i += 2
+		case c == '<':
+			s, next := hexString(content, i)
+			pending = append(pending, s)
+			i = next
+		case c == '%':
+			// Comment up to the end of the line
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isRegular(c) && !isDigit(c) && c != '/':
+			start := i
+			for i < len(content) && isRegular(content[i]) {
+				i++
+			}
+			switch string(content[start:i]) {
+			case "Tj", "TJ", "'", "\"":
+				for _, s := range pending {
+					out.WriteString(s)
+				}
+			case "Td", "TD", "T*", "Tm":
+				out.WriteString(" ")
+			case "ET":
+				out.WriteString("\n")
+			}
+			pending = pending[:0]
+		default:
+			i++
+		}
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.'
+}
+
+// isRegular reports whether c can be part of an operator or number token
+func isRegular(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return false
+	}
+	return true
+}
+
+// PDFString decodes the literal "(...)" or hex "<...>" string starting at
+// data[start] and returns it with the index just past it. ok is false when no
+// string starts there.
+func PDFString(data []byte, start int) (s string, next int, ok bool) {
+	if start >= len(data) {
+		return "", start, false
+	}
+	switch {
+	case data[start] == '(':
+		s, next = literalString(data, start)
+		return s, next, true
+	case data[start] == '<' && (start+1 == len(data) || data[start+1] != '<'):
+		s, next = hexString(data, start)
+		return s, next, true
+	}
+	return "", start, false
+}
+
+// literalString decodes the "(...)" string starting at content[start]
+func literalString(content []byte, start int) (string, int) {
+	var b []byte
+	depth := 0
+	i := start
+	for ; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch e := content[i]; e {
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					for n := 0; n < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; n++ {
+						v = v*8 + int(content[i]-'0')
+						i++
+					}
+					i--
+					b = append(b, byte(v))
+				} else {
+					b = append(b, e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				b = append(b, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return decodeText(b), i + 1
+			}
+			b = append(b, c)
+		default:
+			b = append(b, c)
+		}
+	}
+	return decodeText(b), i
+}
+
+// hexString decodes the "<...>" string starting at content[start]
+func hexString(content []byte, start int) (string, int) {
+	end := bytes.IndexByte(content[start:], '>')
+	if end < 0 {
+		return "", len(content)
+	}
+	var digits []byte
+	for _, c := range content[start+1 : start+end] {
+		if v, ok := hexValue(c); ok {
+			digits = append(digits, v)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, 0)
+	}
+	b := make([]byte, len(digits)/2)
+	for i := range b {
+		b[i] = digits[2*i]<<4 | digits[2*i+1]
+	}
+	return decodeText(b), start + end + 1
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// decodeText turns string bytes into text: UTF-16 when marked with a byte
+// order mark, otherwise single-byte characters. Strings of glyph IDs, as
+// written for embedded subset fonts, come out as control characters and are dropped.
+func decodeText(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	var sb strings.Builder
+	for _, c := range b {
+		if c < 0x20 && c != '\n' && c != '\t' {
+			continue
+		}
+		sb.WriteRune(rune(c))
+	}
+	return sb.String()
+}
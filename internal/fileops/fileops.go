@@ -0,0 +1,273 @@
+package fileops
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"kalycs/internal/logging"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+// File actions a project can perform on classified files
+const (
+	ActionNone     = "none"
+	ActionMove     = "move"
+	ActionCopy     = "copy"
+	ActionHardlink = "hardlink"
+	ActionSymlink  = "symlink"
+	// ActionRename gives a file a new name in its directory. It is applied by
+	// rules with a rename template rather than chosen as a project action.
+	ActionRename = "rename"
+)
+
+// TempPrefix marks in-progress copies so they are never mistaken for finished files
+const TempPrefix = ".kalycs-"
+
+// maxCollisionSuffix bounds the search for a free " (n)" file name
+const maxCollisionSuffix = 10000
+
+// ErrSourceChanged is returned when the source file no longer matches the
+// metadata it was classified with, which usually means it is still being written.
+var ErrSourceChanged = errors.New("source file changed since it was classified")
+
+// Apply performs action on src, placing the result in destDir.
+// It returns the path the file should be tracked under afterwards.
+//
+// Behaviour:
+//   - "none" (or an empty action) leaves the file in place and returns src.
+//   - Files already inside destDir are left untouched.
+//   - Name collisions in destDir are resolved by appending " (n)" before the extension.
+//     The result is created without replacing anything, so a file appearing under
+//     the chosen name meanwhile moves the result on to the next free name.
+//   - If expected is non-nil and src's size or mtime differ from it, ErrSourceChanged
+//     is returned and nothing is touched; the file will be picked up again by a later event.
+//   - Moves across devices fall back to copy followed by removal of the source.
+//   - Hardlinks across devices fall back to a copy.
+//   - Copies are written to a temporary file in destDir and renamed into place, so a
+//     partially-copied file never appears under its final name.
+func Apply(action, src, destDir string, expected os.FileInfo) (string, error) {
+	if action == "" || action == ActionNone {
+		return src, nil
+	}
+	if destDir == "" {
+		return src, fmt.Errorf("no destination directory for action %q", action)
+	}
+
+	if sameDir(filepath.Dir(src), destDir) {
+		return src, nil
+	}
+
+	info, err := os.Lstat(src)
+	if err != nil {
+		return src, err
+	}
+	if !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+		return src, fmt.Errorf("not a regular file: %s", src)
+	}
+	if expected != nil && (info.Size() != expected.Size() || !info.ModTime().Equal(expected.ModTime())) {
+		return src, ErrSourceChanged
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return src, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	var place func(dest string) error
+	switch action {
+	case ActionMove:
+		place = func(dest string) error { return Move(src, dest) }
+	case ActionCopy:
+		place = func(dest string) error { return copyFile(src, dest) }
+	case ActionHardlink:
+		place = func(dest string) error {
+			err := os.Link(src, dest)
+			if err != nil && isCrossDevice(err) {
+				logging.L().Infow("Hardlink across devices, copying instead", "source", src, "destination", dest)
+				err = copyFile(src, dest)
+			}
+			return err
+		}
+	case ActionSymlink:
+		place = func(dest string) error { return os.Symlink(src, dest) }
+	default:
+		return src, fmt.Errorf("unknown file action %q", action)
+	}
+
+	dest, err := placeUnique(destDir, filepath.Base(src), place)
+	if err != nil {
+		return src, fmt.Errorf("failed to %s file: %w", action, err)
+	}
+
+	logging.L().Infow("File action applied", "action", action, "source", src, "destination", dest)
+	return dest, nil
+}
+
+// Rename gives src, a file or a symlink, the file name name within its
+// directory, resolving collisions like Apply. It returns the path the file
+// now has, which is src when the name is unchanged. ErrSourceChanged is
+// returned as by Apply; a nil expected skips that check.
+func Rename(src, name string, expected os.FileInfo) (string, error) {
+	if name == filepath.Base(src) {
+		return src, nil
+	}
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/`+string(filepath.Separator)) {
+		return src, fmt.Errorf("invalid file name %q", name)
+	}
+
+	info, err := os.Lstat(src)
+	if err != nil {
+		return src, err
+	}
+	if !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+		return src, fmt.Errorf("not a regular file: %s", src)
+	}
+	if expected != nil && (info.Size() != expected.Size() || !info.ModTime().Equal(expected.ModTime())) {
+		return src, ErrSourceChanged
+	}
+
+	dest, err := placeUnique(filepath.Dir(src), name, func(dest string) error { return renameNoReplace(src, dest) })
+	if err != nil {
+		return src, fmt.Errorf("failed to rename file: %w", err)
+	}
+
+	logging.L().Infow("File renamed", "source", src, "destination", dest)
+	return dest, nil
+}
+
+// UniquePath returns a path in dir for name that does not exist yet,
+// appending " (n)" before the extension on collision. The path may be taken
+// by the time it is used; file actions place files with placeUnique instead.
+func UniquePath(dir, name string) (string, error) {
+	for i := 0; i < maxCollisionSuffix; i++ {
+		candidate := filepath.Join(dir, collisionName(name, i))
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no free file name for %s in %s", name, dir)
+}
+
+// placeUnique puts a file into dir under name, or under name with the first
+// free " (n)" suffix. place must fail with an error matching fs.ErrExist
+// rather than replace an existing file at dest. It returns the path used.
+func placeUnique(dir, name string, place func(dest string) error) (string, error) {
+	for i := 0; i < maxCollisionSuffix; i++ {
+		dest := filepath.Join(dir, collisionName(name, i))
+		err := place(dest)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return dest, err
+	}
+	return "", fmt.Errorf("no free file name for %s in %s", name, dir)
+}
+
+// collisionName returns name with the collision suffix " (n)" before its
+// extension, or name itself for n = 0
+func collisionName(name string, n int) string {
+	if n == 0 {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), n, ext)
+}
+
+// Move renames src to dest, copying across devices when needed. An existing
+// file at dest is never replaced; an error matching fs.ErrExist is returned.
+func Move(src, dest string) error {
+	err := renameNoReplace(src, dest)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+
+	logging.L().Infow("Move across devices, copying instead", "source", src, "destination", dest)
+	if err := copyFile(src, dest); err != nil {
+		return err
+	}
+	if err := os.Remove(src); err != nil {
+		// Keep the source rather than leave two tracked copies behind silently.
+		os.Remove(dest)
+		return fmt.Errorf("failed to remove source after copy: %w", err)
+	}
+	return nil
+}
+
+// copyFile copies src to dest via a temporary file, preserving mode and
+// mtime. It fails with an error matching fs.ErrExist when dest exists.
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), TempPrefix+"*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Chmod(tmpPath, info.Mode().Perm()); err != nil {
+		logging.L().Warnw("Failed to preserve file mode on copy", "path", dest, "error", err)
+	}
+	if err := os.Chtimes(tmpPath, info.ModTime(), info.ModTime()); err != nil {
+		logging.L().Warnw("Failed to preserve modification time on copy", "path", dest, "error", err)
+	}
+
+	if err := renameNoReplace(tmpPath, dest); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// isCrossDevice reports whether err is the OS refusing to link or rename across filesystems
+func isCrossDevice(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	// ERROR_NOT_SAME_DEVICE on Windows
+	return errno == syscall.EXDEV || (runtime.GOOS == "windows" && errno == 17)
+}
+
+// sameDir reports whether a and b refer to the same directory
+func sameDir(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
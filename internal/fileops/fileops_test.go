@@ -0,0 +1,241 @@
+package fileops
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) os.FileInfo {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat %s: %v", path, err)
+	}
+	return info
+}
+
+func TestApply_None(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "report.pdf")
+	info := writeFile(t, src, "data")
+
+	got, err := Apply(ActionNone, src, t.TempDir(), info)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if got != src {
+		t.Errorf("Apply() = %s, want %s", got, src)
+	}
+}
+
+func TestApply_Move(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "report.pdf")
+	info := writeFile(t, src, "data")
+	destDir := filepath.Join(t.TempDir(), "Reports")
+
+	got, err := Apply(ActionMove, src, destDir, info)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := filepath.Join(destDir, "report.pdf"); got != want {
+		t.Errorf("Apply() = %s, want %s", got, want)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("source should be gone after move, stat error = %v", err)
+	}
+	if b, err := os.ReadFile(got); err != nil || string(b) != "data" {
+		t.Errorf("moved file content = %q, err = %v", b, err)
+	}
+}
+
+func TestApply_CopyPreservesSourceAndMtime(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "photo.jpg")
+	writeFile(t, src, "pixels")
+	mtime := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatalf("failed to set mtime: %v", err)
+	}
+	info, _ := os.Stat(src)
+	destDir := t.TempDir()
+
+	got, err := Apply(ActionCopy, src, destDir, info)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("source should remain after copy: %v", err)
+	}
+	copied, err := os.Stat(got)
+	if err != nil {
+		t.Fatalf("copy missing: %v", err)
+	}
+	if !copied.ModTime().Equal(mtime) {
+		t.Errorf("copy mtime = %v, want %v", copied.ModTime(), mtime)
+	}
+
+	entries, _ := os.ReadDir(destDir)
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == ".tmp" {
+			t.Errorf("temporary file left behind: %s", e.Name())
+		}
+	}
+}
+
+func TestApply_Links(t *testing.T) {
+	for _, action := range []string{ActionHardlink, ActionSymlink} {
+		t.Run(action, func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "notes.txt")
+			info := writeFile(t, src, "hello")
+
+			got, err := Apply(action, src, t.TempDir(), info)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if b, err := os.ReadFile(got); err != nil || string(b) != "hello" {
+				t.Errorf("linked file content = %q, err = %v", b, err)
+			}
+			if _, err := os.Stat(src); err != nil {
+				t.Errorf("source should remain after %s: %v", action, err)
+			}
+		})
+	}
+}
+
+func TestApply_Collision(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+	writeFile(t, filepath.Join(destDir, "invoice.pdf"), "existing")
+	writeFile(t, filepath.Join(destDir, "invoice (1).pdf"), "existing")
+
+	src := filepath.Join(srcDir, "invoice.pdf")
+	info := writeFile(t, src, "new")
+
+	got, err := Apply(ActionMove, src, destDir, info)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := filepath.Join(destDir, "invoice (2).pdf"); got != want {
+		t.Errorf("Apply() = %s, want %s", got, want)
+	}
+	if b, _ := os.ReadFile(filepath.Join(destDir, "invoice.pdf")); string(b) != "existing" {
+		t.Error("existing file was overwritten")
+	}
+}
+
+func TestMove_NeverReplaces(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "new.pdf")
+	dest := filepath.Join(dir, "taken.pdf")
+	writeFile(t, src, "new")
+	writeFile(t, dest, "existing")
+
+	if err := Move(src, dest); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Move() onto an existing file error = %v, want fs.ErrExist", err)
+	}
+	if err := copyFile(src, dest); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("copyFile() onto an existing file error = %v, want fs.ErrExist", err)
+	}
+	if b, _ := os.ReadFile(dest); string(b) != "existing" {
+		t.Error("existing file was overwritten")
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("source is gone after a refused move: %v", err)
+	}
+
+	// A file appearing under the chosen name meanwhile moves on to the next one
+	got, err := placeUnique(dir, "race.pdf", func(dest string) error {
+		if filepath.Base(dest) == "race.pdf" {
+			writeFile(t, dest, "appeared")
+		}
+		return Move(src, dest)
+	})
+	if err != nil {
+		t.Fatalf("placeUnique() error = %v", err)
+	}
+	if want := filepath.Join(dir, "race (1).pdf"); got != want {
+		t.Errorf("placeUnique() = %s, want %s", got, want)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "race.pdf")); string(b) != "appeared" {
+		t.Error("file that appeared was overwritten")
+	}
+}
+
+func TestApply_SourceChanged(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "download.zip")
+	info := writeFile(t, src, "part")
+	writeFile(t, src, "partial download grew")
+
+	got, err := Apply(ActionMove, src, t.TempDir(), info)
+	if !errors.Is(err, ErrSourceChanged) {
+		t.Fatalf("Apply() error = %v, want ErrSourceChanged", err)
+	}
+	if got != src {
+		t.Errorf("Apply() = %s, want unchanged %s", got, src)
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("source should be untouched: %v", err)
+	}
+}
+
+func TestApply_AlreadyInDestination(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.txt")
+	info := writeFile(t, src, "x")
+
+	got, err := Apply(ActionMove, src, dir, info)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if got != src {
+		t.Errorf("Apply() = %s, want %s", got, src)
+	}
+}
+
+func TestRename(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "scan.pdf")
+	info := writeFile(t, src, "data")
+	writeFile(t, filepath.Join(dir, "2024-03-15_scan.pdf"), "taken")
+
+	got, err := Rename(src, "2024-03-15_scan.pdf", info)
+	if err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if want := filepath.Join(dir, "2024-03-15_scan (1).pdf"); got != want {
+		t.Errorf("Rename() = %s, want %s", got, want)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("source still exists after rename: %v", err)
+	}
+
+	// The same name leaves the file alone
+	if same, err := Rename(got, filepath.Base(got), nil); err != nil || same != got {
+		t.Errorf("Rename(same name) = %s, %v; want %s", same, err, got)
+	}
+	if _, err := Rename(got, "../escape.pdf", nil); err == nil {
+		t.Error("Rename() into another directory should fail")
+	}
+	writeFile(t, got, "still being written")
+	if _, err := Rename(got, "other.pdf", info); !errors.Is(err, ErrSourceChanged) {
+		t.Errorf("Rename() with stale metadata error = %v, want ErrSourceChanged", err)
+	}
+
+	// Symlinks made by the symlink action are renamed themselves
+	link := filepath.Join(dir, "link.pdf")
+	if err := os.Symlink(got, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	renamed, err := Rename(link, "renamed-link.pdf", nil)
+	if err != nil {
+		t.Fatalf("Rename(symlink) error = %v", err)
+	}
+	if target, err := os.Readlink(renamed); err != nil || target != got {
+		t.Errorf("renamed symlink points to %q, %v; want %s", target, err, got)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package fileops
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// linkRename renames src to dest unless dest exists, for systems without an
+// atomic rename that refuses to replace: linking fails when dest exists, and
+// the source name is removed once the link is in place. File systems without
+// hard links fall back to a check before a plain rename.
+func linkRename(src, dest string) error {
+	err := os.Link(src, dest)
+	if err == nil {
+		return os.Remove(src)
+	}
+	if errors.Is(err, fs.ErrExist) || isCrossDevice(err) {
+		return err
+	}
+	if _, err := os.Lstat(dest); err == nil {
+		return &os.LinkError{Op: "rename", Old: src, New: dest, Err: fs.ErrExist}
+	}
+	return os.Rename(src, dest)
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package fileops
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkRename(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "new.pdf")
+	dest := filepath.Join(dir, "taken.pdf")
+	writeFile(t, src, "new")
+	writeFile(t, dest, "existing")
+
+	if err := linkRename(src, dest); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("linkRename() onto an existing file error = %v, want fs.ErrExist", err)
+	}
+	if b, _ := os.ReadFile(dest); string(b) != "existing" {
+		t.Error("existing file was overwritten")
+	}
+
+	free := filepath.Join(dir, "free.pdf")
+	if err := linkRename(src, free); err != nil {
+		t.Fatalf("linkRename() error = %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("source still exists after linkRename: %v", err)
+	}
+	if b, _ := os.ReadFile(free); string(b) != "new" {
+		t.Errorf("renamed file holds %q, want %q", b, "new")
+	}
+}
@@ -0,0 +1,23 @@
+//go:build darwin
+
+package fileops
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// renameNoReplace renames src to dest unless dest exists, atomically
+func renameNoReplace(src, dest string) error {
+	err := unix.RenamexNp(src, dest, unix.RENAME_EXCL)
+	if errors.Is(err, unix.ENOTSUP) {
+		// The file system does not support the flag
+		return linkRename(src, dest)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: src, New: dest, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build linux
+
+package fileops
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// renameNoReplace renames src to dest unless dest exists, atomically
+func renameNoReplace(src, dest string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, src, unix.AT_FDCWD, dest, unix.RENAME_NOREPLACE)
+	if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOSYS) {
+		// The kernel or file system does not support the flag
+		return linkRename(src, dest)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: src, New: dest, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package fileops
+
+// renameNoReplace renames src to dest unless dest exists
+func renameNoReplace(src, dest string) error {
+	return linkRename(src, dest)
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package fileops
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// renameNoReplace renames src to dest unless dest exists. Without
+// MOVEFILE_REPLACE_EXISTING, MoveFileEx fails with ERROR_ALREADY_EXISTS, and
+// without MOVEFILE_COPY_ALLOWED it fails across volumes like os.Rename.
+func renameNoReplace(src, dest string) error {
+	from, err := windows.UTF16PtrFromString(src)
+	if err != nil {
+		return err
+	}
+	to, err := windows.UTF16PtrFromString(dest)
+	if err != nil {
+		return err
+	}
+	if err := windows.MoveFileEx(from, to, 0); err != nil {
+		return &os.LinkError{Op: "rename", Old: src, New: dest, Err: err}
+	}
+	return nil
+}
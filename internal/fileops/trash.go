@@ -0,0 +1,130 @@
+package fileops
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"kalycs/internal/logging"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ErrTrashUnsupported is returned on platforms where Kalycs cannot move files to the trash
+var ErrTrashUnsupported = errors.New("moving files to the trash is not supported on this platform")
+
+// trashInfoExt is the extension of the freedesktop.org metadata file kept for each trashed file
+const trashInfoExt = ".trashinfo"
+
+// Trash moves path to the user's trash and returns where it now lives.
+// On macOS this is ~/.Trash. On Linux and other Unix systems the
+// freedesktop.org home trash is used, so the file can be restored from the
+// desktop's trash view. Windows is not supported.
+func Trash(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return "", err
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		return "", ErrTrashUnsupported
+	case "darwin":
+		return trashTo(filepath.Join(home, ".Trash"), path)
+	}
+	return trashFreedesktop(home, path)
+}
+
+// trashTo moves path into dir under a free name
+func trashTo(dir, path string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	dest, err := placeUnique(dir, filepath.Base(path), func(dest string) error { return Move(path, dest) })
+	if err != nil {
+		return "", err
+	}
+	logging.L().Infow("File moved to trash", "path", path, "trash_path", dest)
+	return dest, nil
+}
+
+// freedesktopTrashDir returns $XDG_DATA_HOME/Trash, defaulting to ~/.local/share/Trash
+func freedesktopTrashDir(home string) string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" || !filepath.IsAbs(dataHome) {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "Trash")
+}
+
+// trashFreedesktop follows the freedesktop.org trash specification: the info
+// file is created exclusively first to reserve the name, then the file is moved.
+func trashFreedesktop(home, path string) (string, error) {
+	trashDir := freedesktopTrashDir(home)
+	filesDir := filepath.Join(trashDir, "files")
+	infoDir := filepath.Join(trashDir, "info")
+	for _, d := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			return "", err
+		}
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: path}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+
+	for i := 0; i < maxCollisionSuffix; i++ {
+		name := collisionName(filepath.Base(path), i)
+		infoPath := filepath.Join(infoDir, name+trashInfoExt)
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, werr := f.WriteString(info)
+		cerr := f.Close()
+		if werr != nil || cerr != nil {
+			os.Remove(infoPath)
+			return "", errors.Join(werr, cerr)
+		}
+
+		dest := filepath.Join(filesDir, name)
+		if err := Move(path, dest); err != nil {
+			os.Remove(infoPath)
+			if errors.Is(err, fs.ErrExist) {
+				// A stray file without an info entry; keep looking
+				continue
+			}
+			return "", err
+		}
+		logging.L().Infow("File moved to trash", "path", path, "trash_path", dest)
+		return dest, nil
+	}
+	return "", fmt.Errorf("no free trash name for %s", path)
+}
+
+// ForgetTrashInfo removes the freedesktop.org info file of a trashed file that
+// was restored to its original location. Paths outside a trash are ignored.
+func ForgetTrashInfo(trashedPath string) {
+	filesDir := filepath.Dir(trashedPath)
+	trashDir := filepath.Base(filepath.Dir(filesDir))
+	if filepath.Base(filesDir) != "files" || (trashDir != "Trash" && !strings.HasPrefix(trashDir, ".Trash")) {
+		return
+	}
+	infoPath := filepath.Join(filepath.Dir(filesDir), "info", filepath.Base(trashedPath)+trashInfoExt)
+	if err := os.Remove(infoPath); err != nil && !os.IsNotExist(err) {
+		logging.L().Warnw("failed to remove trash info file", "path", infoPath, "error", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package glob compiles the shell-style patterns used by glob rules.
+//
+// A pattern must match the whole file name:
+//
+//	IMG_*.HEIC   invoice-????-??.pdf   report[0-9].{doc,docx,pdf}
+//
+// "*" matches any run of characters and "?" exactly one; neither crosses a
+// "/", which "**" does. "[abc]", "[a-z]" and "[!abc]" (or "[^abc]") match one
+// character from, or not from, a class. "{a,b}" matches any of its
+// comma-separated alternatives, which may contain further patterns and nest.
+// A backslash makes the next character literal.
+package glob
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Compile translates pattern into an anchored regular expression
+func Compile(pattern string, caseSensitive bool) (*regexp.Regexp, error) {
+	expr, err := translate(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+func translate(pattern string) (string, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	p := []rune(pattern)
+	braces := 0
+	for i := 0; i < len(p); i++ {
+		switch ch := p[i]; ch {
+		case '\\':
+			if i+1 == len(p) {
+				return "", fmt.Errorf("trailing backslash")
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(string(p[i])))
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end, class, err := translateClass(p, i)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(class)
+			i = end
+		case '{':
+			braces++
+			b.WriteString("(?:")
+		case ',':
+			if braces > 0 {
+				b.WriteString("|")
+			} else {
+				b.WriteString(",")
+			}
+		case '}':
+			if braces == 0 {
+				return "", fmt.Errorf("unmatched '}'")
+			}
+			braces--
+			b.WriteString(")")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	if braces > 0 {
+		return "", fmt.Errorf("unclosed '{'")
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// translateClass translates the character class starting at p[start] and
+// returns the index of its closing bracket
+func translateClass(p []rune, start int) (int, string, error) {
+	var b strings.Builder
+	b.WriteString("[")
+	i := start + 1
+	if i < len(p) && (p[i] == '!' || p[i] == '^') {
+		b.WriteString("^")
+		i++
+	}
+	// A "]" right after the opening bracket is a literal member
+	first := i
+	for ; i < len(p); i++ {
+		switch ch := p[i]; {
+		case ch == ']' && i > first:
+			b.WriteString("]")
+			return i, b.String(), nil
+		case ch == '\\':
+			if i+1 == len(p) {
+				return 0, "", fmt.Errorf("trailing backslash")
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(string(p[i])))
+		case ch == '-' && i > first && i+1 < len(p) && p[i+1] != ']':
+			b.WriteString("-")
+		case ch == '[' || ch == ']' || ch == '-' || ch == '^':
+			b.WriteString(`\` + string(ch))
+		default:
+			b.WriteRune(ch)
+		}
+	}
+	return 0, "", fmt.Errorf("unclosed '['")
+}
@@ -0,0 +1,54 @@
+package glob
+
+import "testing"
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		pattern       string
+		caseSensitive bool
+		in            []string
+		out           []string
+	}{
+		{pattern: "IMG_*.HEIC", in: []string{"IMG_0001.HEIC", "img_2.heic", "IMG_.HEIC"}, out: []string{"IMG_0001.HEIC.bak", "xIMG_1.HEIC"}},
+		{pattern: "IMG_*.HEIC", caseSensitive: true, in: []string{"IMG_1.HEIC"}, out: []string{"img_1.heic"}},
+		{pattern: "invoice-????-??.pdf", in: []string{"invoice-2024-03.pdf"}, out: []string{"invoice-24-03.pdf", "invoice-2024-003.pdf"}},
+		{pattern: "report[0-9].txt", in: []string{"report1.txt"}, out: []string{"reportA.txt", "report10.txt"}},
+		{pattern: "file[!abc].txt", in: []string{"filed.txt"}, out: []string{"filea.txt"}},
+		{pattern: "file[^abc].txt", in: []string{"filed.txt"}, out: []string{"fileb.txt"}},
+		{pattern: "[]x]*", in: []string{"]1", "x1"}, out: []string{"y1"}},
+		{pattern: "a[-_]b", in: []string{"a-b", "a_b"}, out: []string{"a.b"}},
+		{pattern: "*.{jpg,jpeg,png}", in: []string{"a.jpg", "b.JPEG", "c.png"}, out: []string{"d.gif", "e.jpg.txt"}},
+		{pattern: "{scan,IMG}_{*.pdf,[0-9]*}", in: []string{"scan_a.pdf", "IMG_1x"}, out: []string{"scan_a.doc"}},
+		{pattern: "a,b", in: []string{"a,b"}, out: []string{"a"}},
+		{pattern: `literal\*.txt`, in: []string{"literal*.txt"}, out: []string{"literalx.txt"}},
+		{pattern: "a.(b)+", in: []string{"a.(b)+"}, out: []string{"axb", "a.bb"}},
+		{pattern: "*", in: []string{"anything"}, out: []string{"dir/file"}},
+		{pattern: "**", in: []string{"dir/file"}},
+	}
+
+	for _, tt := range tests {
+		re, err := Compile(tt.pattern, tt.caseSensitive)
+		if err != nil {
+			t.Errorf("Compile(%q) error = %v", tt.pattern, err)
+			continue
+		}
+		for _, name := range tt.in {
+			if !re.MatchString(name) {
+				t.Errorf("%q should match %q", tt.pattern, name)
+			}
+		}
+		for _, name := range tt.out {
+			if re.MatchString(name) {
+				t.Errorf("%q should not match %q", tt.pattern, name)
+			}
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, pattern := range []string{"[abc", "{a,b", "a}", `trailing\`, "[z-a]"} {
+		if _, err := Compile(pattern, false); err == nil {
+			t.Errorf("Compile(%q) should fail", pattern)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operators of a Condition
+const (
+	OpEq       = "="
+	OpNe       = "!="
+	OpLt       = "<"
+	OpLe       = "<="
+	OpGt       = ">"
+	OpGe       = ">="
+	OpContains = "contains"
+	OpExists   = "exists" // takes no value
+)
+
+// symbolOps are tried longest first so "<=" is not read as "<"
+var symbolOps = []string{OpNe, OpLe, OpGe, OpEq, OpLt, OpGt}
+
+// Condition tests one metadata field, written as "key op value":
+//
+//	camera_model = iPhone 15 Pro
+//	date_taken >= 2024-01-01
+//	producer contains scan
+//	gps = true
+//	author exists
+//
+// A file without the field never satisfies a condition, whatever the operator.
+// The ordering operators compare numbers numerically and anything else as
+// text, which orders the dates produced by the extractors correctly.
+type Condition struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+// ParseCondition parses a condition. Keys are case-insensitive; a value may
+// be wrapped in double quotes.
+func ParseCondition(text string) (Condition, error) {
+	text = strings.TrimSpace(text)
+	end := 0
+	for end < len(text) && isKeyChar(text[end]) {
+		end++
+	}
+	if end == 0 {
+		return Condition{}, fmt.Errorf("condition %q must start with a field name", text)
+	}
+	c := Condition{Key: strings.ToLower(text[:end])}
+	rest := strings.TrimSpace(text[end:])
+
+	for _, op := range symbolOps {
+		if strings.HasPrefix(rest, op) {
+			c.Op, rest = op, rest[len(op):]
+			break
+		}
+	}
+	if c.Op == "" {
+		word, value, _ := strings.Cut(rest, " ")
+		switch strings.ToLower(word) {
+		case OpContains:
+			c.Op, rest = OpContains, value
+		case OpExists:
+			if strings.TrimSpace(value) != "" {
+				return Condition{}, fmt.Errorf("condition %q: %s takes no value", text, OpExists)
+			}
+			c.Op = OpExists
+			return c, nil
+		default:
+			return Condition{}, fmt.Errorf("condition %q: expected an operator (=, !=, <, <=, >, >=, contains or exists) after %q", text, c.Key)
+		}
+	}
+
+	c.Value = strings.TrimSpace(rest)
+	if len(c.Value) >= 2 && c.Value[0] == '"' && c.Value[len(c.Value)-1] == '"' {
+		c.Value = c.Value[1 : len(c.Value)-1]
+	}
+	if c.Value == "" {
+		return Condition{}, fmt.Errorf("condition %q: missing value after %q", text, c.Op)
+	}
+	return c, nil
+}
+
+func isKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.'
+}
+
+// String renders the condition in the form ParseCondition reads
+func (c Condition) String() string {
+	if c.Op == OpExists {
+		return c.Key + " " + OpExists
+	}
+	value := c.Value
+	if value != strings.TrimSpace(value) {
+		value = `"` + value + `"`
+	}
+	return c.Key + " " + c.Op + " " + value
+}
+
+// Match reports whether fields satisfy the condition
+func (c Condition) Match(fields map[string]string, caseSensitive bool) bool {
+	v, ok := fields[c.Key]
+	if !ok {
+		return false
+	}
+	if c.Op == OpExists {
+		return true
+	}
+
+	want := c.Value
+	if !caseSensitive {
+		v, want = strings.ToLower(v), strings.ToLower(want)
+	}
+	switch c.Op {
+	case OpEq:
+		return v == want
+	case OpNe:
+		return v != want
+	case OpContains:
+		return strings.Contains(v, want)
+	}
+
+	order := strings.Compare(v, want)
+	if a, err := strconv.ParseFloat(v, 64); err == nil {
+		if b, err := strconv.ParseFloat(want, 64); err == nil {
+			order = cmp.Compare(a, b)
+		}
+	}
+	switch c.Op {
+	case OpLt:
+		return order < 0
+	case OpLe:
+		return order <= 0
+	case OpGt:
+		return order > 0
+	case OpGe:
+		return order >= 0
+	}
+	return false
+}
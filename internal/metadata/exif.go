@@ -0,0 +1,228 @@
+package metadata
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// EXIF reads camera fields from JPEG and TIFF images
+type EXIF struct{}
+
+// Supports reports whether mime is JPEG or TIFF
+func (EXIF) Supports(mime string) bool {
+	return mime == "image/jpeg" || mime == "image/tiff"
+}
+
+// EXIF tags read by the extractor
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagLensModel        = 0xA434
+	tagGPSLatitude      = 0x0002
+	tagGPSLongitude     = 0x0004
+)
+
+// maxIFDEntries bounds a directory, so a corrupt count cannot trigger a huge read
+const maxIFDEntries = 512
+
+// maxJPEGSegments bounds how many segments are skipped looking for EXIF data,
+// which sits in one of the first few
+const maxJPEGSegments = 64
+
+var errNotTIFF = errors.New("exif: invalid TIFF header")
+
+// Extract returns the EXIF fields of the image at path
+func (EXIF) Extract(ctx context.Context, path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var magic [2]byte
+	if _, err := f.ReadAt(magic[:], 0); err != nil {
+		return nil, nil
+	}
+	if magic == [2]byte{0xFF, 0xD8} {
+		exif, err := jpegEXIF(ctx, f)
+		if exif == nil || err != nil {
+			return nil, err
+		}
+		return readTIFF(exif)
+	}
+	return readTIFF(f)
+}
+
+// jpegEXIF returns the TIFF data held by the APP1 segment of a JPEG, or nil
+// when the image has none
+func jpegEXIF(ctx context.Context, r io.ReaderAt) (io.ReaderAt, error) {
+	off := int64(2)
+	for n := 0; n < maxJPEGSegments; n++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var hdr [4]byte
+		if _, err := r.ReadAt(hdr[:], off); err != nil {
+			return nil, nil
+		}
+		if hdr[0] != 0xFF {
+			return nil, nil
+		}
+		marker := hdr[1]
+		if marker == 0xFF {
+			// Fill byte before a marker
+			off++
+			continue
+		}
+		// Entropy-coded image data follows start of scan; metadata comes before it
+		if marker == 0xDA || marker == 0xD9 {
+			return nil, nil
+		}
+		size := int64(binary.BigEndian.Uint16(hdr[2:]))
+		if size < 2 {
+			return nil, nil
+		}
+		if marker == 0xE1 && size >= 8 {
+			var id [6]byte
+			if _, err := r.ReadAt(id[:], off+4); err == nil && string(id[:]) == "Exif\x00\x00" {
+				return io.NewSectionReader(r, off+10, size-8), nil
+			}
+		}
+		off += 2 + size
+	}
+	return nil, nil
+}
+
+// ifdEntry is one field of an image file directory
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value [4]byte // the value itself when it fits, otherwise its offset
+}
+
+type tiffReader struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+}
+
+// readTIFF reads the fields of a TIFF structure, the container of EXIF data
+func readTIFF(r io.ReaderAt) (map[string]string, error) {
+	var hdr [8]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil {
+		return nil, errNotTIFF
+	}
+	t := tiffReader{r: r}
+	switch string(hdr[:4]) {
+	case "II*\x00":
+		t.order = binary.LittleEndian
+	case "MM\x00*":
+		t.order = binary.BigEndian
+	default:
+		return nil, errNotTIFF
+	}
+
+	ifd0, err := t.readIFD(int64(t.order.Uint32(hdr[4:])))
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string)
+	t.setString(fields, "camera_make", ifd0, tagMake)
+	t.setString(fields, "camera_model", ifd0, tagModel)
+
+	date := t.stringValue(ifd0, tagDateTime)
+	if e, ok := ifd0[tagExifIFD]; ok {
+		if exif, err := t.readIFD(int64(t.order.Uint32(e.value[:]))); err == nil {
+			if original := t.stringValue(exif, tagDateTimeOriginal); original != "" {
+				date = original
+			}
+			t.setString(fields, "lens_model", exif, tagLensModel)
+		}
+	}
+	if date := exifDate(date); date != "" {
+		fields["date_taken"] = date
+	}
+
+	fields["gps"] = "false"
+	if e, ok := ifd0[tagGPSIFD]; ok {
+		if gps, err := t.readIFD(int64(t.order.Uint32(e.value[:]))); err == nil {
+			_, lat := gps[tagGPSLatitude]
+			_, lon := gps[tagGPSLongitude]
+			if lat && lon {
+				fields["gps"] = "true"
+			}
+		}
+	}
+	return fields, nil
+}
+
+// readIFD reads the directory at off, keyed by tag
+func (t tiffReader) readIFD(off int64) (map[uint16]ifdEntry, error) {
+	var count [2]byte
+	if _, err := t.r.ReadAt(count[:], off); err != nil {
+		return nil, err
+	}
+	n := int(t.order.Uint16(count[:]))
+	if n > maxIFDEntries {
+		return nil, errors.New("exif: too many directory entries")
+	}
+	buf := make([]byte, 12*n)
+	if _, err := t.r.ReadAt(buf, off+2); err != nil {
+		return nil, err
+	}
+	entries := make(map[uint16]ifdEntry, n)
+	for i := 0; i < n; i++ {
+		b := buf[12*i:]
+		e := ifdEntry{tag: t.order.Uint16(b), typ: t.order.Uint16(b[2:]), count: t.order.Uint32(b[4:])}
+		copy(e.value[:], b[8:12])
+		entries[e.tag] = e
+	}
+	return entries, nil
+}
+
+// stringValue returns the ASCII field tag of ifd, or "" when it is missing
+func (t tiffReader) stringValue(ifd map[uint16]ifdEntry, tag uint16) string {
+	const typeASCII = 2
+	e, ok := ifd[tag]
+	if !ok || e.typ != typeASCII || e.count == 0 || e.count > 1024 {
+		return ""
+	}
+	b := e.value[:min(e.count, 4)]
+	if e.count > 4 {
+		b = make([]byte, e.count)
+		if _, err := t.r.ReadAt(b, int64(t.order.Uint32(e.value[:]))); err != nil {
+			return ""
+		}
+	}
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(string(b), ""))
+}
+
+func (t tiffReader) setString(fields map[string]string, key string, ifd map[uint16]ifdEntry, tag uint16) {
+	if v := t.stringValue(ifd, tag); v != "" {
+		fields[key] = v
+	}
+}
+
+// exifDate converts an EXIF timestamp, "2006:01:02 15:04:05", to
+// "2006-01-02T15:04:05"; cameras without a clock write blanks or zeros
+func exifDate(s string) string {
+	if len(s) < 10 || s[4] != ':' || s[7] != ':' || strings.HasPrefix(s, "0000") {
+		return ""
+	}
+	date := s[:4] + "-" + s[5:7] + "-" + s[8:10]
+	if len(s) >= 19 && s[10] == ' ' {
+		return date + "T" + s[11:19]
+	}
+	return date
+}
@@ -0,0 +1,75 @@
+// Package metadata reads the fields files carry about themselves, such as
+// the camera and date recorded in a photo's EXIF data or the author and
+// producer in a PDF's document information, for metadata rules.
+//
+// Fields are flat key/value pairs with lowercase keys. The built-in
+// extractors produce:
+//
+//	camera_make, camera_model, lens_model   EXIF, JPEG and TIFF
+//	date_taken                              EXIF, as 2006-01-02T15:04:05
+//	gps                                     EXIF, "true" when a position is recorded, else "false"
+//	title, author, subject, keywords,
+//	creator, producer                       PDF document information
+//	created                                 PDF, as 2006-01-02T15:04:05
+//
+// Other extractors can be added by implementing Extractor.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Extractor reads metadata fields from files of the content types it supports
+type Extractor interface {
+	// Supports reports whether the extractor reads files of the sniffed content type
+	Supports(mime string) bool
+	// Extract returns the fields found in the file at path; a file without
+	// metadata yields no fields and no error. It stops with the context's
+	// error once ctx is done.
+	Extract(ctx context.Context, path string) (map[string]string, error)
+}
+
+// Timeout bounds the extraction of one file's fields, so a large or
+// malformed file cannot hold up the caller
+const Timeout = 3 * time.Second
+
+// Default returns the built-in extractors
+func Default() []Extractor {
+	return []Extractor{EXIF{}, PDF{}}
+}
+
+// Extract runs every extractor that supports mime against the file at path
+// and merges their fields; an earlier extractor wins a key both produce. The
+// fields read before an extractor failed are returned along with its error.
+// Extraction is abandoned after Timeout.
+func Extract(ctx context.Context, path, mime string, extractors []Extractor) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	fields := make(map[string]string)
+	var errs []error
+	for _, e := range extractors {
+		if !e.Supports(mime) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("metadata extraction stopped: %w", err))
+			break
+		}
+		found, err := e.Extract(ctx, path)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for k, v := range found {
+			k = strings.ToLower(k)
+			if _, ok := fields[k]; !ok && v != "" {
+				fields[k] = v
+			}
+		}
+	}
+	return fields, errors.Join(errs...)
+}
@@ -0,0 +1,342 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+type tiffField struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func ascii(tag uint16, s string) tiffField {
+	return tiffField{tag: tag, typ: 2, count: uint32(len(s) + 1), data: []byte(s + "\x00")}
+}
+
+func rational(tag uint16, count uint32) tiffField {
+	return tiffField{tag: tag, typ: 5, count: count, data: make([]byte, 8*count)}
+}
+
+func ifdSize(fields []tiffField) int {
+	n := 2 + 12*len(fields) + 4
+	for _, f := range fields {
+		if len(f.data) > 4 {
+			n += len(f.data)
+		}
+	}
+	return n
+}
+
+// buildTIFF lays out IFD0 and the optional EXIF and GPS directories one after
+// another, each followed by the values that do not fit in its entries
+func buildTIFF(order binary.ByteOrder, ifd0, exif, gps []tiffField) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II*\x00")
+	} else {
+		buf.WriteString("MM\x00*")
+	}
+	binary.Write(&buf, order, uint32(8))
+
+	pointer := func(tag uint16) tiffField {
+		return tiffField{tag: tag, typ: 4, count: 1, data: make([]byte, 4)}
+	}
+	if exif != nil {
+		ifd0 = append(ifd0, pointer(tagExifIFD))
+	}
+	if gps != nil {
+		ifd0 = append(ifd0, pointer(tagGPSIFD))
+	}
+	exifOff := 8 + ifdSize(ifd0)
+	gpsOff := exifOff + ifdSize(exif)
+	for i, f := range ifd0 {
+		switch f.tag {
+		case tagExifIFD:
+			order.PutUint32(ifd0[i].data, uint32(exifOff))
+		case tagGPSIFD:
+			order.PutUint32(ifd0[i].data, uint32(gpsOff))
+		}
+	}
+
+	for _, fields := range [][]tiffField{ifd0, exif, gps} {
+		if fields == nil {
+			continue
+		}
+		dataOff := buf.Len() + 2 + 12*len(fields) + 4
+		var data bytes.Buffer
+		binary.Write(&buf, order, uint16(len(fields)))
+		for _, f := range fields {
+			binary.Write(&buf, order, f.tag)
+			binary.Write(&buf, order, f.typ)
+			binary.Write(&buf, order, f.count)
+			if len(f.data) > 4 {
+				binary.Write(&buf, order, uint32(dataOff+data.Len()))
+				data.Write(f.data)
+			} else {
+				var v [4]byte
+				copy(v[:], f.data)
+				buf.Write(v[:])
+			}
+		}
+		binary.Write(&buf, order, uint32(0))
+		buf.Write(data.Bytes())
+	}
+	return buf.Bytes()
+}
+
+// buildJPEG wraps tiff in the APP1 segment of a JPEG, after a JFIF segment
+func buildJPEG(tiff []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8})
+	buf.Write([]byte{0xFF, 0xE0, 0x00, 0x10})
+	buf.WriteString("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")
+	if tiff != nil {
+		buf.Write([]byte{0xFF, 0xE1})
+		binary.Write(&buf, binary.BigEndian, uint16(2+6+len(tiff)))
+		buf.WriteString("Exif\x00\x00")
+		buf.Write(tiff)
+	}
+	buf.Write([]byte{0xFF, 0xDA, 0x00, 0x02, 0x12, 0x34, 0xFF, 0xD9})
+	return buf.Bytes()
+}
+
+func TestEXIF_JPEG(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		tiff := buildTIFF(order,
+			[]tiffField{ascii(tagMake, "Apple"), ascii(tagModel, "iPhone 15 Pro"), ascii(tagDateTime, "2024:05:01 09:00:00")},
+			[]tiffField{ascii(tagDateTimeOriginal, "2024:04:30 18:12:45"), ascii(tagLensModel, "Main Camera")},
+			[]tiffField{rational(tagGPSLatitude, 3), rational(tagGPSLongitude, 3)},
+		)
+		path := writeFile(t, "IMG_0001.JPG", buildJPEG(tiff))
+		got, err := EXIF{}.Extract(context.Background(), path)
+		if err != nil {
+			t.Fatalf("Extract() error = %v", err)
+		}
+		want := map[string]string{
+			"camera_make":  "Apple",
+			"camera_model": "iPhone 15 Pro",
+			"lens_model":   "Main Camera",
+			"date_taken":   "2024-04-30T18:12:45",
+			"gps":          "true",
+		}
+		if !maps.Equal(got, want) {
+			t.Errorf("Extract(%v) = %v, want %v", order, got, want)
+		}
+	}
+}
+
+func TestEXIF_TIFFWithoutGPS(t *testing.T) {
+	// Short strings are stored inside their entry; DateTime stands in for a missing DateTimeOriginal
+	tiff := buildTIFF(binary.BigEndian, []tiffField{ascii(tagMake, "NKN"), ascii(tagDateTime, "2023:12:24 20:00:00")}, nil, nil)
+	got, err := EXIF{}.Extract(context.Background(), writeFile(t, "scan.tif", tiff))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := map[string]string{"camera_make": "NKN", "date_taken": "2023-12-24T20:00:00", "gps": "false"}
+	if !maps.Equal(got, want) {
+		t.Errorf("Extract() = %v, want %v", got, want)
+	}
+}
+
+func TestEXIF_Missing(t *testing.T) {
+	got, err := EXIF{}.Extract(context.Background(), writeFile(t, "plain.jpg", buildJPEG(nil)))
+	if err != nil || len(got) != 0 {
+		t.Errorf("Extract() = %v, %v; want no fields", got, err)
+	}
+
+	// A segment length running past the end of the file is not an error
+	got, err = EXIF{}.Extract(context.Background(), writeFile(t, "cut.jpg", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x40, 0x00, 'E', 'x'}))
+	if err != nil || len(got) != 0 {
+		t.Errorf("Extract(truncated) = %v, %v; want no fields", got, err)
+	}
+}
+
+func TestExifDate(t *testing.T) {
+	tests := map[string]string{
+		"2024:01:02 03:04:05":  "2024-01-02T03:04:05",
+		"2024:01:02":           "2024-01-02",
+		"0000:00:00 00:00:00":  "",
+		"                    ": "",
+	}
+	for in, want := range tests {
+		if got := exifDate(in); got != want {
+			t.Errorf("exifDate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPDF_Info(t *testing.T) {
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Type /Pages /Kids [] /Count 0 >>\nendobj\n")
+	// An earlier revision of the information dictionary, superseded by an incremental update
+	pdf.WriteString("7 0 obj\n<< /Title (Draft) >>\nendobj\n")
+	pdf.WriteString("trailer\n<< /Root 1 0 R /Info 7 0 R >>\n")
+	pdf.WriteString("17 0 obj\n<< /Title (Quarterly report \\(Q1\\)) /Author <FEFF004A00FC007200670065006E> " +
+		"/Custom (not read >>) /Producer (ScanSnap Manager #2) /CreationDate (D:20240315101500+01'00') " +
+		"/Extra << /Author (nested) >> >>\nendobj\n")
+	pdf.WriteString("trailer\n<< /Root 1 0 R /Info 17 0 R /Prev 0 >>\n%%EOF\n")
+
+	got, err := PDF{}.Extract(context.Background(), writeFile(t, "report.pdf", pdf.Bytes()))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := map[string]string{
+		"title":    "Quarterly report (Q1)",
+		"author":   "Jürgen",
+		"producer": "ScanSnap Manager #2",
+		"created":  "2024-03-15T10:15:00",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("Extract() = %v, want %v", got, want)
+	}
+
+	// A PDF without document information has no fields
+	got, err = PDF{}.Extract(context.Background(), writeFile(t, "bare.pdf", []byte("%PDF-1.4\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")))
+	if err != nil || len(got) != 0 {
+		t.Errorf("Extract(bare) = %v, %v; want no fields", got, err)
+	}
+}
+
+func TestPDFDate(t *testing.T) {
+	tests := map[string]string{
+		"D:20240315101500+01'00'": "2024-03-15T10:15:00",
+		"D:202403":                "2024-03-01T00:00:00",
+		"2024":                    "2024-01-01T00:00:00",
+		"D:":                      "",
+	}
+	for in, want := range tests {
+		if got := pdfDate(in); got != want {
+			t.Errorf("pdfDate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+type fakeExtractor struct {
+	mime   string
+	fields map[string]string
+}
+
+func (f fakeExtractor) Supports(mime string) bool { return mime == f.mime }
+
+func (f fakeExtractor) Extract(context.Context, string) (map[string]string, error) {
+	return f.fields, nil
+}
+
+func TestExtract(t *testing.T) {
+	extractors := []Extractor{
+		fakeExtractor{"image/png", map[string]string{"Camera_Model": "first", "width": "640"}},
+		fakeExtractor{"image/png", map[string]string{"camera_model": "second", "height": ""}},
+		fakeExtractor{"image/gif", map[string]string{"frames": "3"}},
+	}
+	got, err := Extract(context.Background(), "ignored.png", "image/png", extractors)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := map[string]string{"camera_model": "first", "width": "640"}
+	if !maps.Equal(got, want) {
+		t.Errorf("Extract() = %v, want %v", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got, err := Extract(ctx, "ignored.png", "image/png", extractors); len(got) != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("Extract() with a cancelled context = %v, %v; want no fields and context.Canceled", got, err)
+	}
+	pdf := writeFile(t, "report.pdf", []byte("%PDF-1.4\ntrailer\n<< /Info 1 0 R >>\n%%EOF\n"))
+	if _, err := (PDF{}).Extract(ctx, pdf); !errors.Is(err, context.Canceled) {
+		t.Errorf("PDF.Extract() with a cancelled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    Condition
+		wantErr bool
+	}{
+		{text: "camera_model = iPhone 15 Pro", want: Condition{"camera_model", OpEq, "iPhone 15 Pro"}},
+		{text: "Date_Taken>=2024-01-01", want: Condition{"date_taken", OpGe, "2024-01-01"}},
+		{text: "gps != true", want: Condition{"gps", OpNe, "true"}},
+		{text: "iso < 400", want: Condition{"iso", OpLt, "400"}},
+		{text: `producer contains "Scan "`, want: Condition{"producer", OpContains, "Scan "}},
+		{text: "author EXISTS", want: Condition{"author", OpExists, ""}},
+		{text: "author exists now", wantErr: true},
+		{text: "= value", wantErr: true},
+		{text: "author", wantErr: true},
+		{text: "author is me", wantErr: true},
+		{text: "author =", wantErr: true},
+		{text: `author = ""`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCondition(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCondition(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseCondition(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+		if !tt.wantErr {
+			if again, err := ParseCondition(got.String()); err != nil || again != got {
+				t.Errorf("ParseCondition(%q) = %+v, %v; want it to round-trip", got.String(), again, err)
+			}
+		}
+	}
+}
+
+func TestConditionMatch(t *testing.T) {
+	fields := map[string]string{
+		"camera_model": "iPhone 15 Pro",
+		"date_taken":   "2024-04-30T18:12:45",
+		"gps":          "true",
+		"iso":          "80",
+	}
+	tests := []struct {
+		text          string
+		caseSensitive bool
+		want          bool
+	}{
+		{"camera_model = iphone 15 pro", false, true},
+		{"camera_model = iphone 15 pro", true, false},
+		{"camera_model contains iPhone", true, true},
+		{"camera_model != Pixel 8", false, true},
+		{"date_taken >= 2024-01-01", false, true},
+		{"date_taken < 2024-04-30", false, false},
+		{"date_taken <= 2024-04-30T23:59:59", false, true},
+		{"iso > 100", false, false}, // numbers compare numerically, not as text
+		{"iso >= 80.0", false, true},
+		{"gps = true", false, true},
+		{"gps exists", false, true},
+		{"author exists", false, false},
+		{"author != someone", false, false}, // a missing field satisfies nothing
+	}
+	for _, tt := range tests {
+		c, err := ParseCondition(tt.text)
+		if err != nil {
+			t.Fatalf("ParseCondition(%q) error = %v", tt.text, err)
+		}
+		if got := c.Match(fields, tt.caseSensitive); got != tt.want {
+			t.Errorf("%q.Match(caseSensitive=%v) = %v, want %v", tt.text, tt.caseSensitive, got, tt.want)
+		}
+	}
+}
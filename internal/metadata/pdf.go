@@ -0,0 +1,159 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"kalycs/internal/extract"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// PDF reads the document information dictionary of PDFs
+type PDF struct{}
+
+// Supports reports whether mime is PDF
+func (PDF) Supports(mime string) bool {
+	return mime == "application/pdf"
+}
+
+// maxPDFSize bounds the files read in full to find their information dictionary
+const maxPDFSize = 20 << 20
+
+// infoKeys maps the entries of the information dictionary to field keys
+var infoKeys = map[string]string{
+	"Title":        "title",
+	"Author":       "author",
+	"Subject":      "subject",
+	"Keywords":     "keywords",
+	"Creator":      "creator",
+	"Producer":     "producer",
+	"CreationDate": "created",
+}
+
+// infoRef finds the trailer's reference to the information dictionary, e.g. "/Info 12 0 R"
+var infoRef = regexp.MustCompile(`/Info\s*(\d+)\s+(\d+)\s+R`)
+
+// Extract returns the document information of the PDF at path
+func (PDF) Extract(ctx context.Context, path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxPDFSize {
+		return nil, errors.New("pdf: file is too large to read metadata")
+	}
+	data, err := extract.ReadAll(ctx, f, maxPDFSize)
+	if err != nil {
+		return nil, err
+	}
+
+	dict := infoDict(data)
+	if dict == nil {
+		return nil, nil
+	}
+	return parseInfo(dict), nil
+}
+
+// infoDict returns the data starting at the information dictionary's "<<".
+// An incrementally updated file has several trailers; the last one is current.
+func infoDict(data []byte) []byte {
+	refs := infoRef.FindAllSubmatch(data, -1)
+	if len(refs) == 0 {
+		return nil
+	}
+	ref := refs[len(refs)-1]
+	obj := regexp.MustCompile(`(?:^|\s)` + string(ref[1]) + `\s+` + string(ref[2]) + `\s+obj\b`)
+	locs := obj.FindAllIndex(data, -1)
+	if len(locs) == 0 {
+		return nil
+	}
+	body := data[locs[len(locs)-1][1]:]
+	start := bytes.Index(body, []byte("<<"))
+	if start < 0 || bytes.Contains(body[:start], []byte("endobj")) {
+		return nil
+	}
+	return body[start:]
+}
+
+// parseInfo reads the text entries of the dictionary at the start of data
+func parseInfo(data []byte) map[string]string {
+	fields := make(map[string]string)
+	depth := 0
+	for i := 0; i < len(data); {
+		switch {
+		case bytes.HasPrefix(data[i:], []byte("<<")):
+			depth++
+			i += 2
+		case bytes.HasPrefix(data[i:], []byte(">>")):
+			depth--
+			i += 2
+			if depth == 0 {
+				return fields
+			}
+		case data[i] == '/':
+			start := i + 1
+			i = start
+			for i < len(data) && isNameChar(data[i]) {
+				i++
+			}
+			key, known := infoKeys[string(data[start:i])]
+			for i < len(data) && isSpace(data[i]) {
+				i++
+			}
+			if s, next, ok := extract.PDFString(data, i); ok {
+				if known && depth == 1 {
+					setInfo(fields, key, s)
+				}
+				i = next
+			}
+		case data[i] == '(' || data[i] == '<':
+			// A string value of an entry that is not read; skipped whole as it may hold ">>"
+			_, next, _ := extract.PDFString(data, i)
+			i = max(next, i+1)
+		default:
+			i++
+		}
+	}
+	return fields
+}
+
+func setInfo(fields map[string]string, key, value string) {
+	value = strings.TrimSpace(value)
+	if key == "created" {
+		value = pdfDate(value)
+	}
+	if value != "" {
+		fields[key] = value
+	}
+}
+
+// pdfDate converts a PDF date, "D:20060102150405+01'00'" with everything
+// after the year optional, to "2006-01-02T15:04:05", dropping the time zone
+func pdfDate(s string) string {
+	s = strings.TrimPrefix(s, "D:")
+	digits := 0
+	for digits < len(s) && digits < 14 && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	if digits < 4 {
+		return ""
+	}
+	// Missing month and day default to the first, missing time to midnight
+	d := s[:digits] + "0101000000"[max(digits-4, 0):]
+	return d[0:4] + "-" + d[4:6] + "-" + d[6:8] + "T" + d[8:10] + ":" + d[10:12] + ":" + d[12:14]
+}
+
+func isNameChar(c byte) bool {
+	return !isSpace(c) && !strings.ContainsRune("()<>[]{}/%", rune(c))
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
@@ -0,0 +1,63 @@
+// Package provenance reads where a downloaded file came from, as recorded by
+// the browser that saved it.
+//
+// Browsers keep the download URL and the page it was linked from next to the
+// file: on Linux in the extended attributes user.xdg.origin.url and
+// user.xdg.referrer.url, on macOS in the com.apple.metadata:kMDItemWhereFroms
+// attribute, and on Windows in the Zone.Identifier alternate data stream.
+// Files that were not downloaded, or whose record was lost on the way, have
+// no source.
+package provenance
+
+import (
+	"net/url"
+	"strings"
+)
+
+// maxAttrSize bounds the records read, which hold no more than two URLs
+const maxAttrSize = 64 << 10
+
+// Source is where a file was downloaded from; either URL may be empty
+type Source struct {
+	OriginURL   string // the URL the file was downloaded from
+	ReferrerURL string // the page that linked to the download
+}
+
+// Read returns the recorded source of the file at path. A file without a
+// record, or on a file system without extended attributes, has an empty
+// Source and no error.
+func Read(path string) (Source, error) {
+	return readSource(path)
+}
+
+// Hosts returns the host names of the origin and referrer URLs, lowercased,
+// skipping those that are empty or not URLs
+func (s Source) Hosts() []string {
+	var hosts []string
+	for _, u := range []string{s.OriginURL, s.ReferrerURL} {
+		if h := Host(u); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// Host returns the lowercased host name of rawURL, e.g. "github.com" for
+// "https://GitHub.com/owner/repo". The origin a blob: URL was created by
+// counts as its host. Anything that is not an absolute URL yields "".
+func Host(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	if u.Scheme == "blob" && u.Opaque != "" {
+		return Host(u.Opaque)
+	}
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// MatchDomain reports whether host is domain or one of its subdomains, so
+// "github.com" covers "objects.github.com" but not "notgithub.com"
+func MatchDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
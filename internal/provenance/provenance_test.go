@@ -0,0 +1,138 @@
+package provenance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestHost(t *testing.T) {
+	tests := map[string]string{
+		"https://GitHub.com/owner/repo/archive/main.zip": "github.com",
+		"https://example.org.:8443/file":                 "example.org",
+		"blob:https://mail.google.com/6f1b":              "mail.google.com",
+		"":                                               "",
+		"not a url":                                      "",
+		"/home/user/file.txt":                            "",
+	}
+	for in, want := range tests {
+		if got := Host(in); got != want {
+			t.Errorf("Host(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	s := Source{OriginURL: "https://objects.githubusercontent.com/x", ReferrerURL: "https://github.com/owner/repo"}
+	if got, want := s.Hosts(), []string{"objects.githubusercontent.com", "github.com"}; !slices.Equal(got, want) {
+		t.Errorf("Hosts() = %v, want %v", got, want)
+	}
+}
+
+func TestMatchDomain(t *testing.T) {
+	tests := []struct {
+		host, domain string
+		want         bool
+	}{
+		{"github.com", "github.com", true},
+		{"codeload.github.com", "github.com", true},
+		{"notgithub.com", "github.com", false},
+		{"github.com", "codeload.github.com", false},
+	}
+	for _, tt := range tests {
+		if got := MatchDomain(tt.host, tt.domain); got != tt.want {
+			t.Errorf("MatchDomain(%q, %q) = %v, want %v", tt.host, tt.domain, got, tt.want)
+		}
+	}
+}
+
+// buildWhereFroms encodes urls as a binary property list array, the first
+// as ASCII and the rest as UTF-16 strings
+func buildWhereFroms(urls ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("bplist00")
+	var offsets []int
+
+	writeLength := func(kind byte, n int) {
+		if n < 15 {
+			buf.WriteByte(kind<<4 | byte(n))
+			return
+		}
+		buf.WriteByte(kind<<4 | 0x0F)
+		buf.WriteByte(0x11) // two-byte integer
+		binary.Write(&buf, binary.BigEndian, uint16(n))
+	}
+
+	offsets = append(offsets, buf.Len())
+	writeLength(0xA, len(urls))
+	for i := range urls {
+		buf.WriteByte(byte(i + 1))
+	}
+	for i, u := range urls {
+		offsets = append(offsets, buf.Len())
+		if i == 0 {
+			writeLength(0x5, len(u))
+			buf.WriteString(u)
+			continue
+		}
+		units := utf16.Encode([]rune(u))
+		writeLength(0x6, len(units))
+		for _, c := range units {
+			binary.Write(&buf, binary.BigEndian, c)
+		}
+	}
+
+	tableStart := buf.Len()
+	for _, off := range offsets {
+		buf.WriteByte(byte(off))
+	}
+	buf.Write(make([]byte, 6))
+	buf.WriteByte(1) // offset size
+	buf.WriteByte(1) // object reference size
+	binary.Write(&buf, binary.BigEndian, uint64(len(offsets)))
+	binary.Write(&buf, binary.BigEndian, uint64(0))
+	binary.Write(&buf, binary.BigEndian, uint64(tableStart))
+	return buf.Bytes()
+}
+
+func TestParseWhereFroms(t *testing.T) {
+	urls := []string{"https://dl.example.com/setup.dmg", "https://example.com/downloads/é"}
+	got := parseWhereFroms(buildWhereFroms(urls...))
+	if !slices.Equal(got, urls) {
+		t.Errorf("parseWhereFroms() = %q, want %q", got, urls)
+	}
+	if s := sourceFromList(got); s.OriginURL != urls[0] || s.ReferrerURL != urls[1] {
+		t.Errorf("sourceFromList() = %+v", s)
+	}
+
+	for name, data := range map[string][]byte{
+		"empty":     nil,
+		"xml":       []byte(`<?xml version="1.0"?><plist><array><string>x</string></array></plist>`),
+		"truncated": buildWhereFroms(urls...)[:40],
+	} {
+		if got := parseWhereFroms(data); got != nil {
+			t.Errorf("parseWhereFroms(%s) = %q, want nil", name, got)
+		}
+	}
+}
+
+func TestParseZoneIdentifier(t *testing.T) {
+	text := "[ZoneTransfer]\r\nZoneId=3\r\nReferrerUrl=https://github.com/owner/repo\r\nHostUrl=https://codeload.github.com/owner/repo/zip/main\r\n"
+	want := Source{OriginURL: "https://codeload.github.com/owner/repo/zip/main", ReferrerURL: "https://github.com/owner/repo"}
+	if got := parseZoneIdentifier([]byte(text)); got != want {
+		t.Errorf("parseZoneIdentifier() = %+v, want %+v", got, want)
+	}
+
+	// Some tools write the stream as UTF-16
+	encoded := []byte{0xFF, 0xFE}
+	for _, c := range utf16.Encode([]rune(text)) {
+		encoded = binary.LittleEndian.AppendUint16(encoded, c)
+	}
+	if got := parseZoneIdentifier(encoded); got != want {
+		t.Errorf("parseZoneIdentifier(UTF-16) = %+v, want %+v", got, want)
+	}
+
+	if got := parseZoneIdentifier([]byte("[ZoneTransfer]\nZoneId=3\n")); got != (Source{}) {
+		t.Errorf("parseZoneIdentifier() = %+v, want no source", got)
+	}
+}
@@ -0,0 +1,179 @@
+package provenance
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+)
+
+// sourceFromList turns a kMDItemWhereFroms list, download URL first and
+// referring page second, into a Source
+func sourceFromList(urls []string) Source {
+	var s Source
+	if len(urls) > 0 {
+		s.OriginURL = urls[0]
+	}
+	if len(urls) > 1 {
+		s.ReferrerURL = urls[1]
+	}
+	return s
+}
+
+// parseWhereFroms decodes the binary property list macOS stores in
+// kMDItemWhereFroms: an array of strings, or a single string. Anything else
+// yields nil.
+func parseWhereFroms(data []byte) []string {
+	const trailerSize = 32
+	if !bytes.HasPrefix(data, []byte("bplist00")) || len(data) < 8+trailerSize {
+		return nil
+	}
+	trailer := data[len(data)-trailerSize:]
+	p := bplist{
+		data:       data[:len(data)-trailerSize],
+		offsetSize: int(trailer[6]),
+		refSize:    int(trailer[7]),
+		numObjects: binary.BigEndian.Uint64(trailer[8:]),
+		tableStart: binary.BigEndian.Uint64(trailer[24:]),
+	}
+	if p.offsetSize < 1 || p.offsetSize > 8 || p.refSize < 1 || p.refSize > 8 || p.numObjects > uint64(len(data)) ||
+		p.tableStart > uint64(len(p.data)) || p.numObjects*uint64(p.offsetSize) > uint64(len(p.data))-p.tableStart {
+		return nil
+	}
+
+	top := binary.BigEndian.Uint64(trailer[16:])
+	if s, ok := p.string(top); ok {
+		return []string{s}
+	}
+	refs, ok := p.array(top)
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, ref := range refs {
+		if s, ok := p.string(ref); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// bplist reads objects of a binary property list by their index
+type bplist struct {
+	data       []byte
+	offsetSize int
+	refSize    int
+	numObjects uint64
+	tableStart uint64
+}
+
+// object returns the marker byte of object i and the data following it
+func (p bplist) object(i uint64) (byte, []byte, bool) {
+	if i >= p.numObjects {
+		return 0, nil, false
+	}
+	at := p.tableStart + i*uint64(p.offsetSize)
+	off := uintN(p.data[at : at+uint64(p.offsetSize)])
+	if off >= uint64(len(p.data)) {
+		return 0, nil, false
+	}
+	return p.data[off], p.data[off+1:], true
+}
+
+// length decodes the element count of an object; counts of 15 and more
+// follow the marker as an integer object
+func length(marker byte, rest []byte) (int, []byte, bool) {
+	if n := int(marker & 0x0F); n != 0x0F {
+		return n, rest, true
+	}
+	if len(rest) == 0 || rest[0]>>4 != 0x1 {
+		return 0, nil, false
+	}
+	size := 1 << (rest[0] & 0x0F)
+	if size > 8 || len(rest) < 1+size {
+		return 0, nil, false
+	}
+	return int(uintN(rest[1 : 1+size])), rest[1+size:], true
+}
+
+func (p bplist) string(i uint64) (string, bool) {
+	marker, rest, ok := p.object(i)
+	if !ok {
+		return "", false
+	}
+	n, rest, ok := length(marker, rest)
+	if !ok {
+		return "", false
+	}
+	switch marker >> 4 {
+	case 0x5: // ASCII
+		if n > len(rest) {
+			return "", false
+		}
+		return string(rest[:n]), true
+	case 0x6: // UTF-16BE, n code units
+		if 2*n > len(rest) {
+			return "", false
+		}
+		units := make([]uint16, n)
+		for j := range units {
+			units[j] = binary.BigEndian.Uint16(rest[2*j:])
+		}
+		return string(utf16.Decode(units)), true
+	}
+	return "", false
+}
+
+func (p bplist) array(i uint64) ([]uint64, bool) {
+	marker, rest, ok := p.object(i)
+	if !ok || marker>>4 != 0xA {
+		return nil, false
+	}
+	n, rest, ok := length(marker, rest)
+	if !ok || n*p.refSize > len(rest) {
+		return nil, false
+	}
+	refs := make([]uint64, n)
+	for j := range refs {
+		refs[j] = uintN(rest[j*p.refSize : (j+1)*p.refSize])
+	}
+	return refs, true
+}
+
+// uintN decodes a big-endian unsigned integer of up to eight bytes
+func uintN(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// parseZoneIdentifier reads the HostUrl and ReferrerUrl entries of a
+// Windows Zone.Identifier stream, an INI file that may be UTF-16 encoded
+func parseZoneIdentifier(data []byte) Source {
+	if len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE {
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			units = append(units, binary.LittleEndian.Uint16(data[i:]))
+		}
+		data = []byte(string(utf16.Decode(units)))
+	}
+
+	var s Source
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "hosturl":
+			s.OriginURL = strings.TrimSpace(value)
+		case "referrerurl":
+			s.ReferrerURL = strings.TrimSpace(value)
+		}
+	}
+	return s
+}
@@ -0,0 +1,30 @@
+//go:build darwin
+
+package provenance
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// whereFromsAttr holds a property list with the download URL and, when
+// known, the referring page; Safari, Chrome and Firefox all write it
+const whereFromsAttr = "com.apple.metadata:kMDItemWhereFroms"
+
+func readSource(path string) (Source, error) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := unix.Getxattr(path, whereFromsAttr, buf)
+		switch {
+		case err == nil:
+			return sourceFromList(parseWhereFroms(buf[:n])), nil
+		case errors.Is(err, unix.ERANGE) && len(buf) < maxAttrSize:
+			buf = make([]byte, 2*len(buf))
+		case errors.Is(err, unix.ENOATTR), errors.Is(err, unix.ENOTSUP):
+			return Source{}, nil
+		default:
+			return Source{}, err
+		}
+	}
+}
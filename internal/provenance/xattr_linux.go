@@ -0,0 +1,45 @@
+//go:build linux
+
+package provenance
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Attributes written by Chromium, Firefox and wget
+const (
+	originAttr   = "user.xdg.origin.url"
+	referrerAttr = "user.xdg.referrer.url"
+)
+
+func readSource(path string) (Source, error) {
+	origin, err := getxattr(path, originAttr)
+	if err != nil {
+		return Source{}, err
+	}
+	referrer, err := getxattr(path, referrerAttr)
+	if err != nil {
+		return Source{}, err
+	}
+	return Source{OriginURL: origin, ReferrerURL: referrer}, nil
+}
+
+// getxattr returns the value of an extended attribute, or "" when the file
+// has no such attribute or its file system has none at all
+func getxattr(path, name string) (string, error) {
+	buf := make([]byte, 1024)
+	for {
+		n, err := syscall.Getxattr(path, name, buf)
+		switch {
+		case err == nil:
+			return string(buf[:n]), nil
+		case errors.Is(err, syscall.ERANGE) && len(buf) < maxAttrSize:
+			buf = make([]byte, 2*len(buf))
+		case errors.Is(err, syscall.ENODATA), errors.Is(err, syscall.ENOTSUP):
+			return "", nil
+		default:
+			return "", err
+		}
+	}
+}
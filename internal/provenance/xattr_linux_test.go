@@ -0,0 +1,35 @@
+package provenance
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestRead_Xattr(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.zip")
+	if err := os.WriteFile(path, []byte("PK"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	// A file without attributes has no source
+	if s, err := Read(path); err != nil || s != (Source{}) {
+		t.Fatalf("Read() = %+v, %v; want no source", s, err)
+	}
+
+	want := Source{OriginURL: "https://codeload.github.com/owner/repo/zip/main", ReferrerURL: "https://github.com/owner/repo"}
+	if err := syscall.Setxattr(path, originAttr, []byte(want.OriginURL), 0); err != nil {
+		if errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EPERM) {
+			t.Skipf("file system does not support user attributes: %v", err)
+		}
+		t.Fatalf("Setxattr() error = %v", err)
+	}
+	if err := syscall.Setxattr(path, referrerAttr, []byte(want.ReferrerURL), 0); err != nil {
+		t.Fatalf("Setxattr() error = %v", err)
+	}
+	if s, err := Read(path); err != nil || s != want {
+		t.Errorf("Read() = %+v, %v; want %+v", s, err, want)
+	}
+}
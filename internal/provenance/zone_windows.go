@@ -0,0 +1,28 @@
+//go:build windows
+
+package provenance
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+func readSource(path string) (Source, error) {
+	// The stream is opened like a file whose name has the stream name appended
+	f, err := os.Open(path + ":Zone.Identifier")
+	if errors.Is(err, fs.ErrNotExist) {
+		return Source{}, nil
+	}
+	if err != nil {
+		return Source{}, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxAttrSize))
+	if err != nil {
+		return Source{}, err
+	}
+	return parseZoneIdentifier(data), nil
+}
@@ -0,0 +1,226 @@
+// Package rename builds new file names from the rename templates of rules.
+//
+// A template mixes literal text with placeholders:
+//
+//	{name}              the file name without its extension
+//	{ext}               the extension including its dot, e.g. ".pdf"; empty when there is none
+//	{project}           the name of the project the file is assigned to
+//	{date} {date:LAYOUT}  the file's modification time, formatted with a Go
+//	                    time layout; 2006-01-02 by default
+//	{now} {now:LAYOUT}  the time the file is classified, formatted the same way
+//	${1} ${word}        a numbered or named capture group of the regex rule
+//	                    that matched the file
+//
+// "{{", "}}" and "$$" stand for a literal "{", "}" and "$". For example
+// "{date:2006-01-02}_{project}_{name}{ext}" turns "scan.pdf" into
+// "2024-03-15_Invoices_scan.pdf".
+//
+// Results are made safe to use as a file name on every platform by Sanitize.
+package rename
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultDateLayout formats {date} and {now} without a layout
+const DefaultDateLayout = "2006-01-02"
+
+// MaxNameLength is the longest name produced, in bytes, as most file systems allow
+const MaxNameLength = 255
+
+// ErrEmptyName is returned when a template produces no usable name
+var ErrEmptyName = errors.New("rename template produced an empty file name")
+
+// Vars are the values placeholders are replaced with
+type Vars struct {
+	Name     string            // file name without its extension
+	Ext      string            // extension including its dot
+	Project  string            // project the file is assigned to
+	Modified time.Time         // file modification time, for {date}
+	Now      time.Time         // classification time, for {now}
+	Groups   map[string]string // capture groups by number and by name
+}
+
+// part is a literal text or a placeholder of a template
+type part struct {
+	field  string // "" for literal text
+	text   string // literal text, a date layout or a group name
+	layout bool   // a layout was given explicitly
+}
+
+// Template is a parsed rename template
+type Template struct {
+	source string
+	parts  []part
+}
+
+var groupName = regexp.MustCompile(`^[0-9]+$|^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Parse parses a rename template
+func Parse(template string) (*Template, error) {
+	t := &Template{source: template}
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			t.parts = append(t.parts, part{text: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(template); {
+		rest := template[i:]
+		switch {
+		case strings.HasPrefix(rest, "{{"), strings.HasPrefix(rest, "}}"), strings.HasPrefix(rest, "$$"):
+			literal.WriteByte(rest[0])
+			i += 2
+		case strings.HasPrefix(rest, "${"):
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed capture group reference in %q", template)
+			}
+			name := rest[2:end]
+			if !groupName.MatchString(name) {
+				return nil, fmt.Errorf("invalid capture group reference ${%s}", name)
+			}
+			flush()
+			t.parts = append(t.parts, part{field: "group", text: name})
+			i += end + 1
+		case rest[0] == '{':
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed placeholder in %q", template)
+			}
+			p, err := parsePlaceholder(rest[1:end])
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			t.parts = append(t.parts, p)
+			i += end + 1
+		case rest[0] == '}':
+			return nil, fmt.Errorf("unmatched \"}\" in %q; write \"}}\" for a literal brace", template)
+		default:
+			literal.WriteByte(rest[0])
+			i++
+		}
+	}
+	flush()
+
+	if len(t.parts) == 0 {
+		return nil, ErrEmptyName
+	}
+	return t, nil
+}
+
+func parsePlaceholder(s string) (part, error) {
+	field, layout, hasLayout := strings.Cut(s, ":")
+	switch field {
+	case "name", "ext", "project":
+		if hasLayout {
+			return part{}, fmt.Errorf("placeholder {%s} takes no format", field)
+		}
+		return part{field: field}, nil
+	case "date", "now":
+		if !hasLayout {
+			return part{field: field, text: DefaultDateLayout}, nil
+		}
+		if layout == "" {
+			return part{}, fmt.Errorf("placeholder {%s:} is missing its date layout", field)
+		}
+		return part{field: field, text: layout, layout: true}, nil
+	}
+	return part{}, fmt.Errorf("unknown placeholder {%s}; expected name, ext, project, date or now", s)
+}
+
+// String returns the template as written
+func (t *Template) String() string {
+	return t.source
+}
+
+// Groups returns the capture groups the template refers to, numbers and names
+func (t *Template) Groups() []string {
+	var groups []string
+	for _, p := range t.parts {
+		if p.field == "group" {
+			groups = append(groups, p.text)
+		}
+	}
+	return groups
+}
+
+// Execute fills in the template and returns the sanitized file name. A
+// group that did not take part in the match is empty.
+func (t *Template) Execute(v Vars) (string, error) {
+	var b strings.Builder
+	for _, p := range t.parts {
+		switch p.field {
+		case "":
+			b.WriteString(p.text)
+		case "name":
+			b.WriteString(v.Name)
+		case "ext":
+			b.WriteString(v.Ext)
+		case "project":
+			b.WriteString(v.Project)
+		case "date":
+			b.WriteString(v.Modified.Format(p.text))
+		case "now":
+			b.WriteString(v.Now.Format(p.text))
+		case "group":
+			b.WriteString(v.Groups[p.text])
+		}
+	}
+	name := Sanitize(b.String())
+	if name == "" {
+		return "", ErrEmptyName
+	}
+	return name, nil
+}
+
+// reservedNames cannot be used as file names on Windows, with any extension
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Sanitize makes name safe to use as a file name on Linux, macOS and
+// Windows: path separators, characters Windows forbids and control
+// characters become "_", leading dots and surrounding spaces are dropped so
+// the file does not turn hidden, trailing dots are dropped, reserved device
+// names get a "_" prefix, and names longer than MaxNameLength bytes are
+// shortened before their extension.
+func Sanitize(name string) string {
+	name = strings.ToValidUTF8(name, "_")
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7F || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ". ")
+	name = strings.TrimRight(name, ". ")
+
+	stem, _, _ := strings.Cut(name, ".")
+	if reservedNames[strings.ToUpper(strings.TrimSpace(stem))] {
+		name = "_" + name
+	}
+
+	if len(name) > MaxNameLength {
+		ext := ""
+		if i := strings.LastIndexByte(name, '.'); i > 0 && len(name)-i <= 16 {
+			ext = name[i:]
+		}
+		stem := name[:MaxNameLength-len(ext)]
+		for !utf8.ValidString(stem) {
+			stem = stem[:len(stem)-1]
+		}
+		name = strings.TrimRight(stem, ". ") + ext
+	}
+	return name
+}
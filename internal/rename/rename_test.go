@@ -0,0 +1,106 @@
+package rename
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		template string
+		errMsg   string
+	}{
+		{template: "", errMsg: "empty file name"},
+		{template: "{name", errMsg: "unclosed placeholder"},
+		{template: "${1", errMsg: "unclosed capture group"},
+		{template: "${1a}", errMsg: "invalid capture group"},
+		{template: "{title}", errMsg: "unknown placeholder"},
+		{template: "{name:upper}", errMsg: "takes no format"},
+		{template: "{date:}", errMsg: "missing its date layout"},
+		{template: "name}", errMsg: "unmatched"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			_, err := Parse(tt.template)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Parse(%q) error = %v, want to contain %q", tt.template, err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	vars := Vars{
+		Name:     "scan 7",
+		Ext:      ".pdf",
+		Project:  "Invoices",
+		Modified: time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC),
+		Now:      time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		Groups:   map[string]string{"0": "scan 7", "1": "7", "n": "7"},
+	}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{template: "{date}_{project}_{name}{ext}", want: "2024-03-15_Invoices_scan 7.pdf"},
+		{template: "{date:2006/01} {name}{ext}", want: "2024_03 scan 7.pdf"},
+		{template: "{now:2006}-${1}{ext}", want: "2025-7.pdf"},
+		{template: "${n}-${2}{ext}", want: "7-.pdf"},
+		{template: "{{{name}}} $$5", want: "{scan 7} $5"},
+		{template: "{project}: {name}?{ext}", want: "Invoices_ scan 7_.pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := Parse(tt.template)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := tmpl.Execute(vars)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	tmpl, err := Parse("${1}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tmpl.Groups(); len(got) != 1 || got[0] != "1" {
+		t.Errorf("Groups() = %v, want [1]", got)
+	}
+	if _, err := tmpl.Execute(Vars{}); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("Execute() without the group error = %v, want ErrEmptyName", err)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "report.pdf", want: "report.pdf"},
+		{name: `a/b\c:d*e?f"g<h>i|j.txt`, want: "a_b_c_d_e_f_g_h_i_j.txt"},
+		{name: "tab\there.txt", want: "tab_here.txt"},
+		{name: " ..hidden. ", want: "hidden"},
+		{name: "CON.txt", want: "_CON.txt"},
+		{name: "lpt1", want: "_lpt1"},
+		{name: "console.txt", want: "console.txt"},
+		{name: "...", want: ""},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.name); got != tt.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	long := Sanitize(strings.Repeat("é", 200) + ".pdf")
+	if len(long) > MaxNameLength || !strings.HasSuffix(long, "é.pdf") {
+		t.Errorf("Sanitize(long name) = %q (%d bytes), want at most %d bytes keeping the extension", long, len(long), MaxNameLength)
+	}
+}
@@ -0,0 +1,216 @@
+// Package ruleexpr parses the comparison expressions used by size, age and
+// modification-time rules.
+//
+// An expression is a comparison against a single value or an inclusive range:
+//
+//	>100MB   <=2GB   =0B   10MB..50MB
+//	>30d     <2h     1w..4w
+//	>=2024-01-01   <2024-06-30T12:00   2024-01-01..2024-03-31
+//
+// Sizes take the units B, KB, MB, GB and TB (powers of 1024; KiB, MiB, GiB
+// and TiB are accepted as well) and may be fractional. Ages take the units
+// s, m, h, d and w. Dates are interpreted in local time; a date without a
+// time stands for the whole day, so "<=2024-01-31" includes that day.
+package ruleexpr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule kinds whose texts are comparison expressions
+const (
+	KindSize     = "size"     // file size in bytes
+	KindAge      = "age"      // time since the file was last modified, in seconds
+	KindModified = "modified" // modification time, in Unix seconds
+)
+
+// IsKind reports whether kind is a rule kind handled by this package
+func IsKind(kind string) bool {
+	return kind == KindSize || kind == KindAge || kind == KindModified
+}
+
+// Range is an inclusive interval; a bound that is not set is open
+type Range struct {
+	Min    int64 `json:"min"`
+	Max    int64 `json:"max"`
+	HasMin bool  `json:"has_min"`
+	HasMax bool  `json:"has_max"`
+}
+
+// Contains reports whether v lies within the range
+func (r Range) Contains(v int64) bool {
+	if r.HasMin && v < r.Min {
+		return false
+	}
+	if r.HasMax && v > r.Max {
+		return false
+	}
+	return true
+}
+
+// span is the inclusive interval a single value stands for; a date covers a whole day
+type span struct{ lo, hi int64 }
+
+// Parse parses text as an expression for the given rule kind
+func Parse(kind, text string) (Range, error) {
+	switch kind {
+	case KindSize:
+		return ParseSize(text)
+	case KindAge:
+		return ParseAge(text)
+	case KindModified:
+		return ParseModified(text)
+	}
+	return Range{}, fmt.Errorf("unknown expression kind %q", kind)
+}
+
+// ParseSize parses a size expression such as ">100MB" or "1GB..4GB"
+func ParseSize(text string) (Range, error) {
+	return parse(text, parseSizeValue)
+}
+
+// ParseAge parses an age expression such as ">30d" or "1h..12h"
+func ParseAge(text string) (Range, error) {
+	return parse(text, parseAgeValue)
+}
+
+// ParseModified parses a modification-time expression such as ">=2024-01-01"
+func ParseModified(text string) (Range, error) {
+	return parse(text, parseDateValue)
+}
+
+func parse(text string, value func(string) (span, error)) (Range, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Range{}, fmt.Errorf("expression is empty")
+	}
+
+	if lo, hi, ok := strings.Cut(text, ".."); ok {
+		from, err := value(strings.TrimSpace(lo))
+		if err != nil {
+			return Range{}, err
+		}
+		to, err := value(strings.TrimSpace(hi))
+		if err != nil {
+			return Range{}, err
+		}
+		if from.lo > to.hi {
+			return Range{}, fmt.Errorf("range %q is empty: start is after end", text)
+		}
+		return Range{Min: from.lo, Max: to.hi, HasMin: true, HasMax: true}, nil
+	}
+
+	op := ""
+	for _, candidate := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(text, candidate) {
+			op = candidate
+			break
+		}
+	}
+	v, err := value(strings.TrimSpace(text[len(op):]))
+	if err != nil {
+		return Range{}, err
+	}
+
+	switch op {
+	case "<":
+		return Range{Max: v.lo - 1, HasMax: true}, nil
+	case "<=":
+		return Range{Max: v.hi, HasMax: true}, nil
+	case ">":
+		return Range{Min: v.hi + 1, HasMin: true}, nil
+	case ">=":
+		return Range{Min: v.lo, HasMin: true}, nil
+	default:
+		return Range{Min: v.lo, Max: v.hi, HasMin: true, HasMax: true}, nil
+	}
+}
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+	"tb":  1 << 40,
+	"tib": 1 << 40,
+}
+
+var ageUnits = map[string]float64{
+	"s": 1,
+	"m": 60,
+	"h": 60 * 60,
+	"d": 24 * 60 * 60,
+	"w": 7 * 24 * 60 * 60,
+}
+
+func parseSizeValue(s string) (span, error) {
+	n, err := parseQuantity(s, sizeUnits, "size")
+	return span{n, n}, err
+}
+
+func parseAgeValue(s string) (span, error) {
+	n, err := parseQuantity(s, ageUnits, "age")
+	return span{n, n}, err
+}
+
+// parseQuantity parses a non-negative number followed by one of units
+func parseQuantity(s string, units map[string]float64, what string) (int64, error) {
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	number, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	if number == "" {
+		return 0, fmt.Errorf("invalid %s %q: expected a number", what, s)
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", what, s, err)
+	}
+	factor, ok := units[unit]
+	if !ok || (unit == "" && what == "age") {
+		return 0, fmt.Errorf("invalid %s %q: unknown unit %q", what, s, unit)
+	}
+	v := n * factor
+	if v > math.MaxInt64/2 {
+		return 0, fmt.Errorf("invalid %s %q: value too large", what, s)
+	}
+	return int64(math.Round(v)), nil
+}
+
+var dateLayouts = []struct {
+	layout string
+	length time.Duration // how much time the value stands for
+}{
+	{"2006-01-02", 24 * time.Hour},
+	{"2006-01-02T15:04", time.Minute},
+	{"2006-01-02T15:04:05", time.Second},
+}
+
+func parseDateValue(s string) (span, error) {
+	upper := strings.ToUpper(s)
+	if t, err := time.Parse(time.RFC3339, upper); err == nil {
+		return span{t.Unix(), t.Unix()}, nil
+	}
+	for _, l := range dateLayouts {
+		t, err := time.ParseInLocation(l.layout, upper, time.Local)
+		if err != nil {
+			continue
+		}
+		// AddDate keeps whole days correct across daylight saving changes
+		end := t.Add(l.length)
+		if l.length == 24*time.Hour {
+			end = t.AddDate(0, 0, 1)
+		}
+		return span{t.Unix(), end.Unix() - 1}, nil
+	}
+	return span{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD, YYYY-MM-DDTHH:MM or RFC 3339", s)
+}
@@ -0,0 +1,117 @@
+package ruleexpr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	const mb = 1 << 20
+	tests := []struct {
+		text    string
+		in      []int64
+		out     []int64
+		wantErr bool
+	}{
+		{text: ">100MB", in: []int64{100*mb + 1, 1 << 40}, out: []int64{0, 100 * mb}},
+		{text: ">=100mb", in: []int64{100 * mb}, out: []int64{100*mb - 1}},
+		{text: "<1KB", in: []int64{0, 1023}, out: []int64{1024}},
+		{text: "<= 1.5 KiB", in: []int64{1536}, out: []int64{1537}},
+		{text: "=0", in: []int64{0}, out: []int64{1}},
+		{text: "10MB..50MB", in: []int64{10 * mb, 50 * mb}, out: []int64{10*mb - 1, 50*mb + 1}},
+		{text: "2GB", in: []int64{2 << 30}, out: []int64{2<<30 - 1}},
+		{text: "", wantErr: true},
+		{text: ">", wantErr: true},
+		{text: ">100XB", wantErr: true},
+		{text: "-5MB", wantErr: true},
+		{text: "50MB..10MB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			r, err := ParseSize(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			for _, v := range tt.in {
+				if !r.Contains(v) {
+					t.Errorf("ParseSize(%q) = %+v does not contain %d", tt.text, r, v)
+				}
+			}
+			for _, v := range tt.out {
+				if r.Contains(v) {
+					t.Errorf("ParseSize(%q) = %+v contains %d", tt.text, r, v)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	const day = 24 * 60 * 60
+	r, err := ParseAge(">30d")
+	if err != nil {
+		t.Fatalf("ParseAge() error = %v", err)
+	}
+	if r.Contains(30*day) || !r.Contains(30*day+1) {
+		t.Errorf("ParseAge(>30d) = %+v", r)
+	}
+
+	r, err = ParseAge("1w..2w")
+	if err != nil {
+		t.Fatalf("ParseAge() error = %v", err)
+	}
+	if !r.Contains(7*day) || !r.Contains(14*day) || r.Contains(15*day) {
+		t.Errorf("ParseAge(1w..2w) = %+v", r)
+	}
+
+	for _, text := range []string{"30", "30y", "d"} {
+		if _, err := ParseAge(text); err == nil {
+			t.Errorf("ParseAge(%q) should fail", text)
+		}
+	}
+}
+
+func TestParseModified(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		text string
+		in   []string
+		out  []string
+	}{
+		{">=2024-01-01", []string{"2024-01-01 00:00:00"}, []string{"2023-12-31 23:59:59"}},
+		{"<=2024-01-31", []string{"2024-01-31 23:59:59"}, []string{"2024-02-01 00:00:00"}},
+		{">2024-01-31", []string{"2024-02-01 00:00:00"}, []string{"2024-01-31 12:00:00"}},
+		{"<2024-01-31", []string{"2024-01-30 23:59:59"}, []string{"2024-01-31 00:00:00"}},
+		{"=2024-03-10", []string{"2024-03-10 00:00:00", "2024-03-10 23:59:59"}, []string{"2024-03-11 00:00:00"}},
+		{"2024-01-01..2024-03-31", []string{"2024-03-31 18:00:00"}, []string{"2024-04-01 00:00:00"}},
+		{">=2024-06-30t12:00", []string{"2024-06-30 12:00:00"}, []string{"2024-06-30 11:59:59"}},
+	}
+	for _, tt := range tests {
+		r, err := ParseModified(tt.text)
+		if err != nil {
+			t.Fatalf("ParseModified(%q) error = %v", tt.text, err)
+		}
+		for _, s := range tt.in {
+			if !r.Contains(day(s).Unix()) {
+				t.Errorf("ParseModified(%q) does not contain %s", tt.text, s)
+			}
+		}
+		for _, s := range tt.out {
+			if r.Contains(day(s).Unix()) {
+				t.Errorf("ParseModified(%q) contains %s", tt.text, s)
+			}
+		}
+	}
+
+	if _, err := ParseModified(">01/02/2024"); err == nil {
+		t.Error("ParseModified() accepted an unsupported date format")
+	}
+}
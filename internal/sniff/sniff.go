@@ -0,0 +1,223 @@
+// Package sniff detects a file's content type from its leading bytes, so
+// rules can match what a file is rather than what its extension claims.
+//
+// Signatures for common documents, Office formats, archives, images, audio
+// and video are checked first; anything else falls back to
+// http.DetectContentType. The result is a bare MIME type without parameters,
+// e.g. "application/pdf" or "text/plain", and "application/octet-stream"
+// when nothing is recognised.
+package sniff
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// HeaderSize is how many leading bytes are inspected. It is large enough to
+// find the entry names of Office documents, which are ZIP archives.
+const HeaderSize = 8 << 10
+
+// Unknown is the type of content that is not recognised
+const Unknown = "application/octet-stream"
+
+// Detect returns the content type of the file at path
+func Detect(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, HeaderSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return DetectBytes(buf[:n]), nil
+}
+
+// DetectBytes returns the content type of data, the leading bytes of a file
+func DetectBytes(data []byte) string {
+	for _, s := range signatures {
+		if s.match(data) {
+			if s.refine != nil {
+				if t := s.refine(data); t != "" {
+					return t
+				}
+			}
+			return s.mime
+		}
+	}
+	t := http.DetectContentType(data)
+	if i := strings.IndexByte(t, ';'); i >= 0 {
+		t = strings.TrimSpace(t[:i])
+	}
+	return t
+}
+
+type signature struct {
+	mime   string
+	match  func([]byte) bool
+	refine func([]byte) string // narrows a container format down, e.g. ZIP to DOCX; "" keeps mime
+}
+
+// prefix matches magic bytes at offset
+func prefix(offset int, magic string) func([]byte) bool {
+	return func(b []byte) bool {
+		return len(b) >= offset+len(magic) && string(b[offset:offset+len(magic)]) == magic
+	}
+}
+
+// riff matches a RIFF container of the given form type, e.g. "WEBP"
+func riff(form string) func([]byte) bool {
+	return func(b []byte) bool {
+		return prefix(0, "RIFF")(b) && prefix(8, form)(b)
+	}
+}
+
+// ftyp matches an ISO base media file (MP4, MOV, HEIC) whose major brand is one of brands
+func ftyp(brands ...string) func([]byte) bool {
+	return func(b []byte) bool {
+		if !prefix(4, "ftyp")(b) || len(b) < 12 {
+			return false
+		}
+		major := string(b[8:12])
+		for _, brand := range brands {
+			if major == brand {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var signatures = []signature{
+	// Documents
+	{mime: "application/pdf", match: prefix(0, "%PDF-")},
+	{mime: "application/rtf", match: prefix(0, `{\rtf`)},
+	{mime: "application/postscript", match: prefix(0, "%!PS")},
+	{mime: "application/x-ole-storage", match: prefix(0, "\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"), refine: refineOLE},
+
+	// Archives; ZIP also holds Office, OpenDocument and EPUB files
+	{mime: "application/zip", match: prefix(0, "PK\x03\x04"), refine: refineZip},
+	{mime: "application/gzip", match: prefix(0, "\x1F\x8B")},
+	{mime: "application/x-7z-compressed", match: prefix(0, "7z\xBC\xAF\x27\x1C")},
+	{mime: "application/vnd.rar", match: prefix(0, "Rar!\x1A\x07")},
+	{mime: "application/x-bzip2", match: prefix(0, "BZh")},
+	{mime: "application/x-xz", match: prefix(0, "\xFD7zXZ\x00")},
+	{mime: "application/zstd", match: prefix(0, "\x28\xB5\x2F\xFD")},
+	{mime: "application/x-tar", match: prefix(257, "ustar")},
+
+	// Images
+	{mime: "image/png", match: prefix(0, "\x89PNG\r\n\x1A\n")},
+	{mime: "image/jpeg", match: prefix(0, "\xFF\xD8\xFF")},
+	{mime: "image/gif", match: prefix(0, "GIF8")},
+	{mime: "image/webp", match: riff("WEBP")},
+	{mime: "image/bmp", match: func(b []byte) bool { return prefix(0, "BM")(b) && prefix(6, "\x00\x00\x00\x00")(b) }},
+	{mime: "image/tiff", match: prefix(0, "II*\x00")},
+	{mime: "image/tiff", match: prefix(0, "MM\x00*")},
+	{mime: "image/vnd.adobe.photoshop", match: prefix(0, "8BPS")},
+	{mime: "image/x-icon", match: prefix(0, "\x00\x00\x01\x00")},
+	{mime: "image/heic", match: ftyp("heic", "heix", "heim", "heis")},
+	{mime: "image/heif", match: ftyp("mif1", "msf1")},
+	{mime: "image/avif", match: ftyp("avif", "avis")},
+
+	// Audio
+	{mime: "audio/mpeg", match: prefix(0, "ID3")},
+	{mime: "audio/wav", match: riff("WAVE")},
+	{mime: "audio/flac", match: prefix(0, "fLaC")},
+	{mime: "audio/ogg", match: prefix(0, "OggS")},
+	{mime: "audio/mp4", match: ftyp("M4A ", "M4B ")},
+
+	// Video
+	{mime: "video/quicktime", match: ftyp("qt  ")},
+	{mime: "video/mp4", match: ftyp("isom", "iso2", "mp41", "mp42", "avc1", "dash", "MSNV", "M4V ")},
+	{mime: "video/3gpp", match: ftyp("3gp4", "3gp5", "3gp6")},
+	{mime: "video/x-msvideo", match: riff("AVI ")},
+	{mime: "video/x-matroska", match: prefix(0, "\x1A\x45\xDF\xA3"), refine: refineMatroska},
+	{mime: "video/mp2t", match: func(b []byte) bool { return len(b) > 188 && b[0] == 0x47 && b[188] == 0x47 }},
+
+	// Executables
+	{mime: "application/x-msdownload", match: prefix(0, "MZ")},
+	{mime: "application/x-executable", match: prefix(0, "\x7FELF")},
+	{mime: "application/x-mach-binary", match: func(b []byte) bool {
+		return prefix(0, "\xCF\xFA\xED\xFE")(b) || prefix(0, "\xCE\xFA\xED\xFE")(b) || prefix(0, "\xCA\xFE\xBA\xBE")(b)
+	}},
+}
+
+// zipMimes maps the first path component of an archive entry to the Office
+// format it indicates
+var zipMimes = []struct {
+	entry string
+	mime  string
+}{
+	{"word/", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	{"xl/", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	{"ppt/", "application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+}
+
+// refineZip recognises ZIP-based formats. OpenDocument and EPUB store their
+// type uncompressed in a first entry named "mimetype"; Office Open XML
+// documents are told apart by the directories their entries live in.
+func refineZip(b []byte) string {
+	const nameOffset = 30 // size of a local file header before the entry name
+	if len(b) > nameOffset {
+		size := int(le16(b[18:])) | int(le16(b[20:]))<<16
+		nameLen, extraLen := int(le16(b[26:])), int(le16(b[28:]))
+		start := nameOffset + nameLen + extraLen
+		if size == 0 && start < len(b) {
+			// Written with a trailing data descriptor; the content runs up to the next signature
+			size = max(bytes.Index(b[start:], []byte("PK")), 0)
+		}
+		if string(b[nameOffset:min(len(b), nameOffset+nameLen)]) == "mimetype" && size < 128 && start+size <= len(b) {
+			if t := string(b[start : start+size]); strings.Contains(t, "/") {
+				return t
+			}
+		}
+	}
+	for _, z := range zipMimes {
+		if bytes.Contains(b, []byte(z.entry)) {
+			return z.mime
+		}
+	}
+	return ""
+}
+
+// refineOLE recognises legacy Office documents by the stream names in the
+// compound file's directory, when it lies within the header
+func refineOLE(b []byte) string {
+	switch {
+	case bytes.Contains(b, utf16("WordDocument")):
+		return "application/msword"
+	case bytes.Contains(b, utf16("Workbook")), bytes.Contains(b, utf16("Book")):
+		return "application/vnd.ms-excel"
+	case bytes.Contains(b, utf16("PowerPoint Document")):
+		return "application/vnd.ms-powerpoint"
+	}
+	return ""
+}
+
+// refineMatroska tells WebM apart from other Matroska files by its doctype
+func refineMatroska(b []byte) string {
+	if bytes.Contains(b[:min(len(b), 64)], []byte("webm")) {
+		return "video/webm"
+	}
+	return ""
+}
+
+func le16(b []byte) uint16 {
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+// utf16 encodes an ASCII string as UTF-16LE, as OLE stream names are stored
+func utf16(s string) []byte {
+	out := make([]byte, 0, 2*len(s))
+	for i := 0; i < len(s); i++ {
+		out = append(out, s[i], 0)
+	}
+	return out
+}
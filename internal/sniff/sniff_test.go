@@ -0,0 +1,79 @@
+package sniff
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func zipBytes(t *testing.T, entries ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for i := 0; i < len(entries); i += 2 {
+		// The OpenDocument mimetype entry must be stored uncompressed
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: entries[i], Method: zip.Store})
+		if err != nil {
+			t.Fatalf("failed to add zip entry: %v", err)
+		}
+		fw.Write([]byte(entries[i+1]))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to write zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectBytes(t *testing.T) {
+	tar := make([]byte, 512)
+	copy(tar[257:], "ustar")
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"pdf", []byte("%PDF-1.7\n"), "application/pdf"},
+		{"png", []byte("\x89PNG\r\n\x1A\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"jpeg", []byte("\xFF\xD8\xFF\xE0\x00\x10JFIF"), "image/jpeg"},
+		{"heic", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"), "image/heic"},
+		{"mp4", []byte("\x00\x00\x00\x20ftypisom\x00\x00\x02\x00"), "video/mp4"},
+		{"mov", []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00"), "video/quicktime"},
+		{"webm", []byte("\x1A\x45\xDF\xA3\x9F\x42\x86\x81\x01\x42\x82\x84webm"), "video/webm"},
+		{"gzip", []byte("\x1F\x8B\x08\x00"), "application/gzip"},
+		{"tar", tar, "application/x-tar"},
+		{"zip", zipBytes(t, "notes.txt", "hello"), "application/zip"},
+		{"docx", zipBytes(t, "[Content_Types].xml", "<Types/>", "word/document.xml", "<w:document/>"), "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"xlsx", zipBytes(t, "[Content_Types].xml", "<Types/>", "xl/workbook.xml", "<workbook/>"), "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{"odt", zipBytes(t, "mimetype", "application/vnd.oasis.opendocument.text", "content.xml", "<office/>"), "application/vnd.oasis.opendocument.text"},
+		{"epub", zipBytes(t, "mimetype", "application/epub+zip"), "application/epub+zip"},
+		{"doc", append([]byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"), utf16("WordDocument")...), "application/msword"},
+		{"text", []byte("just some notes\n"), "text/plain"},
+		{"html", []byte("<!DOCTYPE html><html>"), "text/html"},
+		{"binary", []byte{0x00, 0x01, 0x02, 0x03}, Unknown},
+		{"empty", nil, "text/plain"},
+	}
+	for _, tt := range tests {
+		if got := DetectBytes(tt.data); got != tt.want {
+			t.Errorf("DetectBytes(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	// A PNG saved with the wrong extension
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte("\x89PNG\r\n\x1A\n\x00\x00\x00\rIHDR"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	got, err := Detect(path)
+	if err != nil || got != "image/png" {
+		t.Errorf("Detect() = %q, %v; want image/png", got, err)
+	}
+
+	if _, err := Detect(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Detect() of a missing file should fail")
+	}
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"kalycs/db"
+	"kalycs/internal/database"
+	"kalycs/internal/logging"
+	"time"
+)
+
+// ActionRepo defines methods for the file action journal
+type ActionRepo interface {
+	Record(ctx context.Context, a *db.FileAction) error
+	GetByID(ctx context.Context, id string) (*db.FileAction, error)
+	ListRecent(ctx context.Context, limit int) ([]db.FileAction, error)
+	ListByBatch(ctx context.Context, batchID string) ([]db.FileAction, error)
+	ListSince(ctx context.Context, since time.Time) ([]db.FileAction, error)
+	MarkUndone(ctx context.Context, id string) error
+	WasUndone(ctx context.Context, oldPath string, since time.Time) (bool, error)
+	IsLiveSource(ctx context.Context, oldPath string) (bool, error)
+	RenamedFrom(ctx context.Context, fileID string) (string, error)
+}
+
+type actionRepo struct {
+	db *sql.DB
+}
+
+func NewActionRepo(db *sql.DB) ActionRepo {
+	return &actionRepo{db: db}
+}
+
+const actionColumns = `id, file_id, action, old_path, new_path, size, mtime, rule_id, batch_id, created_at, undone_at`
+
+func (r *actionRepo) Record(ctx context.Context, a *db.FileAction) error {
+	if a.ID == "" {
+		a.ID = database.GenerateID()
+	}
+	a.CreatedAt = time.Now().UTC()
+
+	q := `INSERT INTO file_actions (id, file_id, action, old_path, new_path, size, mtime, rule_id, batch_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.db.ExecContext(ctx, q, a.ID, a.FileID, a.Action, a.OldPath, a.NewPath, a.Size, a.Mtime, a.RuleID, a.BatchID, a.CreatedAt)
+	if err != nil {
+		logging.L().Errorw("Failed to record file action", "file_id", a.FileID, "action", a.Action, "error", err)
+		return err
+	}
+	logging.L().Infow("File action recorded", "action_id", a.ID, "file_id", a.FileID, "action", a.Action, "old_path", a.OldPath, "new_path", a.NewPath)
+	return nil
+}
+
+func (r *actionRepo) GetByID(ctx context.Context, id string) (*db.FileAction, error) {
+	q := `SELECT ` + actionColumns + ` FROM file_actions WHERE id = ?`
+	a := &db.FileAction{}
+	err := r.db.QueryRowContext(ctx, q, id).Scan(&a.ID, &a.FileID, &a.Action, &a.OldPath, &a.NewPath, &a.Size, &a.Mtime, &a.RuleID, &a.BatchID, &a.CreatedAt, &a.UndoneAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+func (r *actionRepo) ListRecent(ctx context.Context, limit int) ([]db.FileAction, error) {
+	q := `SELECT ` + actionColumns + ` FROM file_actions ORDER BY created_at DESC LIMIT ?`
+	return r.list(ctx, q, limit)
+}
+
+// ListByBatch returns the actions of a batch, newest first
+func (r *actionRepo) ListByBatch(ctx context.Context, batchID string) ([]db.FileAction, error) {
+	q := `SELECT ` + actionColumns + ` FROM file_actions WHERE batch_id = ? ORDER BY created_at DESC`
+	return r.list(ctx, q, batchID)
+}
+
+// ListSince returns the actions recorded at or after since, newest first
+func (r *actionRepo) ListSince(ctx context.Context, since time.Time) ([]db.FileAction, error) {
+	q := `SELECT ` + actionColumns + ` FROM file_actions WHERE created_at >= ? ORDER BY created_at DESC`
+	return r.list(ctx, q, since.UTC())
+}
+
+func (r *actionRepo) list(ctx context.Context, q string, args ...interface{}) ([]db.FileAction, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []db.FileAction
+	for rows.Next() {
+		var a db.FileAction
+		if err := rows.Scan(&a.ID, &a.FileID, &a.Action, &a.OldPath, &a.NewPath, &a.Size, &a.Mtime, &a.RuleID, &a.BatchID, &a.CreatedAt, &a.UndoneAt); err != nil {
+			return nil, err
+		}
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
+
+func (r *actionRepo) MarkUndone(ctx context.Context, id string) error {
+	q := `UPDATE file_actions SET undone_at = ? WHERE id = ? AND undone_at IS NULL`
+	result, err := r.db.ExecContext(ctx, q, time.Now().UTC(), id)
+	if err != nil {
+		logging.L().Errorw("Failed to mark file action undone", "action_id", id, "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("file action with ID '%s' not found or already undone", id)
+	}
+	return nil
+}
+
+// WasUndone reports whether an action that moved a file away from oldPath
+// was undone at or after since.
+func (r *actionRepo) WasUndone(ctx context.Context, oldPath string, since time.Time) (bool, error) {
+	q := `SELECT EXISTS(SELECT 1 FROM file_actions WHERE old_path = ? AND undone_at >= ?)`
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, q, oldPath, since.UTC()).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// IsLiveSource reports whether oldPath is the source of a copy or link that has
+// not been undone. Such files stay in place after classification but are
+// tracked under their copy's path, so they must not be classified again.
+func (r *actionRepo) IsLiveSource(ctx context.Context, oldPath string) (bool, error) {
+	q := `SELECT EXISTS(SELECT 1 FROM file_actions
+	WHERE old_path = ? AND action IN ('copy', 'hardlink', 'symlink') AND undone_at IS NULL)`
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, q, oldPath).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// RenamedFrom returns the path a file had before its most recent rename that
+// has not been undone, or "" when no such rename was journaled for the file
+func (r *actionRepo) RenamedFrom(ctx context.Context, fileID string) (string, error) {
+	q := `SELECT old_path FROM file_actions
+	WHERE file_id = ? AND action = 'rename' AND undone_at IS NULL
+	ORDER BY created_at DESC, id DESC LIMIT 1`
+	var oldPath string
+	err := r.db.QueryRowContext(ctx, q, fileID).Scan(&oldPath)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return oldPath, err
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"kalycs/internal/logging"
+)
+
+// MetadataRepo defines methods for the metadata fields read from files
+type MetadataRepo interface {
+	Replace(ctx context.Context, fileID string, fields map[string]string) error
+	Get(ctx context.Context, fileID string) (map[string]string, error)
+	ListAll(ctx context.Context) (map[string]map[string]string, error)
+}
+
+type metadataRepo struct {
+	db *sql.DB
+}
+
+func NewMetadataRepo(db *sql.DB) MetadataRepo {
+	return &metadataRepo{db: db}
+}
+
+// Replace stores the fields of a file in place of those stored before
+func (r *metadataRepo) Replace(ctx context.Context, fileID string, fields map[string]string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM file_metadata WHERE file_id = ?`, fileID); err != nil {
+		logging.L().Errorw("Failed to clear file metadata", "file_id", fileID, "error", err)
+		return err
+	}
+	for key, value := range fields {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO file_metadata (file_id, key, value) VALUES (?, ?, ?)`, fileID, key, value); err != nil {
+			logging.L().Errorw("Failed to store file metadata", "file_id", fileID, "key", key, "error", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Get returns the fields of a file, or nil when none are stored
+func (r *metadataRepo) Get(ctx context.Context, fileID string) (map[string]string, error) {
+	all, err := r.list(ctx, `SELECT file_id, key, value FROM file_metadata WHERE file_id = ?`, fileID)
+	if err != nil {
+		return nil, err
+	}
+	return all[fileID], nil
+}
+
+// ListAll returns the fields of every file that has any, by file ID
+func (r *metadataRepo) ListAll(ctx context.Context) (map[string]map[string]string, error) {
+	return r.list(ctx, `SELECT file_id, key, value FROM file_metadata`)
+}
+
+func (r *metadataRepo) list(ctx context.Context, q string, args ...any) (map[string]map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	all := make(map[string]map[string]string)
+	for rows.Next() {
+		var fileID, key, value string
+		if err := rows.Scan(&fileID, &key, &value); err != nil {
+			return nil, err
+		}
+		if all[fileID] == nil {
+			all[fileID] = make(map[string]string)
+		}
+		all[fileID][key] = value
+	}
+	return all, rows.Err()
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"kalycs/db"
+)
+
+func TestMetadataRepo(t *testing.T) {
+	testDB := setupTestDB(t)
+	files := NewFileRepo(testDB)
+	repo := NewMetadataRepo(testDB)
+	ctx := context.Background()
+
+	photo := &db.File{Path: "/tmp/IMG_0001.jpg", Name: "IMG_0001.jpg", Ext: "jpg", Size: 10, Mtime: time.Now()}
+	scan := &db.File{Path: "/tmp/scan.pdf", Name: "scan.pdf", Ext: "pdf", Size: 10, Mtime: time.Now()}
+	for _, f := range []*db.File{photo, scan} {
+		if err := files.Upsert(ctx, f); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+
+	if err := repo.Replace(ctx, photo.ID, map[string]string{"camera_model": "X100V", "gps": "true"}); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+	if err := repo.Replace(ctx, scan.ID, map[string]string{"producer": "ScanSnap"}); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+	// Replacing drops fields the file no longer has
+	want := map[string]string{"camera_model": "X100V", "gps": "false"}
+	if err := repo.Replace(ctx, photo.ID, want); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+
+	got, err := repo.Get(ctx, photo.ID)
+	if err != nil || !maps.Equal(got, want) {
+		t.Errorf("Get() = %v, %v; want %v", got, err, want)
+	}
+	if got, err := repo.Get(ctx, "missing-id"); err != nil || got != nil {
+		t.Errorf("Get(missing) = %v, %v; want nil", got, err)
+	}
+
+	all, err := repo.ListAll(ctx)
+	if err != nil {
+		t.Fatalf("ListAll() error = %v", err)
+	}
+	if len(all) != 2 || all[scan.ID]["producer"] != "ScanSnap" {
+		t.Errorf("ListAll() = %v, want the fields of both files", all)
+	}
+
+	// Fields go with their file
+	if err := files.Delete(ctx, scan.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, _ := repo.Get(ctx, scan.ID); got != nil {
+		t.Errorf("Get() after Delete() = %v, want nil", got)
+	}
+}
@@ -7,13 +7,50 @@ import (
 	"kalycs/db"
 	"kalycs/internal/database"
 	"kalycs/internal/logging"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 type FileRepo interface {
 	Upsert(ctx context.Context, f *db.File) error
 	SetProject(ctx context.Context, fileID string, projectID string) error
+	Reassign(ctx context.Context, fileID, fromProjectID string, to Assignment) (bool, error)
+	SetAssignment(ctx context.Context, fileID string, a Assignment) error
 	ByProject(ctx context.Context, projectID string) ([]db.File, error)
 	GetByPath(ctx context.Context, path string) (*db.File, error)
+	GetByID(ctx context.Context, id string) (*db.File, error)
+	UpdatePath(ctx context.Context, fileID string, path string) error
+	Delete(ctx context.Context, fileID string) error
+	MarkDeleted(ctx context.Context, fileID string) error
+	ListMissing(ctx context.Context) ([]db.File, error)
+	ListPresent(ctx context.Context) ([]db.File, error)
+	ListUnderPath(ctx context.Context, path string) ([]db.File, error)
+	SetHash(ctx context.Context, fileID string, hash string) error
+	SetMime(ctx context.Context, fileID string, mime string) error
+	SetSource(ctx context.Context, fileID string, originURL, referrerURL string) error
+	ListUnhashed(ctx context.Context) ([]db.File, error)
+	ListDuplicates(ctx context.Context) ([]db.File, error)
+}
+
+const fileColumns = `id, path, name, ext, size, mtime, project_id, assignment_source, rule_id, match_kind, match_text, mime, origin_url, referrer_url, hash, deleted_at, created_at, updated_at`
+
+// Assignment is the project chosen for a file and how it was chosen
+type Assignment struct {
+	ProjectID string
+	Source    string // db.AssignedByRule, db.AssignedIncoming or db.AssignedManually
+	RuleID    string // rule assignments only
+	MatchKind string
+	MatchText string
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFile(row rowScanner, f *db.File) error {
+	return row.Scan(&f.ID, &f.Path, &f.Name, &f.Ext, &f.Size, &f.Mtime, &f.ProjectID, &f.AssignmentSource, &f.RuleID, &f.MatchKind, &f.MatchText, &f.Mime, &f.OriginURL, &f.ReferrerURL, &f.Hash, &f.DeletedAt, &f.CreatedAt, &f.UpdatedAt)
 }
 
 type fileRepo struct {
@@ -25,10 +62,10 @@ func NewFileRepo(db *sql.DB) FileRepo {
 }
 
 func (r *fileRepo) GetByPath(ctx context.Context, path string) (*db.File, error) {
-	q := `SELECT id, path, name, ext, size, mtime, project_id, created_at, updated_at FROM files WHERE path = ?`
+	q := `SELECT ` + fileColumns + ` FROM files WHERE path = ?`
 	row := r.db.QueryRowContext(ctx, q, path)
 	f := &db.File{}
-	err := row.Scan(&f.ID, &f.Path, &f.Name, &f.Ext, &f.Size, &f.Mtime, &f.ProjectID, &f.CreatedAt, &f.UpdatedAt)
+	err := scanFile(row, f)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found is not an error, just means no file
@@ -38,25 +75,59 @@ func (r *fileRepo) GetByPath(ctx context.Context, path string) (*db.File, error)
 	return f, nil
 }
 
+func (r *fileRepo) GetByID(ctx context.Context, id string) (*db.File, error) {
+	q := `SELECT ` + fileColumns + ` FROM files WHERE id = ?`
+	row := r.db.QueryRowContext(ctx, q, id)
+	f := &db.File{}
+	err := scanFile(row, f)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
 func (r *fileRepo) Upsert(ctx context.Context, f *db.File) error {
 	// Use ON CONFLICT to perform an upsert. This is more atomic and efficient.
+	// A manually assigned file keeps its project, so the assignment is read
+	// back along with the ID and the source. The stored hash is kept only
+	// while size and mtime are unchanged. A recorded source is kept when the
+	// file no longer carries one, as copying it may have dropped the
+	// attributes it came in.
 	q := `
-	INSERT INTO files (id, path, name, ext, size, mtime, project_id)
-	VALUES (?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO files (id, path, name, ext, size, mtime, project_id, assignment_source, rule_id, match_kind, match_text, mime, origin_url, referrer_url)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(path) DO UPDATE SET
 		name = excluded.name,
 		ext = excluded.ext,
 		size = excluded.size,
 		mtime = excluded.mtime,
-		project_id = excluded.project_id,
-		updated_at = CURRENT_TIMESTAMP`
+		project_id = CASE WHEN files.assignment_source = 'manual' THEN files.project_id ELSE excluded.project_id END,
+		rule_id = CASE WHEN files.assignment_source = 'manual' THEN files.rule_id ELSE excluded.rule_id END,
+		match_kind = CASE WHEN files.assignment_source = 'manual' THEN files.match_kind ELSE excluded.match_kind END,
+		match_text = CASE WHEN files.assignment_source = 'manual' THEN files.match_text ELSE excluded.match_text END,
+		assignment_source = CASE WHEN files.assignment_source = 'manual' THEN files.assignment_source ELSE excluded.assignment_source END,
+		mime = excluded.mime,
+		origin_url = CASE WHEN excluded.origin_url != '' OR excluded.referrer_url != '' THEN excluded.origin_url ELSE files.origin_url END,
+		referrer_url = CASE WHEN excluded.origin_url != '' OR excluded.referrer_url != '' THEN excluded.referrer_url ELSE files.referrer_url END,
+		hash = CASE WHEN files.size IS excluded.size AND files.mtime IS excluded.mtime THEN files.hash END,
+		deleted_at = NULL,
+		updated_at = CURRENT_TIMESTAMP
+	RETURNING id, project_id, assignment_source, rule_id, match_kind, match_text, origin_url, referrer_url`
 
 	// If the file doesn't have an ID, it's new, so we generate one.
 	if f.ID == "" {
 		f.ID = database.GenerateID()
 	}
+	if f.AssignmentSource == "" {
+		f.AssignmentSource = db.AssignedByRule
+	}
 
-	_, err := r.db.ExecContext(ctx, q, f.ID, f.Path, f.Name, f.Ext, f.Size, f.Mtime, f.ProjectID)
+	// On conflict the existing row keeps its ID, so read it back.
+	err := r.db.QueryRowContext(ctx, q, f.ID, f.Path, f.Name, f.Ext, f.Size, f.Mtime, f.ProjectID, f.AssignmentSource, f.RuleID, f.MatchKind, f.MatchText, f.Mime, f.OriginURL, f.ReferrerURL).
+		Scan(&f.ID, &f.ProjectID, &f.AssignmentSource, &f.RuleID, &f.MatchKind, &f.MatchText, &f.OriginURL, &f.ReferrerURL)
 	if err != nil {
 		logging.L().Errorw("Failed to upsert file", "file_path", f.Path, "file_name", f.Name, "error", err)
 		return err
@@ -71,6 +142,8 @@ func (r *fileRepo) Upsert(ctx context.Context, f *db.File) error {
 	return nil
 }
 
+// SetProject records the user's choice of project for a file. The assignment
+// is marked manual so reclassification leaves it alone.
 func (r *fileRepo) SetProject(ctx context.Context, fileID string, projectID string) error {
 	var pid interface{}
 	if projectID == "" {
@@ -79,8 +152,8 @@ func (r *fileRepo) SetProject(ctx context.Context, fileID string, projectID stri
 		pid = projectID
 	}
 
-	q := `UPDATE files SET project_id = ? WHERE id = ?`
-	result, err := r.db.ExecContext(ctx, q, pid, fileID)
+	q := `UPDATE files SET project_id = ?, assignment_source = ?, rule_id = NULL, match_kind = '', match_text = '' WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, q, pid, db.AssignedManually, fileID)
 	if err != nil {
 		logging.L().Errorw("Failed to set project for file", "file_id", fileID, "project_id", projectID, "error", err)
 		return err
@@ -101,9 +174,60 @@ func (r *fileRepo) SetProject(ctx context.Context, fileID string, projectID stri
 	return nil
 }
 
+// Reassign moves a file to another project on behalf of the classifier. The
+// update only applies while the file is still in fromProjectID and not
+// manually assigned, so a concurrent change by the user wins; it reports
+// whether the file was moved.
+func (r *fileRepo) Reassign(ctx context.Context, fileID, fromProjectID string, to Assignment) (bool, error) {
+	q := `UPDATE files SET project_id = ?, assignment_source = ?, rule_id = ?, match_kind = ?, match_text = ?
+	WHERE id = ? AND project_id IS ? AND assignment_source != ?`
+	result, err := r.db.ExecContext(ctx, q, to.ProjectID, to.Source, nullString(to.RuleID), to.MatchKind, to.MatchText,
+		fileID, nullString(fromProjectID), db.AssignedManually)
+	if err != nil {
+		logging.L().Errorw("Failed to reassign file", "file_id", fileID, "project_id", to.ProjectID, "error", err)
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// SetAssignment overwrites a file's project and how it was chosen, whatever it was before
+func (r *fileRepo) SetAssignment(ctx context.Context, fileID string, a Assignment) error {
+	q := `UPDATE files SET project_id = ?, assignment_source = ?, rule_id = ?, match_kind = ?, match_text = ? WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, q, nullString(a.ProjectID), a.Source, nullString(a.RuleID), a.MatchKind, a.MatchText, fileID)
+	if err != nil {
+		logging.L().Errorw("Failed to set file assignment", "file_id", fileID, "project_id", a.ProjectID, "source", a.Source, "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("file with ID '%s' not found", fileID)
+	}
+
+	logging.L().Infow("File assignment updated", "file_id", fileID, "project_id", a.ProjectID, "source", a.Source, "rule_id", a.RuleID)
+	return nil
+}
+
+// nullString maps an empty string to NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
 func (r *fileRepo) ByProject(ctx context.Context, projectID string) ([]db.File, error) {
-	q := `SELECT id, path, name, ext, size, mtime, project_id, created_at, updated_at FROM files WHERE project_id = ?`
-	rows, err := r.db.QueryContext(ctx, q, projectID)
+	q := `SELECT ` + fileColumns + ` FROM files WHERE project_id = ? AND deleted_at IS NULL`
+	return r.list(ctx, q, projectID)
+}
+
+func (r *fileRepo) list(ctx context.Context, q string, args ...any) ([]db.File, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +236,7 @@ func (r *fileRepo) ByProject(ctx context.Context, projectID string) ([]db.File,
 	var files []db.File
 	for rows.Next() {
 		var f db.File
-		if err := rows.Scan(&f.ID, &f.Path, &f.Name, &f.Ext, &f.Size, &f.Mtime, &f.ProjectID, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		if err := scanFile(rows, &f); err != nil {
 			return nil, err
 		}
 		files = append(files, f)
@@ -124,3 +248,171 @@ func (r *fileRepo) ByProject(ctx context.Context, projectID string) ([]db.File,
 
 	return files, nil
 }
+
+// UpdatePath points an existing file row at a new location, keeping its ID and
+// assignment. A row previously marked deleted is present again afterwards.
+func (r *fileRepo) UpdatePath(ctx context.Context, fileID string, path string) error {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	if len(ext) > 0 {
+		ext = ext[1:]
+	}
+
+	q := `UPDATE files SET path = ?, name = ?, ext = ?, deleted_at = NULL WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, q, path, name, ext, fileID)
+	if err != nil {
+		logging.L().Errorw("Failed to update file path", "file_id", fileID, "path", path, "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		logging.L().Warnw("File path update failed - file not found", "file_id", fileID)
+		return fmt.Errorf("file with ID '%s' not found", fileID)
+	}
+
+	logging.L().Infow("File path updated successfully", "file_id", fileID, "path", path)
+	return nil
+}
+
+func (r *fileRepo) Delete(ctx context.Context, fileID string) error {
+	q := `DELETE FROM files WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, q, fileID)
+	if err != nil {
+		logging.L().Errorw("Failed to delete file", "file_id", fileID, "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		logging.L().Warnw("File deletion failed - file not found", "file_id", fileID)
+		return fmt.Errorf("file with ID '%s' not found", fileID)
+	}
+
+	logging.L().Infow("File deleted successfully", "file_id", fileID)
+	return nil
+}
+
+// MarkDeleted flags a file whose path no longer exists on disk. The row is kept
+// so its assignment and action history survive; a later Upsert or UpdatePath
+// for the file clears the flag.
+func (r *fileRepo) MarkDeleted(ctx context.Context, fileID string) error {
+	q := `UPDATE files SET deleted_at = COALESCE(deleted_at, ?) WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, q, time.Now().UTC(), fileID)
+	if err != nil {
+		logging.L().Errorw("Failed to mark file deleted", "file_id", fileID, "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		logging.L().Warnw("Marking file deleted failed - file not found", "file_id", fileID)
+		return fmt.Errorf("file with ID '%s' not found", fileID)
+	}
+
+	logging.L().Infow("File marked deleted", "file_id", fileID)
+	return nil
+}
+
+// ListMissing returns files marked deleted, most recently missing first
+func (r *fileRepo) ListMissing(ctx context.Context) ([]db.File, error) {
+	q := `SELECT ` + fileColumns + ` FROM files WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC`
+	return r.list(ctx, q)
+}
+
+// ListPresent returns every file that is still on disk, ordered by path
+func (r *fileRepo) ListPresent(ctx context.Context) ([]db.File, error) {
+	q := `SELECT ` + fileColumns + ` FROM files WHERE deleted_at IS NULL ORDER BY path`
+	return r.list(ctx, q)
+}
+
+// ListUnderPath returns present files at path or, when path is a directory, anywhere beneath it
+func (r *fileRepo) ListUnderPath(ctx context.Context, path string) ([]db.File, error) {
+	prefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+	q := `SELECT ` + fileColumns + ` FROM files
+	WHERE deleted_at IS NULL AND (path = ? OR substr(path, 1, length(?)) = ?)`
+	return r.list(ctx, q, path, prefix, prefix)
+}
+
+// SetHash stores the content hash computed for a file
+func (r *fileRepo) SetHash(ctx context.Context, fileID string, hash string) error {
+	q := `UPDATE files SET hash = ? WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, q, hash, fileID)
+	if err != nil {
+		logging.L().Errorw("Failed to set file hash", "file_id", fileID, "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("file with ID '%s' not found", fileID)
+	}
+	return nil
+}
+
+// SetMime caches the content type detected for a file
+func (r *fileRepo) SetMime(ctx context.Context, fileID string, mime string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE files SET mime = ? WHERE id = ?`, mime, fileID)
+	if err != nil {
+		logging.L().Errorw("Failed to set file content type", "file_id", fileID, "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("file with ID '%s' not found", fileID)
+	}
+	return nil
+}
+
+// SetSource records where a file was downloaded from
+func (r *fileRepo) SetSource(ctx context.Context, fileID string, originURL, referrerURL string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE files SET origin_url = ?, referrer_url = ? WHERE id = ?`, originURL, referrerURL, fileID)
+	if err != nil {
+		logging.L().Errorw("Failed to set file source", "file_id", fileID, "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("file with ID '%s' not found", fileID)
+	}
+	return nil
+}
+
+// ListUnhashed returns present, non-empty files whose content hash is not known yet
+func (r *fileRepo) ListUnhashed(ctx context.Context) ([]db.File, error) {
+	q := `SELECT ` + fileColumns + ` FROM files WHERE hash IS NULL AND deleted_at IS NULL AND size > 0`
+	return r.list(ctx, q)
+}
+
+// ListDuplicates returns present files whose hash is shared with at least one
+// other present file, ordered by hash and then by age
+func (r *fileRepo) ListDuplicates(ctx context.Context) ([]db.File, error) {
+	q := `SELECT ` + fileColumns + ` FROM files
+	WHERE deleted_at IS NULL AND hash IN (
+		SELECT hash FROM files
+		WHERE hash IS NOT NULL AND deleted_at IS NULL
+		GROUP BY hash HAVING COUNT(*) > 1
+	)
+	ORDER BY hash, created_at, path`
+	return r.list(ctx, q)
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kalycs/db"
+)
+
+func TestFileRepo_MarkDeletedAndRestore(t *testing.T) {
+	testDB := setupTestDB(t)
+	repo := NewFileRepo(testDB)
+	ctx := context.Background()
+
+	dir := filepath.Join(t.TempDir(), "Downloads")
+	f := &db.File{Path: filepath.Join(dir, "report.pdf"), Name: "report.pdf", Ext: "pdf", Size: 10, Mtime: time.Now()}
+	if err := repo.Upsert(ctx, f); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	if err := repo.MarkDeleted(ctx, f.ID); err != nil {
+		t.Fatalf("MarkDeleted() error = %v", err)
+	}
+	missing, err := repo.ListMissing(ctx)
+	if err != nil {
+		t.Fatalf("ListMissing() error = %v", err)
+	}
+	if len(missing) != 1 || missing[0].ID != f.ID || !missing[0].DeletedAt.Valid {
+		t.Fatalf("ListMissing() = %+v, want the deleted file", missing)
+	}
+	if under, _ := repo.ListUnderPath(ctx, dir); len(under) != 0 {
+		t.Errorf("ListUnderPath() returned %d missing files, want 0", len(under))
+	}
+
+	// The file showing up again at the same path clears the flag
+	if err := repo.Upsert(ctx, &db.File{Path: f.Path, Name: f.Name, Ext: f.Ext, Size: 10, Mtime: f.Mtime}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	got, err := repo.GetByID(ctx, f.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetByID() = %v, %v", got, err)
+	}
+	if got.DeletedAt.Valid {
+		t.Error("Upsert() did not clear deleted_at")
+	}
+
+	if err := repo.MarkDeleted(ctx, "missing-id"); err == nil {
+		t.Error("MarkDeleted() of unknown file should fail")
+	}
+}
+
+func TestFileRepo_ListUnderPath(t *testing.T) {
+	testDB := setupTestDB(t)
+	repo := NewFileRepo(testDB)
+	ctx := context.Background()
+
+	root := t.TempDir()
+	paths := []string{
+		filepath.Join(root, "photos", "a.jpg"),
+		filepath.Join(root, "photos", "2024", "b.jpg"),
+		filepath.Join(root, "photos-old", "c.jpg"),
+		filepath.Join(root, "café", "d.jpg"),
+	}
+	for _, p := range paths {
+		if err := repo.Upsert(ctx, &db.File{Path: p, Name: filepath.Base(p), Ext: "jpg"}); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{filepath.Join(root, "photos"), 2},
+		{filepath.Join(root, "photos", "a.jpg"), 1},
+		{filepath.Join(root, "café"), 1},
+		{root, 4},
+		{filepath.Join(root, "none"), 0},
+	}
+	for _, tt := range tests {
+		got, err := repo.ListUnderPath(ctx, tt.path)
+		if err != nil {
+			t.Fatalf("ListUnderPath(%q) error = %v", tt.path, err)
+		}
+		if len(got) != tt.want {
+			t.Errorf("ListUnderPath(%q) returned %d files, want %d", tt.path, len(got), tt.want)
+		}
+	}
+}
+
+func TestFileRepo_UpsertKeepsSource(t *testing.T) {
+	testDB := setupTestDB(t)
+	repo := NewFileRepo(testDB)
+	ctx := context.Background()
+
+	f := &db.File{Path: filepath.Join(t.TempDir(), "main.zip"), Name: "main.zip", Ext: "zip", Size: 10, Mtime: time.Now(),
+		OriginURL: "https://codeload.github.com/owner/repo/zip/main", ReferrerURL: "https://github.com/owner/repo"}
+	if err := repo.Upsert(ctx, f); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	// Seen again without its attributes, e.g. after a copy that dropped them
+	again := &db.File{Path: f.Path, Name: f.Name, Ext: f.Ext, Size: 10, Mtime: f.Mtime}
+	if err := repo.Upsert(ctx, again); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if again.OriginURL != f.OriginURL || again.ReferrerURL != f.ReferrerURL {
+		t.Errorf("Upsert() source = %q, %q; want the recorded one", again.OriginURL, again.ReferrerURL)
+	}
+	got, _ := repo.GetByID(ctx, f.ID)
+	if got.OriginURL != f.OriginURL {
+		t.Errorf("OriginURL = %q, want %q", got.OriginURL, f.OriginURL)
+	}
+
+	if err := repo.SetSource(ctx, f.ID, "https://example.com/main.zip", ""); err != nil {
+		t.Fatalf("SetSource() error = %v", err)
+	}
+	got, _ = repo.GetByID(ctx, f.ID)
+	if got.OriginURL != "https://example.com/main.zip" || got.ReferrerURL != "" {
+		t.Errorf("source = %q, %q after SetSource()", got.OriginURL, got.ReferrerURL)
+	}
+	if err := repo.SetSource(ctx, "missing-id", "", ""); err == nil {
+		t.Error("SetSource() of unknown file should fail")
+	}
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// moveID returns ids with id taken out and put back directly before or after targetID
+func moveID(ids []string, id, targetID string, after bool) []string {
+	moved := make([]string, 0, len(ids))
+	for _, other := range ids {
+		if other == id {
+			continue
+		}
+		if other == targetID && !after {
+			moved = append(moved, id)
+		}
+		moved = append(moved, other)
+		if other == targetID && after {
+			moved = append(moved, id)
+		}
+	}
+	return moved
+}
+
+// renumber sets the priority of each row of table to its index in ids
+func renumber(ctx context.Context, tx *sql.Tx, table string, ids []string) error {
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("UPDATE %s SET priority = ? WHERE id = ? AND priority != ?", table))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, id := range ids {
+		if _, err := stmt.ExecContext(ctx, i, id, i); err != nil {
+			return fmt.Errorf("failed to update priority of %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// scanIDs reads a single id column and closes rows
+func scanIDs(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 
 	"kalycs/db"
 	"kalycs/internal/database"
@@ -25,8 +26,13 @@ type ProjectRepo interface {
 	Create(ctx context.Context, project *db.Project) error
 	Update(ctx context.Context, project *db.Project) error
 	Delete(ctx context.Context, id string) error
+	Move(ctx context.Context, id, targetID string, after bool) error
 }
 
+// projectOrder sorts projects by priority; projects sharing one fall back to
+// the newest first and then ID
+const projectOrder = `priority, created_at DESC, id`
+
 // NewProjectRepo creates a new instance of ProjectRepo with the given database connection
 func NewProjectRepo(db *sql.DB) ProjectRepo {
 	return &projectRepo{db: db}
@@ -43,7 +49,7 @@ func (r *projectRepo) GetByID(ctx context.Context, id string) (*db.Project, erro
 	}
 
 	query := `
-		SELECT id, name, description, is_active, is_favourite, created_at, updated_at
+		SELECT id, name, description, is_active, is_favourite, destination_dir, action, priority, created_at, updated_at
 		FROM projects
 		WHERE id = ?
 	`
@@ -55,6 +61,9 @@ func (r *projectRepo) GetByID(ctx context.Context, id string) (*db.Project, erro
 		&project.Description,
 		&project.IsActive,
 		&project.IsFavourite,
+		&project.DestinationDir,
+		&project.Action,
+		&project.Priority,
 		&project.CreatedAt,
 		&project.UpdatedAt,
 	)
@@ -71,7 +80,7 @@ func (r *projectRepo) GetByID(ctx context.Context, id string) (*db.Project, erro
 
 func (r *projectRepo) GetByName(ctx context.Context, name string) (*db.Project, error) {
 	query := `
-		SELECT id, name, description, is_active, is_favourite, created_at, updated_at
+		SELECT id, name, description, is_active, is_favourite, destination_dir, action, priority, created_at, updated_at
 		FROM projects
 		WHERE name = ?
 	`
@@ -83,6 +92,9 @@ func (r *projectRepo) GetByName(ctx context.Context, name string) (*db.Project,
 		&project.Description,
 		&project.IsActive,
 		&project.IsFavourite,
+		&project.DestinationDir,
+		&project.Action,
+		&project.Priority,
 		&project.CreatedAt,
 		&project.UpdatedAt,
 	)
@@ -99,10 +111,9 @@ func (r *projectRepo) GetByName(ctx context.Context, name string) (*db.Project,
 
 func (r *projectRepo) GetAll(ctx context.Context) ([]db.Project, error) {
 	query := `
-		SELECT id, name, description, is_active, is_favourite, created_at, updated_at
+		SELECT id, name, description, is_active, is_favourite, destination_dir, action, priority, created_at, updated_at
 		FROM projects
-		ORDER BY created_at DESC
-	`
+		ORDER BY ` + projectOrder
 
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
@@ -119,6 +130,9 @@ func (r *projectRepo) GetAll(ctx context.Context) ([]db.Project, error) {
 			&project.Description,
 			&project.IsActive,
 			&project.IsFavourite,
+			&project.DestinationDir,
+			&project.Action,
+			&project.Priority,
 			&project.CreatedAt,
 			&project.UpdatedAt,
 		)
@@ -152,21 +166,25 @@ func (r *projectRepo) Create(ctx context.Context, project *db.Project) error {
 	database.NormalizeProjectData(project)
 	database.PrepareProjectForCreation(project)
 
-	// Direct insert - no transaction needed for simple insert
+	// Direct insert - no transaction needed for simple insert.
+	// New projects go first, matching the newest-first listing.
 	query := `
-		INSERT INTO projects (id, name, description, is_active, is_favourite, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO projects (id, name, description, is_active, is_favourite, destination_dir, action, priority, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MIN(priority) - 1, 0) FROM projects), ?, ?)
+		RETURNING priority
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	err := r.db.QueryRowContext(ctx, query,
 		project.ID,
 		project.Name,
 		project.Description,
 		project.IsActive,
 		project.IsFavourite,
+		project.DestinationDir,
+		project.Action,
 		project.CreatedAt,
 		project.UpdatedAt,
-	)
+	).Scan(&project.Priority)
 
 	if err != nil {
 		// Handle specific database errors using database utilities
@@ -204,7 +222,7 @@ func (r *projectRepo) Update(ctx context.Context, project *db.Project) error {
 
 	query := `
 		UPDATE projects 
-		SET name = ?, description = ?, is_active = ?, is_favourite = ?, updated_at = ?
+		SET name = ?, description = ?, is_active = ?, is_favourite = ?, destination_dir = ?, action = ?, updated_at = ?
 		WHERE id = ?
 	`
 
@@ -213,6 +231,8 @@ func (r *projectRepo) Update(ctx context.Context, project *db.Project) error {
 		project.Description,
 		project.IsActive,
 		project.IsFavourite,
+		project.DestinationDir,
+		project.Action,
 		project.UpdatedAt,
 		project.ID,
 	)
@@ -282,3 +302,46 @@ func (r *projectRepo) Delete(ctx context.Context, id string) error {
 	logging.L().Infow("Project deleted successfully", "project_id", id)
 	return nil
 }
+
+// Move places the project id directly before or after targetID in the
+// evaluation order and renumbers all projects from 0.
+func (r *projectRepo) Move(ctx context.Context, id, targetID string, after bool) error {
+	if id == "" || targetID == "" {
+		return fmt.Errorf("project ID cannot be empty")
+	}
+	if id == targetID {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM projects ORDER BY `+projectOrder)
+	if err != nil {
+		return fmt.Errorf("failed to query projects: %w", err)
+	}
+	ids, err := scanIDs(rows)
+	if err != nil {
+		return fmt.Errorf("failed to scan projects: %w", err)
+	}
+
+	for _, projectID := range []string{id, targetID} {
+		if !slices.Contains(ids, projectID) {
+			return fmt.Errorf("project with ID '%s' not found", projectID)
+		}
+	}
+
+	if err := renumber(ctx, tx, "projects", moveID(ids, id, targetID, after)); err != nil {
+		logging.L().Errorw("Failed to reorder projects", "project_id", id, "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit project order: %w", err)
+	}
+
+	logging.L().Infow("Project moved", "project_id", id, "target_id", targetID, "after", after)
+	return nil
+}
@@ -1153,3 +1153,58 @@ func BenchmarkProjectRepo_GetAll(b *testing.B) {
 		}
 	}
 }
+
+func TestProjectRepo_Move(t *testing.T) {
+	testDB := setupTestDB(t)
+	repo := NewProjectRepo(testDB)
+	ctx := context.Background()
+
+	var ids []string
+	for _, name := range []string{"First", "Second", "Third"} {
+		p := createTestProject(name)
+		if err := repo.Create(ctx, p); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		ids = append(ids, p.ID)
+	}
+
+	names := func() string {
+		projects, err := repo.GetAll(ctx)
+		if err != nil {
+			t.Fatalf("GetAll() error = %v", err)
+		}
+		var got []string
+		for _, p := range projects {
+			got = append(got, p.Name)
+		}
+		return strings.Join(got, ",")
+	}
+
+	if got := names(); got != "Third,Second,First" {
+		t.Fatalf("GetAll() order = %s, want newest first", got)
+	}
+	if err := repo.Move(ctx, ids[0], ids[2], false); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if got := names(); got != "First,Third,Second" {
+		t.Errorf("after Move() order = %s, want First,Third,Second", got)
+	}
+	if err := repo.Move(ctx, ids[2], ids[1], true); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if got := names(); got != "First,Second,Third" {
+		t.Errorf("after Move() order = %s, want First,Second,Third", got)
+	}
+
+	p, err := repo.GetByID(ctx, ids[1])
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if p.Priority != 1 {
+		t.Errorf("priority after Move() = %d, want 1", p.Priority)
+	}
+
+	if err := repo.Move(ctx, ids[0], "missing-id", true); err == nil {
+		t.Error("Move() onto unknown project should fail")
+	}
+}
@@ -25,6 +25,16 @@ type RuleRepo interface {
 	Create(ctx context.Context, rule *db.Rule) error
 	Update(ctx context.Context, rule *db.Rule) error
 	Delete(ctx context.Context, id string) error
+	Move(ctx context.Context, id, targetID string, after bool) error
+}
+
+const ruleColumns = `id, name, project_id, rule, texts, conditions, case_sensitive, priority, rename_template, created_at, updated_at`
+
+// ruleOrder sorts rules by priority; rules sharing one fall back to creation order and then ID
+const ruleOrder = `priority, created_at, id`
+
+func scanRule(row rowScanner, rule *db.Rule) error {
+	return row.Scan(&rule.ID, &rule.Name, &rule.ProjectID, &rule.Rule, &rule.Texts, &rule.Conditions, &rule.CaseSensitive, &rule.Priority, &rule.RenameTemplate, &rule.CreatedAt, &rule.UpdatedAt)
 }
 
 func NewRuleRepo(db *sql.DB) RuleRepo {
@@ -35,10 +45,10 @@ func NewRuleRepo(db *sql.DB) RuleRepo {
 }
 
 func (r *ruleRepo) GetByID(ctx context.Context, id string) (*db.Rule, error) {
-	q := `SELECT id, name, project_id, rule, texts, case_sensitive, created_at, updated_at FROM rules WHERE id = ?`
+	q := `SELECT ` + ruleColumns + ` FROM rules WHERE id = ?`
 	row := r.db.QueryRowContext(ctx, q, id)
 	rule := &db.Rule{}
-	err := row.Scan(&rule.ID, &rule.Name, &rule.ProjectID, &rule.Rule, &rule.Texts, &rule.CaseSensitive, &rule.CreatedAt, &rule.UpdatedAt)
+	err := scanRule(row, rule)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Consider not found as nil, not an error
@@ -49,7 +59,7 @@ func (r *ruleRepo) GetByID(ctx context.Context, id string) (*db.Rule, error) {
 }
 
 func (r *ruleRepo) GetAllByProject(ctx context.Context, projectID string) ([]db.Rule, error) {
-	q := `SELECT id, name, project_id, rule, texts, case_sensitive, created_at, updated_at FROM rules WHERE project_id = ?`
+	q := `SELECT ` + ruleColumns + ` FROM rules WHERE project_id = ? ORDER BY ` + ruleOrder
 	rows, err := r.db.QueryContext(ctx, q, projectID)
 	if err != nil {
 		return nil, err
@@ -59,7 +69,7 @@ func (r *ruleRepo) GetAllByProject(ctx context.Context, projectID string) ([]db.
 	var rules []db.Rule
 	for rows.Next() {
 		var rule db.Rule
-		if err := rows.Scan(&rule.ID, &rule.Name, &rule.ProjectID, &rule.Rule, &rule.Texts, &rule.CaseSensitive, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+		if err := scanRule(rows, &rule); err != nil {
 			return nil, err
 		}
 		rules = append(rules, rule)
@@ -69,10 +79,11 @@ func (r *ruleRepo) GetAllByProject(ctx context.Context, projectID string) ([]db.
 
 func (r *ruleRepo) ListActive(ctx context.Context) ([]db.Rule, error) {
 	q := `
-        SELECT r.id, r.name, r.project_id, r.rule, r.texts, r.case_sensitive, r.created_at, r.updated_at
+        SELECT r.id, r.name, r.project_id, r.rule, r.texts, r.conditions, r.case_sensitive, r.priority, r.rename_template, r.created_at, r.updated_at
         FROM rules r
         INNER JOIN projects p ON r.project_id = p.id
-        WHERE p.is_active = 1`
+        WHERE p.is_active = 1
+        ORDER BY p.priority, p.created_at DESC, p.id, r.priority, r.created_at, r.id`
 	rows, err := r.db.QueryContext(ctx, q)
 	if err != nil {
 		return nil, err
@@ -82,7 +93,7 @@ func (r *ruleRepo) ListActive(ctx context.Context) ([]db.Rule, error) {
 	var rules []db.Rule
 	for rows.Next() {
 		var rule db.Rule
-		if err := rows.Scan(&rule.ID, &rule.Name, &rule.ProjectID, &rule.Rule, &rule.Texts, &rule.CaseSensitive, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+		if err := scanRule(rows, &rule); err != nil {
 			return nil, err
 		}
 		rules = append(rules, rule)
@@ -96,8 +107,11 @@ func (r *ruleRepo) Create(ctx context.Context, rule *db.Rule) error {
 		return err
 	}
 	rule.ID = database.GenerateID()
-	q := `INSERT INTO rules (id, name, project_id, rule, texts, case_sensitive) VALUES (?, ?, ?, ?, ?, ?)`
-	_, err := r.db.ExecContext(ctx, q, rule.ID, rule.Name, rule.ProjectID, rule.Rule, rule.Texts, rule.CaseSensitive)
+	// New rules go last in their project
+	q := `INSERT INTO rules (id, name, project_id, rule, texts, conditions, case_sensitive, rename_template, priority)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(priority) + 1, 0) FROM rules WHERE project_id = ?))
+		RETURNING priority`
+	err := r.db.QueryRowContext(ctx, q, rule.ID, rule.Name, rule.ProjectID, rule.Rule, rule.Texts, rule.Conditions, rule.CaseSensitive, rule.RenameTemplate, rule.ProjectID).Scan(&rule.Priority)
 	if err != nil {
 		logging.L().Errorw("Failed to create rule", "rule_id", rule.ID, "rule_name", rule.Name, "project_id", rule.ProjectID, "error", err)
 		return err
@@ -111,8 +125,14 @@ func (r *ruleRepo) Update(ctx context.Context, rule *db.Rule) error {
 		logging.L().Warnw("Rule validation failed during update", "rule_id", rule.ID, "rule_name", rule.Name, "error", err)
 		return err
 	}
-	q := `UPDATE rules SET name = ?, project_id = ?, rule = ?, texts = ?, case_sensitive = ? WHERE id = ?`
-	result, err := r.db.ExecContext(ctx, q, rule.Name, rule.ProjectID, rule.Rule, rule.Texts, rule.CaseSensitive, rule.ID)
+	// The priority is kept, unless the rule moves to another project where it goes last
+	q := `UPDATE rules SET name = ?, rule = ?, texts = ?, conditions = ?, case_sensitive = ?, rename_template = ?,
+		priority = CASE WHEN project_id = ? THEN priority
+			ELSE (SELECT COALESCE(MAX(priority) + 1, 0) FROM rules WHERE project_id = ?) END,
+		project_id = ?
+		WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, q, rule.Name, rule.Rule, rule.Texts, rule.Conditions, rule.CaseSensitive, rule.RenameTemplate,
+		rule.ProjectID, rule.ProjectID, rule.ProjectID, rule.ID)
 	if err != nil {
 		logging.L().Errorw("Failed to update rule", "rule_id", rule.ID, "rule_name", rule.Name, "error", err)
 		return err
@@ -155,3 +175,52 @@ func (r *ruleRepo) Delete(ctx context.Context, id string) error {
 	logging.L().Infow("Rule deleted successfully", "rule_id", id)
 	return nil
 }
+
+// Move places the rule id directly before or after targetID, which must be in
+// the same project, and renumbers the project's rules from 0 in the new order.
+func (r *ruleRepo) Move(ctx context.Context, id, targetID string, after bool) error {
+	if id == targetID {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	projectIDs := make(map[string]string, 2)
+	for _, ruleID := range []string{id, targetID} {
+		var projectID string
+		err := tx.QueryRowContext(ctx, `SELECT project_id FROM rules WHERE id = ?`, ruleID).Scan(&projectID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("rule with ID '%s' not found", ruleID)
+		}
+		if err != nil {
+			return err
+		}
+		projectIDs[ruleID] = projectID
+	}
+	if projectIDs[id] != projectIDs[targetID] {
+		return fmt.Errorf("rules can only be reordered within their project")
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM rules WHERE project_id = ? ORDER BY `+ruleOrder, projectIDs[id])
+	if err != nil {
+		return err
+	}
+	ids, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+
+	if err := renumber(ctx, tx, "rules", moveID(ids, id, targetID, after)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logging.L().Infow("Rule moved", "rule_id", id, "target_id", targetID, "after", after)
+	return nil
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"kalycs/db"
+)
+
+func ruleNames(t *testing.T, repo RuleRepo, projectID string) []string {
+	t.Helper()
+	rules, err := repo.GetAllByProject(context.Background(), projectID)
+	if err != nil {
+		t.Fatalf("GetAllByProject() error = %v", err)
+	}
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.Name
+	}
+	return names
+}
+
+func TestRuleRepo_Move(t *testing.T) {
+	testDB := setupTestDB(t)
+	projects := NewProjectRepo(testDB)
+	repo := NewRuleRepo(testDB)
+	ctx := context.Background()
+
+	p1 := createTestProject("Invoices")
+	p2 := createTestProject("Photos")
+	for _, p := range []*db.Project{p1, p2} {
+		if err := projects.Create(ctx, p); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	rules := map[string]*db.Rule{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		r := &db.Rule{Name: name, ProjectID: p1.ID, Rule: "contains", Texts: `["` + name + `"]`}
+		if err := repo.Create(ctx, r); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		rules[name] = r
+	}
+	if rules["d"].Priority != 3 {
+		t.Errorf("Create() priority = %d, want 3", rules["d"].Priority)
+	}
+
+	steps := []struct {
+		id, target string
+		after      bool
+		want       string
+	}{
+		{"d", "a", false, "dabc"},
+		{"d", "c", true, "abcd"},
+		{"a", "c", false, "bacd"},
+		{"c", "c", true, "bacd"},
+	}
+	for _, s := range steps {
+		if err := repo.Move(ctx, rules[s.id].ID, rules[s.target].ID, s.after); err != nil {
+			t.Fatalf("Move(%s, %s) error = %v", s.id, s.target, err)
+		}
+		got := ""
+		for _, name := range ruleNames(t, repo, p1.ID) {
+			got += name
+		}
+		if got != s.want {
+			t.Errorf("after Move(%s, %s, %v) order = %s, want %s", s.id, s.target, s.after, got, s.want)
+		}
+	}
+
+	other := &db.Rule{Name: "e", ProjectID: p2.ID, Rule: "contains", Texts: `["e"]`}
+	if err := repo.Create(ctx, other); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if other.Priority != 0 {
+		t.Errorf("first rule of a project has priority %d, want 0", other.Priority)
+	}
+	if err := repo.Move(ctx, other.ID, rules["a"].ID, false); err == nil {
+		t.Error("Move() across projects should fail")
+	}
+	if err := repo.Move(ctx, "missing-id", rules["a"].ID, false); err == nil {
+		t.Error("Move() of unknown rule should fail")
+	}
+
+	// Moving a rule to another project puts it last there
+	moved := rules["b"]
+	moved.ProjectID = p2.ID
+	if err := repo.Update(ctx, moved); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := ruleNames(t, repo, p2.ID); len(got) != 2 || got[1] != "b" {
+		t.Errorf("rules of second project = %v, want [e b]", got)
+	}
+
+	active, err := repo.ListActive(ctx)
+	if err != nil {
+		t.Fatalf("ListActive() error = %v", err)
+	}
+	// Photos was created last, so it comes first
+	want := []string{"e", "b", "a", "c", "d"}
+	if len(active) != len(want) {
+		t.Fatalf("ListActive() returned %d rules, want %d", len(active), len(want))
+	}
+	for i, r := range active {
+		if r.Name != want[i] {
+			t.Errorf("ListActive()[%d] = %s, want %s", i, r.Name, want[i])
+		}
+	}
+}
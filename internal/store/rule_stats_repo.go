@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"kalycs/db"
+	"kalycs/internal/logging"
+	"time"
+)
+
+// dayLayout formats the local day a match is counted under
+const dayLayout = "2006-01-02"
+
+// RuleStatsRepo defines methods for rule match counters
+type RuleStatsRepo interface {
+	RecordMatch(ctx context.Context, ruleID string, at time.Time) error
+	List(ctx context.Context, now time.Time) ([]db.RuleStats, error)
+	PruneDaily(ctx context.Context, now time.Time) error
+}
+
+type ruleStatsRepo struct {
+	db *sql.DB
+}
+
+func NewRuleStatsRepo(db *sql.DB) RuleStatsRepo {
+	return &ruleStatsRepo{db: db}
+}
+
+// RecordMatch counts one file captured by the rule at the given time
+func (r *ruleStatsRepo) RecordMatch(ctx context.Context, ruleID string, at time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts := []struct {
+		q    string
+		args []any
+	}{
+		{`
+		INSERT INTO rule_stats (rule_id, total_matches, last_matched_at) VALUES (?, 1, ?)
+		ON CONFLICT(rule_id) DO UPDATE SET
+			total_matches = total_matches + 1,
+			last_matched_at = excluded.last_matched_at`, []any{ruleID, at.UTC()}},
+		{`
+		INSERT INTO rule_daily_hits (rule_id, day, hits) VALUES (?, ?, 1)
+		ON CONFLICT(rule_id, day) DO UPDATE SET hits = hits + 1`, []any{ruleID, at.Local().Format(dayLayout)}},
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt.q, stmt.args...); err != nil {
+			logging.L().Errorw("Failed to record rule match", "rule_id", ruleID, "error", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// List returns the counters of every rule, including rules that never matched
+func (r *ruleStatsRepo) List(ctx context.Context, now time.Time) ([]db.RuleStats, error) {
+	q := `
+	SELECT r.id, COALESCE(s.total_matches, 0), s.last_matched_at,
+		COALESCE((SELECT SUM(d.hits) FROM rule_daily_hits d WHERE d.rule_id = r.id AND d.day >= ?), 0)
+	FROM rules r
+	LEFT JOIN rule_stats s ON s.rule_id = r.id
+	ORDER BY r.project_id, ` + ruleOrder
+	rows, err := r.db.QueryContext(ctx, q, recentSince(now))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []db.RuleStats
+	for rows.Next() {
+		var s db.RuleStats
+		if err := rows.Scan(&s.RuleID, &s.TotalMatches, &s.LastMatchedAt, &s.RecentMatches); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// PruneDaily drops daily counts that have left the recent window
+func (r *ruleStatsRepo) PruneDaily(ctx context.Context, now time.Time) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM rule_daily_hits WHERE day < ?`, recentSince(now))
+	if err != nil {
+		logging.L().Errorw("Failed to prune daily rule matches", "error", err)
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		logging.L().Infow("Pruned daily rule matches", "rows", n)
+	}
+	return nil
+}
+
+// recentSince returns the first local day inside the recent window ending today
+func recentSince(now time.Time) string {
+	return now.Local().AddDate(0, 0, -(db.RecentMatchDays - 1)).Format(dayLayout)
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kalycs/db"
+)
+
+func TestRuleStatsRepo(t *testing.T) {
+	testDB := setupTestDB(t)
+	projects := NewProjectRepo(testDB)
+	rules := NewRuleRepo(testDB)
+	repo := NewRuleStatsRepo(testDB)
+	ctx := context.Background()
+
+	p := createTestProject("Invoices")
+	if err := projects.Create(ctx, p); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	hit := &db.Rule{Name: "hit", ProjectID: p.ID, Rule: "contains", Texts: `["invoice"]`}
+	idle := &db.Rule{Name: "idle", ProjectID: p.ID, Rule: "contains", Texts: `["receipt"]`}
+	for _, r := range []*db.Rule{hit, idle} {
+		if err := rules.Create(ctx, r); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -db.RecentMatchDays-5)
+	for _, at := range []time.Time{old, now.Add(-time.Hour), now} {
+		if err := repo.RecordMatch(ctx, hit.ID, at); err != nil {
+			t.Fatalf("RecordMatch() error = %v", err)
+		}
+	}
+
+	stats, err := repo.List(ctx, now)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("List() returned %d rules, want 2", len(stats))
+	}
+	got := stats[0]
+	if got.RuleID != hit.ID || got.TotalMatches != 3 || got.RecentMatches != 2 {
+		t.Errorf("stats = %+v, want 3 total and 2 recent matches", got)
+	}
+	if !got.LastMatchedAt.Valid || got.LastMatchedAt.Time.Sub(now).Abs() > time.Second {
+		t.Errorf("LastMatchedAt = %v, want %v", got.LastMatchedAt, now)
+	}
+	if s := stats[1]; s.RuleID != idle.ID || s.TotalMatches != 0 || s.LastMatchedAt.Valid {
+		t.Errorf("stats = %+v, want no matches", s)
+	}
+
+	if err := repo.PruneDaily(ctx, now); err != nil {
+		t.Fatalf("PruneDaily() error = %v", err)
+	}
+	var days int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM rule_daily_hits`).Scan(&days); err != nil {
+		t.Fatalf("count daily hits: %v", err)
+	}
+	if days != 1 {
+		t.Errorf("daily rows after prune = %d, want 1", days)
+	}
+	stats, _ = repo.List(ctx, now)
+	if stats[0].TotalMatches != 3 {
+		t.Errorf("TotalMatches after prune = %d, want 3", stats[0].TotalMatches)
+	}
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"kalycs/internal/logging"
+)
+
+// Setting keys
+const (
+	// SettingDownloadsDirectory overrides the detected downloads directory
+	SettingDownloadsDirectory = "downloads_directory"
+	// SettingAutoReclassify is "false" when indexed files should not be
+	// reclassified after rules change; reclassification is on by default
+	SettingAutoReclassify = "auto_reclassify"
+)
+
+// SettingRepo defines methods for key/value application settings
+type SettingRepo interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key, value string) error
+	Delete(ctx context.Context, key string) error
+}
+
+type settingRepo struct {
+	db *sql.DB
+}
+
+func NewSettingRepo(db *sql.DB) SettingRepo {
+	return &settingRepo{db: db}
+}
+
+// Get returns the value of key and whether it is set
+func (r *settingRepo) Get(ctx context.Context, key string) (string, bool, error) {
+	q := `SELECT value FROM settings WHERE key = ?`
+	var value string
+	err := r.db.QueryRowContext(ctx, q, key).Scan(&value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (r *settingRepo) Set(ctx context.Context, key, value string) error {
+	q := `
+	INSERT INTO settings (key, value) VALUES (?, ?)
+	ON CONFLICT(key) DO UPDATE SET
+		value = excluded.value,
+		updated_at = CURRENT_TIMESTAMP`
+	if _, err := r.db.ExecContext(ctx, q, key, value); err != nil {
+		logging.L().Errorw("Failed to save setting", "key", key, "error", err)
+		return err
+	}
+	logging.L().Infow("Setting saved", "key", key)
+	return nil
+}
+
+func (r *settingRepo) Delete(ctx context.Context, key string) error {
+	q := `DELETE FROM settings WHERE key = ?`
+	if _, err := r.db.ExecContext(ctx, q, key); err != nil {
+		logging.L().Errorw("Failed to delete setting", "key", key, "error", err)
+		return err
+	}
+	logging.L().Infow("Setting deleted", "key", key)
+	return nil
+}
@@ -6,16 +6,26 @@ import (
 
 // Store holds all repository instances
 type Store struct {
-	Project ProjectRepo
-	Rule    RuleRepo
-	File    FileRepo
+	Project  ProjectRepo
+	Rule     RuleRepo
+	Stats    RuleStatsRepo
+	File     FileRepo
+	Metadata MetadataRepo
+	Action   ActionRepo
+	Watch    WatchRootRepo
+	Setting  SettingRepo
 }
 
 // NewStore initializes the repository store with the given *sql.DB
 func NewStore(db *sql.DB) *Store {
 	return &Store{
-		Project: NewProjectRepo(db),
-		Rule:    NewRuleRepo(db),
-		File:    NewFileRepo(db),
+		Project:  NewProjectRepo(db),
+		Rule:     NewRuleRepo(db),
+		Stats:    NewRuleStatsRepo(db),
+		File:     NewFileRepo(db),
+		Metadata: NewMetadataRepo(db),
+		Action:   NewActionRepo(db),
+		Watch:    NewWatchRootRepo(db),
+		Setting:  NewSettingRepo(db),
 	}
 }
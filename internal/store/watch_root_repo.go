@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"kalycs/db"
+	"kalycs/internal/database"
+	"kalycs/internal/logging"
+	"kalycs/internal/validation"
+)
+
+// WatchRootRepo defines methods for watched directory data access
+type WatchRootRepo interface {
+	GetByID(ctx context.Context, id string) (*db.WatchRoot, error)
+	GetAll(ctx context.Context) ([]db.WatchRoot, error)
+	ListEnabled(ctx context.Context) ([]db.WatchRoot, error)
+	Create(ctx context.Context, root *db.WatchRoot) error
+	Update(ctx context.Context, root *db.WatchRoot) error
+	Delete(ctx context.Context, id string) error
+}
+
+type watchRootRepo struct {
+	db *sql.DB
+}
+
+func NewWatchRootRepo(db *sql.DB) WatchRootRepo {
+	return &watchRootRepo{db: db}
+}
+
+func (r *watchRootRepo) GetByID(ctx context.Context, id string) (*db.WatchRoot, error) {
+	q := `SELECT id, path, enabled, recursive, max_depth, quiet_period_ms, ignore_patterns, created_at, updated_at FROM watch_roots WHERE id = ?`
+	root := &db.WatchRoot{}
+	err := r.db.QueryRowContext(ctx, q, id).Scan(&root.ID, &root.Path, &root.Enabled, &root.Recursive, &root.MaxDepth, &root.QuietPeriodMs, &root.IgnorePatterns, &root.CreatedAt, &root.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return root, nil
+}
+
+func (r *watchRootRepo) GetAll(ctx context.Context) ([]db.WatchRoot, error) {
+	q := `SELECT id, path, enabled, recursive, max_depth, quiet_period_ms, ignore_patterns, created_at, updated_at FROM watch_roots ORDER BY created_at`
+	return r.list(ctx, q)
+}
+
+func (r *watchRootRepo) ListEnabled(ctx context.Context) ([]db.WatchRoot, error) {
+	q := `SELECT id, path, enabled, recursive, max_depth, quiet_period_ms, ignore_patterns, created_at, updated_at FROM watch_roots WHERE enabled = 1 ORDER BY created_at`
+	return r.list(ctx, q)
+}
+
+func (r *watchRootRepo) list(ctx context.Context, q string) ([]db.WatchRoot, error) {
+	rows, err := r.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roots []db.WatchRoot
+	for rows.Next() {
+		var root db.WatchRoot
+		if err := rows.Scan(&root.ID, &root.Path, &root.Enabled, &root.Recursive, &root.MaxDepth, &root.QuietPeriodMs, &root.IgnorePatterns, &root.CreatedAt, &root.UpdatedAt); err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+	return roots, rows.Err()
+}
+
+func (r *watchRootRepo) Create(ctx context.Context, root *db.WatchRoot) error {
+	if root == nil {
+		return fmt.Errorf("watch root cannot be nil")
+	}
+	database.NormalizeWatchRootData(root)
+	if err := validation.ValidateWatchRoot(root); err != nil {
+		logging.L().Warnw("Watch root validation failed", "path", root.Path, "error", err)
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	root.ID = database.GenerateID()
+	q := `INSERT INTO watch_roots (id, path, enabled, recursive, max_depth, quiet_period_ms, ignore_patterns) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.db.ExecContext(ctx, q, root.ID, root.Path, root.Enabled, root.Recursive, root.MaxDepth, root.QuietPeriodMs, root.IgnorePatterns)
+	if err != nil {
+		if database.IsUniqueConstraintError(err) {
+			logging.L().Warnw("Watch root creation failed - path already watched", "path", root.Path, "error", err)
+			return fmt.Errorf("directory '%s' is already watched", root.Path)
+		}
+		logging.L().Errorw("Failed to create watch root", "path", root.Path, "error", err)
+		return err
+	}
+	logging.L().Infow("Watch root created successfully", "watch_root_id", root.ID, "path", root.Path)
+	return nil
+}
+
+func (r *watchRootRepo) Update(ctx context.Context, root *db.WatchRoot) error {
+	if root == nil {
+		return fmt.Errorf("watch root cannot be nil")
+	}
+	if root.ID == "" {
+		return fmt.Errorf("watch root ID cannot be empty for update")
+	}
+	database.NormalizeWatchRootData(root)
+	if err := validation.ValidateWatchRoot(root); err != nil {
+		logging.L().Warnw("Watch root validation failed during update", "watch_root_id", root.ID, "error", err)
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	q := `UPDATE watch_roots SET path = ?, enabled = ?, recursive = ?, max_depth = ?, quiet_period_ms = ?, ignore_patterns = ? WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, q, root.Path, root.Enabled, root.Recursive, root.MaxDepth, root.QuietPeriodMs, root.IgnorePatterns, root.ID)
+	if err != nil {
+		if database.IsUniqueConstraintError(err) {
+			logging.L().Warnw("Watch root update failed - path already watched", "path", root.Path, "error", err)
+			return fmt.Errorf("directory '%s' is already watched", root.Path)
+		}
+		logging.L().Errorw("Failed to update watch root", "watch_root_id", root.ID, "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logging.L().Errorw("Failed to get rows affected for watch root update", "watch_root_id", root.ID, "error", err)
+		return err
+	}
+	if rowsAffected == 0 {
+		logging.L().Warnw("Watch root update failed - watch root not found", "watch_root_id", root.ID)
+		return fmt.Errorf("watch root with ID '%s' not found", root.ID)
+	}
+
+	logging.L().Infow("Watch root updated successfully", "watch_root_id", root.ID, "path", root.Path)
+	return nil
+}
+
+func (r *watchRootRepo) Delete(ctx context.Context, id string) error {
+	q := `DELETE FROM watch_roots WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, q, id)
+	if err != nil {
+		logging.L().Errorw("Failed to delete watch root", "watch_root_id", id, "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logging.L().Errorw("Failed to get rows affected for watch root deletion", "watch_root_id", id, "error", err)
+		return err
+	}
+	if rowsAffected == 0 {
+		logging.L().Warnw("Watch root deletion failed - watch root not found", "watch_root_id", id)
+		return fmt.Errorf("watch root with ID '%s' not found", id)
+	}
+
+	logging.L().Infow("Watch root deleted successfully", "watch_root_id", id)
+	return nil
+}
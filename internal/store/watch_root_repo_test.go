@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kalycs/db"
+)
+
+func TestWatchRootRepo_CRUD(t *testing.T) {
+	testDB := setupTestDB(t)
+	repo := NewWatchRootRepo(testDB)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	root := &db.WatchRoot{Path: dir + string(filepath.Separator), Enabled: true}
+	if err := repo.Create(ctx, root); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if root.Path != dir {
+		t.Errorf("Create() path = %q, want cleaned %q", root.Path, dir)
+	}
+	if root.IgnorePatterns != "[]" {
+		t.Errorf("Create() ignore patterns = %q, want []", root.IgnorePatterns)
+	}
+
+	root.Recursive = true
+	root.QuietPeriodMs = 2000
+	root.IgnorePatterns = `["*.tmp"]`
+	if err := repo.Update(ctx, root); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, root.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetByID() = %v, %v", got, err)
+	}
+	if !got.Recursive || got.QuietPeriodMs != 2000 || got.IgnorePatterns != `["*.tmp"]` {
+		t.Errorf("GetByID() = %+v, want updated settings", got)
+	}
+
+	if err := repo.Delete(ctx, root.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Delete(ctx, root.ID); err == nil {
+		t.Error("Delete() of missing root should fail")
+	}
+}
+
+func TestWatchRootRepo_Validation(t *testing.T) {
+	testDB := setupTestDB(t)
+	repo := NewWatchRootRepo(testDB)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	tests := []struct {
+		name   string
+		root   *db.WatchRoot
+		errMsg string
+	}{
+		{"relative path", &db.WatchRoot{Path: "Downloads"}, "absolute path"},
+		{"empty path", &db.WatchRoot{Path: "  "}, "path is required"},
+		{"bad patterns JSON", &db.WatchRoot{Path: dir, IgnorePatterns: "*.tmp"}, "JSON array"},
+		{"bad glob", &db.WatchRoot{Path: dir, IgnorePatterns: `["[a-"]`}, "not a valid glob"},
+		{"negative quiet period", &db.WatchRoot{Path: dir, QuietPeriodMs: -1}, "quiet period"},
+		{"quiet period too long", &db.WatchRoot{Path: dir, QuietPeriodMs: 120000}, "quiet period"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.Create(ctx, tt.root)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Create() error = %v, want to contain %q", err, tt.errMsg)
+			}
+		})
+	}
+
+	if err := repo.Create(ctx, &db.WatchRoot{Path: dir}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Create(ctx, &db.WatchRoot{Path: dir}); err == nil || !strings.Contains(err.Error(), "already watched") {
+		t.Errorf("duplicate Create() error = %v, want already watched", err)
+	}
+}
@@ -1,16 +1,35 @@
 package utils
 
 import (
+	"bufio"
 	"errors"
+	"fmt"
+	"io"
 	"kalycs/internal/logging"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 // GetDownloadsDirectory returns the default downloads directory for the current user.
-// It supports Windows and macOS. For other operating systems, it returns an error.
+// On Windows and macOS this is ~/Downloads. On Linux and other Unix systems the
+// XDG_DOWNLOAD_DIR from user-dirs.dirs is used, falling back to ~/Downloads.
 func GetDownloadsDirectory() (string, error) {
+	return ResolveDownloadsDirectory("")
+}
+
+// ResolveDownloadsDirectory returns override when it is set, otherwise the
+// default downloads directory for the current user.
+func ResolveDownloadsDirectory(override string) (string, error) {
+	if override = strings.TrimSpace(override); override != "" {
+		if !filepath.IsAbs(override) {
+			return "", errors.New("downloads directory override must be an absolute path: " + override)
+		}
+		logging.L().Infow("Using downloads directory override", "path", override)
+		return filepath.Clean(override), nil
+	}
+
 	logging.L().Info("Attempting to get downloads directory...")
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -26,7 +45,114 @@ func GetDownloadsDirectory() (string, error) {
 		return downloadsPath, nil
 	}
 
-	err = errors.New("unsupported operating system: " + runtime.GOOS)
-	logging.L().Warnw("Unsupported operating system", "os", runtime.GOOS)
-	return "", err
+	if xdgPath, ok := xdgDownloadDir(homeDir); ok {
+		logging.L().Infow("Downloads directory found", "os", runtime.GOOS, "source", "xdg", "path", xdgPath)
+		return xdgPath, nil
+	}
+
+	logging.L().Infow("Downloads directory found", "os", runtime.GOOS, "source", "default", "path", downloadsPath)
+	return downloadsPath, nil
+}
+
+// xdgDownloadDir looks up XDG_DOWNLOAD_DIR in the environment and then in
+// $XDG_CONFIG_HOME/user-dirs.dirs (defaulting to ~/.config/user-dirs.dirs).
+func xdgDownloadDir(homeDir string) (string, bool) {
+	if dir := os.Getenv("XDG_DOWNLOAD_DIR"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Clean(dir), true
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" || !filepath.IsAbs(configHome) {
+		configHome = filepath.Join(homeDir, ".config")
+	}
+
+	f, err := os.Open(filepath.Join(configHome, "user-dirs.dirs"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.L().Warnw("could not read user-dirs.dirs", "error", err)
+		}
+		return "", false
+	}
+	defer f.Close()
+
+	dirs, err := ParseUserDirs(f, homeDir)
+	if err != nil {
+		logging.L().Warnw("could not parse user-dirs.dirs", "error", err)
+		return "", false
+	}
+
+	dir, ok := dirs["XDG_DOWNLOAD_DIR"]
+	return dir, ok
+}
+
+// ParseUserDirs parses an XDG user-dirs.dirs file into a map of variable name
+// to absolute path. Following xdg-user-dirs, values are double-quoted and either
+// absolute or start with $HOME; backslash escapes the next character. Unquoted
+// values are accepted as well. Comments, blank lines and entries that do not
+// resolve to an absolute path are skipped.
+func ParseUserDirs(r io.Reader, homeDir string) (map[string]string, error) {
+	dirs := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if !strings.HasPrefix(key, "XDG_") || !strings.HasSuffix(key, "_DIR") {
+			continue
+		}
+
+		value, err := unquoteUserDir(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		switch {
+		case value == "$HOME":
+			value = homeDir
+		case strings.HasPrefix(value, "$HOME/"):
+			value = filepath.Join(homeDir, filepath.FromSlash(strings.TrimPrefix(value, "$HOME/")))
+		case strings.HasPrefix(value, "${HOME}/"):
+			value = filepath.Join(homeDir, filepath.FromSlash(strings.TrimPrefix(value, "${HOME}/")))
+		}
+		if !filepath.IsAbs(value) {
+			continue
+		}
+		dirs[key] = filepath.Clean(value)
+	}
+	return dirs, scanner.Err()
+}
+
+// unquoteUserDir removes the surrounding double quotes and backslash escapes of a value
+func unquoteUserDir(raw string) (string, error) {
+	if !strings.HasPrefix(raw, `"`) {
+		// Unquoted: stop at a trailing comment
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = raw[:i]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+
+	var b strings.Builder
+	escaped := false
+	for _, r := range raw[1:] {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			return b.String(), nil
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", errors.New("unterminated quoted value")
 }
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// ubuntuUserDirs is the file written by xdg-user-dirs-update on a stock Ubuntu install
+const ubuntuUserDirs = `# This file is written by xdg-user-dirs-update
+# If you want to change or add directories, just edit the line you're
+# interested in. All local changes will be retained on the next run.
+# Format is XDG_xxx_DIR="$HOME/yyy", where yyy is a shell-escaped
+# homedir-relative path, or XDG_xxx_DIR="/yyy", where /yyy is an
+# absolute path. No other format is supported.
+#
+XDG_DESKTOP_DIR="$HOME/Desktop"
+XDG_DOWNLOAD_DIR="$HOME/Downloads"
+XDG_TEMPLATES_DIR="$HOME/Templates"
+XDG_PUBLICSHARE_DIR="$HOME/Public"
+XDG_DOCUMENTS_DIR="$HOME/Documents"
+XDG_MUSIC_DIR="$HOME/Music"
+XDG_PICTURES_DIR="$HOME/Pictures"
+XDG_VIDEOS_DIR="$HOME/Videos"
+`
+
+func TestParseUserDirs(t *testing.T) {
+	home := filepath.FromSlash("/home/alex")
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		found   bool
+		wantErr bool
+	}{
+		{
+			name:    "stock ubuntu file",
+			content: ubuntuUserDirs,
+			want:    filepath.Join(home, "Downloads"),
+			found:   true,
+		},
+		{
+			name:    "localized directory name",
+			content: "XDG_DOWNLOAD_DIR=\"$HOME/Téléchargements\"\n",
+			want:    filepath.Join(home, "Téléchargements"),
+			found:   true,
+		},
+		{
+			name:    "absolute path",
+			content: "XDG_DOWNLOAD_DIR=\"/mnt/data/downloads\"\n",
+			want:    filepath.FromSlash("/mnt/data/downloads"),
+			found:   true,
+		},
+		{
+			name:    "escaped characters",
+			content: `XDG_DOWNLOAD_DIR="$HOME/My \"Files\" \\ Stuff"` + "\n",
+			want:    filepath.Join(home, `My "Files" \ Stuff`),
+			found:   true,
+		},
+		{
+			name:    "set to home directory",
+			content: "XDG_DOWNLOAD_DIR=\"$HOME\"\n",
+			want:    home,
+			found:   true,
+		},
+		{
+			name:    "unquoted with surrounding spaces",
+			content: "  XDG_DOWNLOAD_DIR = $HOME/dl  # personal\n",
+			want:    filepath.Join(home, "dl"),
+			found:   true,
+		},
+		{
+			name:    "relative path is ignored",
+			content: "XDG_DOWNLOAD_DIR=\"Downloads\"\n",
+			found:   false,
+		},
+		{
+			name:    "missing entry",
+			content: "XDG_DESKTOP_DIR=\"$HOME/Desktop\"\n",
+			found:   false,
+		},
+		{
+			name:    "unterminated quote",
+			content: "XDG_DOWNLOAD_DIR=\"$HOME/Downloads\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dirs, err := ParseUserDirs(strings.NewReader(tt.content), home)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUserDirs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, found := dirs["XDG_DOWNLOAD_DIR"]
+			if found != tt.found {
+				t.Fatalf("XDG_DOWNLOAD_DIR found = %v, want %v (dirs: %v)", found, tt.found, dirs)
+			}
+			if found && got != tt.want {
+				t.Errorf("XDG_DOWNLOAD_DIR = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUserDirs_AllEntries(t *testing.T) {
+	dirs, err := ParseUserDirs(strings.NewReader(ubuntuUserDirs), "/home/alex")
+	if err != nil {
+		t.Fatalf("ParseUserDirs() error = %v", err)
+	}
+	if len(dirs) != 8 {
+		t.Errorf("ParseUserDirs() returned %d entries, want 8", len(dirs))
+	}
+}
+
+func TestResolveDownloadsDirectory_Override(t *testing.T) {
+	override := t.TempDir()
+	got, err := ResolveDownloadsDirectory(override)
+	if err != nil {
+		t.Fatalf("ResolveDownloadsDirectory() error = %v", err)
+	}
+	if got != override {
+		t.Errorf("ResolveDownloadsDirectory() = %q, want %q", got, override)
+	}
+
+	if _, err := ResolveDownloadsDirectory("relative/dir"); err == nil {
+		t.Error("ResolveDownloadsDirectory() should reject a relative override")
+	}
+}
+
+func TestResolveDownloadsDirectory_XDG(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("XDG user directories are only used on Linux and other Unix systems")
+	}
+
+	home := t.TempDir()
+	configHome := filepath.Join(home, "config")
+	if err := os.MkdirAll(configHome, 0755); err != nil {
+		t.Fatalf("failed to create config dir: %v", err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_DOWNLOAD_DIR", "")
+
+	// Without user-dirs.dirs the default ~/Downloads is used
+	got, err := GetDownloadsDirectory()
+	if err != nil {
+		t.Fatalf("GetDownloadsDirectory() error = %v", err)
+	}
+	if want := filepath.Join(home, "Downloads"); got != want {
+		t.Errorf("GetDownloadsDirectory() = %q, want %q", got, want)
+	}
+
+	content := "XDG_DOWNLOAD_DIR=\"$HOME/Incoming Files\"\n"
+	if err := os.WriteFile(filepath.Join(configHome, "user-dirs.dirs"), []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write user-dirs.dirs: %v", err)
+	}
+	got, err = GetDownloadsDirectory()
+	if err != nil {
+		t.Fatalf("GetDownloadsDirectory() error = %v", err)
+	}
+	if want := filepath.Join(home, "Incoming Files"); got != want {
+		t.Errorf("GetDownloadsDirectory() = %q, want %q", got, want)
+	}
+
+	// The environment variable takes precedence over the file
+	t.Setenv("XDG_DOWNLOAD_DIR", filepath.Join(home, "env"))
+	got, _ = GetDownloadsDirectory()
+	if want := filepath.Join(home, "env"); got != want {
+		t.Errorf("GetDownloadsDirectory() = %q, want %q", got, want)
+	}
+}
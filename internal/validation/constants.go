@@ -13,6 +13,19 @@ const (
 	MinRuleNameLength = 1
 	MaxRuleTextLength = 64
 	MaxRuleTextsItems = 20
+	// Rename templates
+	MaxRenameTemplateLength = 128
+	// Composite rules
+	MaxConditionDepth  = 8
+	MaxConditionLeaves = 20
+)
+
+// Watch root validation constants
+const (
+	MaxIgnorePatterns      = 50
+	MaxIgnorePatternLength = 128
+	MaxWatchDepth          = 32
+	MaxQuietPeriodMs       = 60000
 )
 
 // Common validation constants
@@ -21,11 +34,32 @@ const (
 	UUIDHyphenCount = 4
 )
 
-// Valid rule types
+// Valid project actions performed on classified files
+var ValidProjectActions = []string{
+	"none",
+	"move",
+	"copy",
+	"hardlink",
+	"symlink",
+}
+
+// Valid rule types; keep in sync with the CHECK constraint on rules.rule
 var ValidRuleTypes = []string{
 	"starts_with",
 	"contains",
 	"ends_with",
 	"extension",
 	"regex",
+	"glob",          // shell-style name patterns, e.g. "IMG_*.HEIC"
+	"path",          // glob over the path below the watched root, e.g. "Slack/**"
+	"parent",        // name of the directory holding the file, e.g. "receipts"
+	"mime",          // content type sniffed from the file, e.g. "image/*"
+	"content",       // keywords in the text of text files and PDFs
+	"content_regex", // regular expressions over the text of text files and PDFs
+	"metadata",      // embedded fields such as EXIF or PDF info, e.g. "camera_model = iPhone 15"
+	"source_domain", // site a download came from, e.g. "github.com"
+	"size",          // file size ranges, e.g. ">100MB"
+	"age",           // time since last modification, e.g. ">30d"
+	"modified",      // modification date, e.g. ">=2024-01-01"
+	"composite",     // AND/OR/NOT tree of the kinds above, stored in conditions
 }
@@ -4,7 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"kalycs/db"
+	"kalycs/internal/condition"
+	"kalycs/internal/glob"
+	"kalycs/internal/metadata"
+	"kalycs/internal/provenance"
+	"kalycs/internal/rename"
+	"kalycs/internal/ruleexpr"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -14,59 +21,242 @@ func NewRuleValidator() *RuleValidator {
 	return &RuleValidator{}
 }
 
+// Validate checks and normalises a rule. Plain rules keep their texts as the
+// source of truth and get the equivalent single-leaf condition tree; composite
+// rules are defined by their condition tree and have no texts of their own.
 func (v *RuleValidator) Validate(r *db.Rule) error {
 	// 1. Trim whitespace
 	r.Name = strings.TrimSpace(r.Name)
+	r.RenameTemplate = strings.TrimSpace(r.RenameTemplate)
+
+	if r.Rule == condition.KindComposite {
+		if err := v.validateName(r.Name); err != nil {
+			return err
+		}
+		if err := validateRenameTemplate(r.Rule, r.RenameTemplate, nil); err != nil {
+			return err
+		}
+		return v.validateConditions(r)
+	}
 
 	var texts []string
 	if err := json.Unmarshal([]byte(r.Texts), &texts); err != nil {
 		return fmt.Errorf("invalid texts format: must be a JSON array of strings")
 	}
 
-	trimmedTexts := make([]string, 0, len(texts))
+	// 2. Enforce max lengths
+	if err := v.validateName(r.Name); err != nil {
+		return err
+	}
+	if err := validateRuleType(r.Rule); err != nil {
+		return err
+	}
+	trimmedTexts, err := normalizeRuleTexts(r.Rule, texts)
+	if err != nil {
+		return err
+	}
+	if err := validateRenameTemplate(r.Rule, r.RenameTemplate, trimmedTexts); err != nil {
+		return err
+	}
+
+	// Update r.Texts with trimmed and validated texts
+	textsJSON, err := json.Marshal(trimmedTexts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal texts: %w", err)
+	}
+	r.Texts = string(textsJSON)
+
+	conditions, err := condition.Leaf(r.Rule, trimmedTexts, r.CaseSensitive).Encode()
+	if err != nil {
+		return fmt.Errorf("failed to marshal conditions: %w", err)
+	}
+	r.Conditions = conditions
+	return nil
+}
+
+func (v *RuleValidator) validateName(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("rule name cannot be empty")
+	}
+	if len(name) > MaxRuleNameLength {
+		return fmt.Errorf("rule name exceeds max length of %d", MaxRuleNameLength)
+	}
+	return nil
+}
+
+// validateRenameTemplate checks that a rule's rename template parses and that
+// the capture groups it refers to exist in every pattern of a regex rule
+func validateRenameTemplate(kind, template string, texts []string) error {
+	if template == "" {
+		return nil
+	}
+	if len(template) > MaxRenameTemplateLength {
+		return fmt.Errorf("rename template exceeds max length of %d", MaxRenameTemplateLength)
+	}
+	t, err := rename.Parse(template)
+	if err != nil {
+		return fmt.Errorf("invalid rename template: %w", err)
+	}
+	groups := t.Groups()
+	if len(groups) == 0 {
+		return nil
+	}
+	if kind != "regex" {
+		return fmt.Errorf("rename template refers to capture group ${%s}, which only regex rules have", groups[0])
+	}
 	for _, text := range texts {
+		re := regexp.MustCompile(text) // compiled by normalizeRuleTexts
+		for _, group := range groups {
+			if !hasCaptureGroup(re, group) {
+				return fmt.Errorf("rename template refers to capture group ${%s}, which pattern '%s' does not have", group, text)
+			}
+		}
+	}
+	return nil
+}
+
+// hasCaptureGroup reports whether re has the numbered or named group
+func hasCaptureGroup(re *regexp.Regexp, group string) bool {
+	if n, err := strconv.Atoi(group); err == nil {
+		return n <= re.NumSubexp()
+	}
+	return re.SubexpIndex(group) >= 0
+}
+
+// validateConditions checks a composite rule's tree and normalises every leaf
+// the same way a plain rule's texts are normalised
+func (v *RuleValidator) validateConditions(r *db.Rule) error {
+	tree, err := condition.Parse(r.Conditions)
+	if err != nil {
+		return err
+	}
+	if tree.Depth() > MaxConditionDepth {
+		return fmt.Errorf("conditions exceed max depth of %d", MaxConditionDepth)
+	}
+	leaves := tree.Leaves()
+	if len(leaves) > MaxConditionLeaves {
+		return fmt.Errorf("conditions exceed max of %d matches", MaxConditionLeaves)
+	}
+
+	for _, leaf := range leaves {
+		if err := validateRuleType(leaf.Kind); err != nil {
+			return err
+		}
+		texts, err := normalizeRuleTexts(leaf.Kind, leaf.Texts)
+		if err != nil {
+			return fmt.Errorf("%s condition: %w", leaf.Kind, err)
+		}
+		leaf.Texts = texts
+	}
+
+	conditions, err := tree.Encode()
+	if err != nil {
+		return fmt.Errorf("failed to marshal conditions: %w", err)
+	}
+	r.Conditions = conditions
+	r.Texts = "[]"
+	return nil
+}
+
+// normalizeRuleTexts trims texts, drops empty ones and checks them against the rule kind
+func normalizeRuleTexts(kind string, texts []string) ([]string, error) {
+	trimmedTexts := make([]string, 0, len(texts))
+	positions := make([]int, 0, len(texts)) // index of each trimmed text in texts
+	for i, text := range texts {
 		trimmed := strings.TrimSpace(text)
 		if trimmed != "" {
 			trimmedTexts = append(trimmedTexts, trimmed)
+			positions = append(positions, i)
 		}
 	}
 
-	// 2. Enforce max lengths
-	if len(r.Name) == 0 {
-		return fmt.Errorf("rule name cannot be empty")
-	}
-	if len(r.Name) > MaxRuleNameLength {
-		return fmt.Errorf("rule name exceeds max length of %d", MaxRuleNameLength)
-	}
 	if len(trimmedTexts) == 0 {
-		return fmt.Errorf("rule must have at least one text")
+		return nil, fmt.Errorf("rule must have at least one text")
 	}
 	if len(trimmedTexts) > MaxRuleTextsItems {
-		return fmt.Errorf("rule texts exceed max items of %d", MaxRuleTextsItems)
+		return nil, fmt.Errorf("rule texts exceed max items of %d", MaxRuleTextsItems)
 	}
 
 	for _, text := range trimmedTexts {
 		if len(text) > MaxRuleTextLength {
-			return fmt.Errorf("rule text '%s' exceeds max length of %d", text, MaxRuleTextLength)
+			return nil, fmt.Errorf("rule text '%s' exceeds max length of %d", text, MaxRuleTextLength)
 		}
 	}
 
-	// Update r.Texts with trimmed and validated texts
-	textsJSON, err := json.Marshal(trimmedTexts)
-	if err != nil {
-		return fmt.Errorf("failed to marshal texts: %w", err)
+	// 3. For regex and glob rules, compile the patterns; size, age and modified rules
+	// must hold comparison expressions
+	if ruleexpr.IsKind(kind) {
+		for _, text := range trimmedTexts {
+			if _, err := ruleexpr.Parse(kind, text); err != nil {
+				return nil, fmt.Errorf("invalid %s expression: %w", kind, err)
+			}
+		}
 	}
-	r.Texts = string(textsJSON)
-
-	// 3. For regex rules, compile the pattern
-	if r.Rule == "regex" {
-		if len(trimmedTexts) != 1 {
-			return fmt.Errorf("regex rule must have exactly one pattern")
+	if kind == "path" {
+		// Patterns are relative to the watched root and name no trailing directory separator
+		for i, text := range trimmedTexts {
+			if trimmed := strings.Trim(text, "/"); trimmed != "" {
+				trimmedTexts[i] = trimmed
+			}
 		}
-		if _, err := regexp.Compile(trimmedTexts[0]); err != nil {
-			return fmt.Errorf("invalid regex pattern: %w", err)
+	}
+	if kind == "mime" {
+		for i, text := range trimmedTexts {
+			major, minor, ok := strings.Cut(strings.ToLower(text), "/")
+			if !ok || major == "" || minor == "" || strings.ContainsAny(minor, "/ ") || (major == "*" && minor != "*") {
+				return nil, fmt.Errorf("invalid content type %q: expected type/subtype, e.g. image/png or image/*", text)
+			}
+			trimmedTexts[i] = major + "/" + minor
+		}
+	}
+	if kind == "source_domain" {
+		// A pasted URL stands for its host, and "*.example.com" for example.com with its subdomains
+		for i, text := range trimmedTexts {
+			domain := strings.ToLower(text)
+			if strings.Contains(domain, "://") {
+				domain = provenance.Host(domain)
+			}
+			domain = strings.TrimSuffix(strings.TrimPrefix(domain, "*."), ".")
+			if domain == "" || strings.ContainsAny(domain, "/:*? ") {
+				return nil, fmt.Errorf("invalid domain %q: expected a host name, e.g. github.com", text)
+			}
+			trimmedTexts[i] = domain
+		}
+	}
+	if kind == "metadata" {
+		// Store conditions in one spelling, e.g. "Camera_Model=X" as "camera_model = X"
+		var errors ValidationErrors
+		for i, text := range trimmedTexts {
+			cond, err := metadata.ParseCondition(text)
+			if err != nil {
+				errors.Add(fmt.Sprintf("texts[%d]", positions[i]), err.Error(), text)
+				continue
+			}
+			trimmedTexts[i] = cond.String()
+		}
+		if err := errors.ToError(); err != nil {
+			return nil, err
+		}
+	}
+	if kind == "glob" || kind == "path" {
+		for _, text := range trimmedTexts {
+			if _, err := glob.Compile(text, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if kind == "regex" || kind == "content_regex" {
+		// Report every bad pattern at once, pointing at its position
+		var errors ValidationErrors
+		for i, text := range trimmedTexts {
+			if _, err := regexp.Compile(text); err != nil {
+				errors.Add(fmt.Sprintf("texts[%d]", positions[i]), fmt.Sprintf("invalid regex pattern: %v", err), text)
+			}
+		}
+		if err := errors.ToError(); err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
+	return trimmedTexts, nil
 }
@@ -1,7 +1,10 @@
 package validation
 
 import (
+	"encoding/json"
+	"fmt"
 	"kalycs/internal/logging"
+	"path/filepath"
 	"strings"
 	"unicode/utf8"
 
@@ -38,6 +41,15 @@ func ValidateProject(project *db.Project) error {
 		}
 	}
 
+	// Validate file action and destination
+	if err := validateProjectAction(project.Action, project.DestinationDir); err != nil {
+		if ve, ok := err.(ValidationError); ok {
+			errors = append(errors, ve)
+		} else {
+			errors.Add("action", err.Error(), project.Action)
+		}
+	}
+
 	// Validate ID format if provided
 	if project.ID != "" {
 		if err := validateUUID(project.ID); err != nil {
@@ -114,6 +126,63 @@ func ValidateRule(rule *db.Rule) error {
 	return errors.ToError()
 }
 
+// ValidateWatchRoot validates a watch root struct and returns any validation errors
+func ValidateWatchRoot(root *db.WatchRoot) error {
+	if root == nil {
+		logging.L().Warnw("Watch root validation failed - watch root is nil")
+		return ValidationError{
+			Field:   "watch_root",
+			Message: "watch root cannot be nil",
+		}
+	}
+
+	var errors ValidationErrors
+
+	// Validate path
+	path := strings.TrimSpace(root.Path)
+	if path == "" {
+		errors.Add("path", "watch root path is required")
+	} else if !filepath.IsAbs(path) {
+		errors.Add("path", "watch root path must be an absolute path", root.Path)
+	}
+
+	// Validate depth limit
+	if root.MaxDepth < 0 || root.MaxDepth > MaxWatchDepth {
+		errors.Add("max_depth", fmt.Sprintf("max depth must be between 0 and %d", MaxWatchDepth), fmt.Sprint(root.MaxDepth))
+	}
+
+	// Validate quiet period
+	if root.QuietPeriodMs < 0 || root.QuietPeriodMs > MaxQuietPeriodMs {
+		errors.Add("quiet_period_ms", fmt.Sprintf("quiet period must be between 0 and %d milliseconds", MaxQuietPeriodMs), fmt.Sprint(root.QuietPeriodMs))
+	}
+
+	// Validate ignore patterns
+	if err := validateIgnorePatterns(root.IgnorePatterns); err != nil {
+		if ve, ok := err.(ValidationError); ok {
+			errors = append(errors, ve)
+		} else {
+			errors.Add("ignore_patterns", err.Error(), root.IgnorePatterns)
+		}
+	}
+
+	// Validate ID format if provided
+	if root.ID != "" {
+		if err := validateUUID(root.ID); err != nil {
+			if ve, ok := err.(ValidationError); ok {
+				errors = append(errors, ve)
+			} else {
+				errors.Add("id", err.Error(), root.ID)
+			}
+		}
+	}
+
+	if errors.HasErrors() {
+		logging.L().Debugw("Watch root validation failed", "path", root.Path, "errors", errors.Error())
+	}
+
+	return errors.ToError()
+}
+
 // ValidateID validates a single ID string
 func ValidateID(id string) error {
 	return validateUUID(id)
@@ -195,6 +264,87 @@ func validateProjectDescription(description string) error {
 	return nil
 }
 
+// validateProjectAction validates the file action and its destination directory
+func validateProjectAction(action, destinationDir string) error {
+	if action == "" {
+		action = "none" // Defaults to leaving files in place
+	}
+
+	valid := false
+	for _, a := range ValidProjectActions {
+		if action == a {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return ValidationError{
+			Field:   "action",
+			Message: "project action must be one of: " + strings.Join(ValidProjectActions, ", "),
+			Value:   action,
+		}
+	}
+
+	destinationDir = strings.TrimSpace(destinationDir)
+	if action != "none" && destinationDir == "" {
+		return ValidationError{
+			Field:   "destination_dir",
+			Message: "destination directory is required when an action is set",
+		}
+	}
+	if destinationDir != "" && !filepath.IsAbs(destinationDir) {
+		return ValidationError{
+			Field:   "destination_dir",
+			Message: "destination directory must be an absolute path",
+			Value:   destinationDir,
+		}
+	}
+
+	return nil
+}
+
+// validateIgnorePatterns validates a JSON array of glob patterns
+func validateIgnorePatterns(patternsJSON string) error {
+	if strings.TrimSpace(patternsJSON) == "" {
+		return nil // No patterns
+	}
+
+	var patterns []string
+	if err := json.Unmarshal([]byte(patternsJSON), &patterns); err != nil {
+		return ValidationError{
+			Field:   "ignore_patterns",
+			Message: "ignore patterns must be a JSON array of strings",
+			Value:   patternsJSON,
+		}
+	}
+
+	if len(patterns) > MaxIgnorePatterns {
+		return ValidationError{
+			Field:   "ignore_patterns",
+			Message: fmt.Sprintf("ignore patterns must not exceed %d items", MaxIgnorePatterns),
+		}
+	}
+
+	for _, p := range patterns {
+		if len(p) > MaxIgnorePatternLength {
+			return ValidationError{
+				Field:   "ignore_patterns",
+				Message: fmt.Sprintf("ignore pattern must not exceed %d characters", MaxIgnorePatternLength),
+				Value:   p,
+			}
+		}
+		if _, err := filepath.Match(p, ""); err != nil {
+			return ValidationError{
+				Field:   "ignore_patterns",
+				Message: "ignore pattern is not a valid glob",
+				Value:   p,
+			}
+		}
+	}
+
+	return nil
+}
+
 // validateRuleName validates rule name according to business rules
 func validateRuleName(name string) error {
 	trimmedName := strings.TrimSpace(name)
@@ -245,7 +395,7 @@ func validateRuleType(ruleType string) error {
 
 	return ValidationError{
 		Field:   "rule",
-		Message: "rule type must be one of: starts_with, contains, ends_with, extension, regex",
+		Message: "rule type must be one of: " + strings.Join(ValidRuleTypes, ", "),
 		Value:   ruleType,
 	}
 }
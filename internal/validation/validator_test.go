@@ -1,6 +1,9 @@
 package validation
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -76,6 +79,43 @@ func TestValidateProject(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "move action with destination",
+			project: &db.Project{
+				Name:           "Invoices",
+				Action:         "move",
+				DestinationDir: filepath.Join(os.TempDir(), "Invoices"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "action without destination",
+			project: &db.Project{
+				Name:   "Invoices",
+				Action: "copy",
+			},
+			wantErr: true,
+			errMsg:  "destination directory is required",
+		},
+		{
+			name: "relative destination",
+			project: &db.Project{
+				Name:           "Invoices",
+				Action:         "move",
+				DestinationDir: "Invoices",
+			},
+			wantErr: true,
+			errMsg:  "must be an absolute path",
+		},
+		{
+			name: "unknown action",
+			project: &db.Project{
+				Name:   "Invoices",
+				Action: "shred",
+			},
+			wantErr: true,
+			errMsg:  "project action must be one of",
+		},
 	}
 
 	for _, tt := range tests {
@@ -168,6 +208,199 @@ func TestValidateRule(t *testing.T) {
 	}
 }
 
+func TestRuleValidator_Expressions(t *testing.T) {
+	v := NewRuleValidator()
+	projectID := "550e8400-e29b-41d4-a716-446655440000"
+
+	tests := []struct {
+		name    string
+		kind    string
+		texts   string
+		wantErr bool
+	}{
+		{name: "size range", kind: "size", texts: `[">100MB", "1GB..2GB"]`},
+		{name: "age", kind: "age", texts: `[">30d"]`},
+		{name: "modified date", kind: "modified", texts: `[">=2024-01-01"]`},
+		{name: "size without number", kind: "size", texts: `[">big"]`, wantErr: true},
+		{name: "age without unit", kind: "age", texts: `[">30"]`, wantErr: true},
+		{name: "malformed date", kind: "modified", texts: `["<31/01/2024"]`, wantErr: true},
+		{name: "glob", kind: "glob", texts: `["IMG_*.HEIC", "invoice-????-??.{pdf,PDF}"]`},
+		{name: "unclosed glob class", kind: "glob", texts: `["report[0-9.txt"]`, wantErr: true},
+		{name: "unclosed glob brace", kind: "glob", texts: `["*.{jpg,png"]`, wantErr: true},
+		{name: "path", kind: "path", texts: `["Slack/", "**/receipts"]`},
+		{name: "parent", kind: "parent", texts: `["receipts"]`},
+		{name: "invalid path pattern", kind: "path", texts: `["Slack/[a-"]`, wantErr: true},
+		{name: "mime", kind: "mime", texts: `["application/pdf", "Image/*"]`},
+		{name: "mime without subtype", kind: "mime", texts: `["image"]`, wantErr: true},
+		{name: "mime with subtype wildcard only", kind: "mime", texts: `["*/png"]`, wantErr: true},
+		{name: "content", kind: "content", texts: `["invoice no", "rechnung"]`},
+		{name: "content regex", kind: "content_regex", texts: `["total: \\d+ EUR"]`},
+		{name: "invalid content regex", kind: "content_regex", texts: `["total: (\\d+"]`, wantErr: true},
+		{name: "metadata", kind: "metadata", texts: `["camera_model = iPhone 15 Pro", "gps exists", "date_taken>=2024-01-01"]`},
+		{name: "metadata without operator", kind: "metadata", texts: `["camera_model iPhone"]`, wantErr: true},
+		{name: "metadata without value", kind: "metadata", texts: `["author ="]`, wantErr: true},
+		{name: "source domain", kind: "source_domain", texts: `["GitHub.com", "https://files.slack.com/x", "*.example.org"]`},
+		{name: "source domain with path", kind: "source_domain", texts: `["github.com/owner"]`, wantErr: true},
+		{name: "unknown kind", kind: "bigger_than", texts: `["1"]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &db.Rule{Name: "Rule", ProjectID: projectID, Rule: tt.kind, Texts: tt.texts}
+			if err := v.Validate(r); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleValidator_RegexPatterns(t *testing.T) {
+	v := NewRuleValidator()
+	projectID := "550e8400-e29b-41d4-a716-446655440000"
+
+	r := &db.Rule{Name: "Scans", ProjectID: projectID, Rule: "regex", Texts: `["^scan_\\d+", "^IMG_\\d{4}$"]`}
+	if err := v.Validate(r); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	// Indexes refer to the submitted list, including texts dropped as blank
+	r = &db.Rule{Name: "Scans", ProjectID: projectID, Rule: "regex", Texts: `["(unclosed", " ", "^ok$", "[z-a]"]`}
+	err := v.Validate(r)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+	}
+	if len(errs) != 2 || errs[0].Field != "texts[0]" || errs[0].Value != "(unclosed" || errs[1].Field != "texts[3]" {
+		t.Errorf("Validate() errors = %+v, want texts[0] and texts[3]", errs)
+	}
+}
+
+func TestRuleValidator_MetadataConditions(t *testing.T) {
+	v := NewRuleValidator()
+	projectID := "550e8400-e29b-41d4-a716-446655440000"
+
+	r := &db.Rule{Name: "Photos", ProjectID: projectID, Rule: "metadata", Texts: `["Camera_Model=X100V", "author EXISTS"]`}
+	if err := v.Validate(r); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if want := `["camera_model = X100V","author exists"]`; r.Texts != want {
+		t.Errorf("Texts = %s, want %s", r.Texts, want)
+	}
+
+	r = &db.Rule{Name: "Photos", ProjectID: projectID, Rule: "metadata", Texts: `["gps = true", "= iPhone"]`}
+	var errs ValidationErrors
+	if err := v.Validate(r); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "texts[1]" {
+		t.Errorf("Validate() error = %v, want one error for texts[1]", err)
+	}
+}
+
+func TestRuleValidator_RenameTemplates(t *testing.T) {
+	v := NewRuleValidator()
+	projectID := "550e8400-e29b-41d4-a716-446655440000"
+
+	tests := []struct {
+		name     string
+		kind     string
+		texts    string
+		template string
+		errMsg   string
+	}{
+		{name: "none", kind: "extension", texts: `["pdf"]`},
+		{name: "placeholders", kind: "extension", texts: `["pdf"]`, template: " {date:2006-01}_{project}_{name}{ext} "},
+		{name: "regex groups", kind: "regex", texts: `["^scan_(\\d+)", "^(?P<n>\\d+)_(?P<year>\\d{4})"]`, template: "${1}{ext}"},
+		{name: "named group", kind: "regex", texts: `["^(?P<year>\\d{4})_"]`, template: "${year}_{name}{ext}"},
+		{name: "unknown placeholder", kind: "extension", texts: `["pdf"]`, template: "{title}{ext}", errMsg: "unknown placeholder"},
+		{name: "unclosed placeholder", kind: "extension", texts: `["pdf"]`, template: "{name", errMsg: "unclosed placeholder"},
+		{name: "group without regex", kind: "glob", texts: `["*.pdf"]`, template: "${1}{ext}", errMsg: "only regex rules have"},
+		{name: "missing group", kind: "regex", texts: `["^scan_(\\d+)", "^IMG_"]`, template: "${1}{ext}", errMsg: "pattern '^IMG_' does not have"},
+		{name: "missing named group", kind: "regex", texts: `["^(?P<year>\\d{4})_"]`, template: "${month}{ext}", errMsg: "does not have"},
+		{name: "too long", kind: "extension", texts: `["pdf"]`, template: strings.Repeat("a", MaxRenameTemplateLength+1), errMsg: "max length"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &db.Rule{Name: "Rule", ProjectID: projectID, Rule: tt.kind, Texts: tt.texts, RenameTemplate: tt.template}
+			err := v.Validate(r)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				if r.RenameTemplate != strings.TrimSpace(tt.template) {
+					t.Errorf("RenameTemplate = %q, want it trimmed", r.RenameTemplate)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want to contain %q", err, tt.errMsg)
+			}
+		})
+	}
+
+	composite := &db.Rule{Name: "Composite", ProjectID: projectID, Rule: "composite", RenameTemplate: "${1}",
+		Conditions: `{"op": "match", "kind": "regex", "texts": ["(a)"]}`}
+	if err := v.Validate(composite); err == nil || !strings.Contains(err.Error(), "only regex rules have") {
+		t.Errorf("Validate() error = %v, want capture groups rejected for composite rules", err)
+	}
+}
+
+func TestRuleValidator_Conditions(t *testing.T) {
+	v := NewRuleValidator()
+	projectID := "550e8400-e29b-41d4-a716-446655440000"
+
+	// Plain rules get the equivalent single match
+	plain := &db.Rule{Name: "PDFs", ProjectID: projectID, Rule: "extension", Texts: `[" pdf "]`}
+	if err := v.Validate(plain); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if want := `{"op":"match","kind":"extension","texts":["pdf"]}`; plain.Conditions != want {
+		t.Errorf("Conditions = %s, want %s", plain.Conditions, want)
+	}
+
+	tests := []struct {
+		name       string
+		conditions string
+		errMsg     string
+	}{
+		{name: "valid tree", conditions: `{"op": "AND", "children": [
+			{"op": "match", "kind": "extension", "texts": [" pdf"]},
+			{"op": "not", "children": [{"op": "match", "kind": "size", "texts": ["<1KB"]}]}]}`},
+		{name: "empty", conditions: ``, errMsg: "conditions are empty"},
+		{name: "unknown operator", conditions: `{"op": "xor", "children": []}`, errMsg: "unknown condition operator"},
+		{name: "or without children", conditions: `{"op": "or"}`, errMsg: "at least one child"},
+		{name: "not with two children", conditions: `{"op": "not", "children": [
+			{"op": "match", "kind": "contains", "texts": ["a"]},
+			{"op": "match", "kind": "contains", "texts": ["b"]}]}`, errMsg: "exactly one child"},
+		{name: "invalid leaf kind", conditions: `{"op": "match", "kind": "bogus", "texts": ["a"]}`, errMsg: "rule type must be one of"},
+		{name: "nested composite", conditions: `{"op": "match", "kind": "composite", "texts": ["a"]}`, errMsg: "cannot use the composite kind"},
+		{name: "leaf without texts", conditions: `{"op": "match", "kind": "contains", "texts": [" "]}`, errMsg: "at least one text"},
+		{name: "invalid leaf expression", conditions: `{"op": "match", "kind": "age", "texts": ["old"]}`, errMsg: "invalid age expression"},
+		{name: "too deep", conditions: strings.Repeat(`{"op": "not", "children": [`, MaxConditionDepth) +
+			`{"op": "match", "kind": "contains", "texts": ["a"]}` + strings.Repeat(`]}`, MaxConditionDepth), errMsg: "max depth"},
+		{name: "too many matches", conditions: `{"op": "or", "children": [` +
+			strings.Repeat(`{"op": "match", "kind": "contains", "texts": ["a"]},`, MaxConditionLeaves) +
+			`{"op": "match", "kind": "contains", "texts": ["a"]}]}`, errMsg: "max of 20 matches"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &db.Rule{Name: "Composite", ProjectID: projectID, Rule: "composite", Texts: `["ignored"]`, Conditions: tt.conditions}
+			err := v.Validate(r)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				if r.Texts != "[]" || !strings.Contains(r.Conditions, `"op":"and"`) || !strings.Contains(r.Conditions, `["pdf"]`) {
+					t.Errorf("Validate() did not normalise rule: texts %s, conditions %s", r.Texts, r.Conditions)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want to contain %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
 func TestValidationError(t *testing.T) {
 	// Test error without value
 	err1 := ValidationError{
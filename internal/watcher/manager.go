@@ -0,0 +1,130 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kalycs/db"
+	"kalycs/internal/classifier"
+	"kalycs/internal/logging"
+	"sync"
+	"time"
+)
+
+// Manager runs one Watcher per enabled watch root and starts or stops them
+// as roots are added, changed or removed.
+type Manager struct {
+	mu         sync.Mutex
+	ctx        context.Context
+	classifier *classifier.Classifier
+	watchers   map[string]*Watcher
+	roots      map[string]db.WatchRoot
+}
+
+func NewManager(ctx context.Context, c *classifier.Classifier) *Manager {
+	return &Manager{
+		ctx:        ctx,
+		classifier: c,
+		watchers:   make(map[string]*Watcher),
+		roots:      make(map[string]db.WatchRoot),
+	}
+}
+
+// Sync makes the running watchers match roots. Unchanged roots keep their
+// watcher; disabled, removed or changed roots are stopped, and new or changed
+// enabled roots are started. A root that fails to start does not prevent the
+// others from being watched; all failures are returned together.
+func (m *Manager) Sync(roots []db.WatchRoot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wanted := make(map[string]db.WatchRoot, len(roots))
+	for _, r := range roots {
+		if r.Enabled {
+			wanted[r.ID] = r
+		}
+	}
+
+	for id, w := range m.watchers {
+		r, ok := wanted[id]
+		if ok && sameRootSettings(r, m.roots[id]) {
+			continue
+		}
+		logging.L().Infow("Stopping watcher for root", "watch_root_id", id, "path", m.roots[id].Path)
+		w.Stop()
+		delete(m.watchers, id)
+		delete(m.roots, id)
+	}
+
+	var errs []error
+	for id, r := range wanted {
+		if _, running := m.watchers[id]; running {
+			continue
+		}
+		opts, err := optionsFromRoot(r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("watch root %s: %w", r.Path, err))
+			continue
+		}
+		w, err := NewWatcherWithOptions(m.ctx, r.Path, m.classifier, opts)
+		if err != nil {
+			logging.L().Errorw("Failed to start watcher for root", "watch_root_id", id, "path", r.Path, "error", err)
+			errs = append(errs, fmt.Errorf("watch root %s: %w", r.Path, err))
+			continue
+		}
+		w.Start()
+		m.watchers[id] = w
+		m.roots[id] = r
+		logging.L().Infow("Watcher started for root", "watch_root_id", id, "path", r.Path)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Running returns the IDs of roots that currently have an active watcher
+func (m *Manager) Running() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.watchers))
+	for id := range m.watchers {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// StopAll stops every running watcher
+func (m *Manager) StopAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, w := range m.watchers {
+		w.Stop()
+		delete(m.watchers, id)
+		delete(m.roots, id)
+	}
+}
+
+// sameRootSettings reports whether a running watcher for b can serve a
+func sameRootSettings(a, b db.WatchRoot) bool {
+	return a.Path == b.Path &&
+		a.Recursive == b.Recursive &&
+		a.MaxDepth == b.MaxDepth &&
+		a.QuietPeriodMs == b.QuietPeriodMs &&
+		a.IgnorePatterns == b.IgnorePatterns
+}
+
+func optionsFromRoot(r db.WatchRoot) (Options, error) {
+	opts := Options{
+		Recursive:   r.Recursive,
+		MaxDepth:    r.MaxDepth,
+		QuietPeriod: time.Duration(r.QuietPeriodMs) * time.Millisecond,
+	}
+	if r.IgnorePatterns != "" {
+		if err := json.Unmarshal([]byte(r.IgnorePatterns), &opts.IgnorePatterns); err != nil {
+			return Options{}, fmt.Errorf("invalid ignore patterns: %w", err)
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,150 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"kalycs/db"
+	"kalycs/internal/classifier"
+	"kalycs/internal/logging"
+	"os"
+	"path/filepath"
+)
+
+// reconcileProgressEvery is how many scanned files pass between progress reports
+const reconcileProgressEvery = 100
+
+// ReconcileProgress reports how far a reconciliation scan has got
+type ReconcileProgress struct {
+	Root       string `json:"root"`
+	RootIndex  int    `json:"root_index"` // 1-based index of the root being scanned
+	RootCount  int    `json:"root_count"`
+	Scanned    int    `json:"scanned"`    // files seen in the current root
+	Classified int    `json:"classified"` // new or changed files classified in the current root
+	Missing    int    `json:"missing"`    // tracked files no longer on disk in the current root
+	Done       bool   `json:"done"`       // set on the final report once every root is scanned
+}
+
+// Reconcile brings the files table up to date with the enabled roots after
+// Kalycs was not running. Each root is walked with the same recursion, depth
+// and ignore settings a watcher would use: untracked files are classified,
+// tracked files whose size or mtime changed are classified again, and tracked
+// files that no longer exist are marked missing. progress may be nil.
+// A root that cannot be scanned does not stop the others; all failures are
+// returned together.
+func Reconcile(ctx context.Context, c *classifier.Classifier, roots []db.WatchRoot, progress func(ReconcileProgress)) error {
+	report := func(p ReconcileProgress) {
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	var enabled []db.WatchRoot
+	for _, r := range roots {
+		if r.Enabled {
+			enabled = append(enabled, r)
+		}
+	}
+
+	var errs []error
+	for i, r := range enabled {
+		p := ReconcileProgress{Root: r.Path, RootIndex: i + 1, RootCount: len(enabled)}
+		if err := reconcileRoot(ctx, c, r, &p, report); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logging.L().Errorw("Failed to reconcile watch root", "path", r.Path, "error", err)
+			errs = append(errs, fmt.Errorf("watch root %s: %w", r.Path, err))
+		}
+		report(p)
+	}
+
+	report(ReconcileProgress{RootIndex: len(enabled), RootCount: len(enabled), Done: true})
+	return errors.Join(errs...)
+}
+
+func reconcileRoot(ctx context.Context, c *classifier.Classifier, r db.WatchRoot, p *ReconcileProgress, report func(ReconcileProgress)) error {
+	opts, err := optionsFromRoot(r)
+	if err != nil {
+		return err
+	}
+	root := filepath.Clean(r.Path)
+	ctx = classifier.WithRoot(ctx, root)
+
+	files, err := c.TrackedUnder(ctx, root)
+	if err != nil {
+		return err
+	}
+	tracked := make(map[string]db.File, len(files))
+	for _, f := range files {
+		tracked[f.Path] = f
+	}
+
+	logging.L().Infow("Reconciling watch root", "path", root, "tracked", len(tracked))
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if path == root {
+				return err
+			}
+			logging.L().Warnw("failed to access path during reconciliation", "path", path, "error", err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && (!opts.Recursive || matchesIgnore(root, opts.IgnorePatterns, path) || beyondDepth(root, opts.MaxDepth, path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || matchesIgnore(root, opts.IgnorePatterns, path) || IsTempFile(path) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		p.Scanned++
+		if p.Scanned%reconcileProgressEvery == 0 {
+			report(*p)
+		}
+
+		f, known := tracked[path]
+		delete(tracked, path)
+		if known && f.Size == info.Size() && f.Mtime.Equal(info.ModTime()) {
+			return nil
+		}
+		logging.L().Infow("classifying file found during reconciliation", "path", path, "changed", known)
+		if err := c.Classify(ctx, path, info); err != nil {
+			logging.L().Errorw("failed to classify file during reconciliation", "path", path, "error", err)
+			return nil
+		}
+		p.Classified++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Tracked files the walk did not reach are missing unless they sit in a
+	// part of the tree the root's settings exclude.
+	for path, f := range tracked {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			continue
+		}
+		if err := c.MarkMissing(ctx, f.ID); err != nil {
+			logging.L().Errorw("failed to mark file missing", "file_id", f.ID, "path", path, "error", err)
+			continue
+		}
+		p.Missing++
+	}
+
+	logging.L().Infow("Watch root reconciled", "path", root, "scanned", p.Scanned, "classified", p.Classified, "missing", p.Missing)
+	return nil
+}
@@ -0,0 +1,98 @@
+package watcher
+
+import (
+	"io/fs"
+	"kalycs/internal/logging"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// addTree subscribes dir and, within the depth limit, every directory below it
+// that is not excluded by an ignore pattern.
+func (w *Watcher) addTree(dir string) {
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logging.L().Warnw("failed to access directory while adding watches", "path", p, "error", err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != w.root && (w.ignored(p) || w.tooDeep(p)) {
+			return filepath.SkipDir
+		}
+		if _, ok := w.dirs[p]; ok {
+			return nil
+		}
+		if err := w.watcher.Add(p); err != nil {
+			logging.L().Warnw("failed to watch directory", "path", p, "error", err)
+			return filepath.SkipDir
+		}
+		w.dirs[p] = struct{}{}
+		logging.L().Debugw("watching directory", "path", p)
+		return nil
+	})
+	if err != nil {
+		logging.L().Errorw("failed to walk directory tree", "path", dir, "error", err)
+	}
+}
+
+// observeTree queues the files already present in a subscribed directory tree
+func (w *Watcher) observeTree(dir string) {
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if _, ok := w.dirs[p]; !ok {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || w.ignored(p) || IsTempFile(p) {
+			return nil
+		}
+		w.stable.Observe(p)
+		return nil
+	})
+	if err != nil {
+		logging.L().Errorw("failed to walk new directory", "path", dir, "error", err)
+	}
+}
+
+// forgetTree drops the subscriptions for dir and everything below it after it
+// was removed or renamed away.
+func (w *Watcher) forgetTree(dir string) {
+	if dir == w.root {
+		return
+	}
+	prefix := dir + string(os.PathSeparator)
+	for d := range w.dirs {
+		if d == dir || strings.HasPrefix(d, prefix) {
+			// fsnotify drops watches on deleted directories itself, so errors are expected
+			_ = w.watcher.Remove(d)
+			delete(w.dirs, d)
+			logging.L().Debugw("stopped watching directory", "path", d)
+		}
+	}
+}
+
+// tooDeep reports whether dir lies beyond the configured depth limit
+func (w *Watcher) tooDeep(dir string) bool {
+	return beyondDepth(w.root, w.opts.MaxDepth, dir)
+}
+
+func beyondDepth(root string, maxDepth int, dir string) bool {
+	if maxDepth <= 0 {
+		return false
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return false
+	}
+	return len(strings.Split(filepath.ToSlash(rel), "/")) > maxDepth
+}
@@ -0,0 +1,118 @@
+package watcher
+
+import (
+	"kalycs/db"
+	"kalycs/internal/logging"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// renameWindow is how long, beyond the quiet period, a renamed-away file waits
+// for its new path to appear before it is marked missing
+const renameWindow = 2 * time.Second
+
+// vanishedFile is a tracked file whose path was renamed away and whose new
+// path has not been seen yet
+type vanishedFile struct {
+	file  db.File
+	timer *time.Timer
+}
+
+// removed marks the files tracked at or beneath p missing
+func (w *Watcher) removed(p string) {
+	files, err := w.classifier.TrackedUnder(w.ctx, p)
+	if err != nil {
+		logging.L().Errorw("failed to look up removed files", "path", p, "error", err)
+		return
+	}
+	for _, f := range files {
+		if err := w.classifier.MarkMissing(w.ctx, f.ID); err != nil {
+			logging.L().Errorw("failed to mark file missing", "file_id", f.ID, "path", f.Path, "error", err)
+		}
+	}
+}
+
+// renamedAway holds the files tracked at or beneath p until their new path is
+// classified. Files that do not reappear within the window are marked missing.
+func (w *Watcher) renamedAway(p string) {
+	files, err := w.classifier.TrackedUnder(w.ctx, p)
+	if err != nil {
+		logging.L().Errorw("failed to look up renamed files", "path", p, "error", err)
+		return
+	}
+
+	w.vanishedMu.Lock()
+	defer w.vanishedMu.Unlock()
+	for _, f := range files {
+		if _, ok := w.vanished[f.ID]; ok {
+			continue
+		}
+		id := f.ID
+		w.vanished[id] = &vanishedFile{
+			file:  f,
+			timer: time.AfterFunc(w.stable.quiet+renameWindow, func() { w.expireRename(id) }),
+		}
+	}
+}
+
+// expireRename marks a renamed-away file missing once its window has passed.
+// A row that meanwhile follows the file elsewhere, as after the classifier
+// moved it to a directory that is not watched, is left alone.
+func (w *Watcher) expireRename(fileID string) {
+	w.vanishedMu.Lock()
+	v, ok := w.vanished[fileID]
+	delete(w.vanished, fileID)
+	w.vanishedMu.Unlock()
+
+	if !ok || w.ctx.Err() != nil {
+		return
+	}
+	current, err := w.classifier.TrackedFile(w.ctx, fileID)
+	if err != nil {
+		logging.L().Errorw("failed to look up renamed file", "file_id", fileID, "error", err)
+		return
+	}
+	if current == nil || current.Path != v.file.Path {
+		logging.L().Infow("renamed file is tracked elsewhere, not marking missing", "file_id", fileID, "path", v.file.Path)
+		return
+	}
+	logging.L().Infow("renamed file did not reappear, marking missing", "file_id", fileID, "path", v.file.Path)
+	if err := w.classifier.MarkMissing(w.ctx, fileID); err != nil {
+		logging.L().Errorw("failed to mark file missing", "file_id", fileID, "path", v.file.Path, "error", err)
+	}
+}
+
+// claimRename returns the renamed-away file that p is the new path of. A rename
+// keeps size and mtime; when several files match, one with the same name
+// (a moved rather than renamed file) is preferred.
+func (w *Watcher) claimRename(p string, info os.FileInfo) (db.File, bool) {
+	w.vanishedMu.Lock()
+	defer w.vanishedMu.Unlock()
+
+	var match *vanishedFile
+	for _, v := range w.vanished {
+		if v.file.Size != info.Size() || v.file.Mtime.Unix() != info.ModTime().Unix() {
+			continue
+		}
+		if match == nil || (filepath.Base(v.file.Path) == filepath.Base(p) && filepath.Base(match.file.Path) != filepath.Base(p)) {
+			match = v
+		}
+	}
+	if match == nil {
+		return db.File{}, false
+	}
+	match.timer.Stop()
+	delete(w.vanished, match.file.ID)
+	return match.file, true
+}
+
+// stopRenames drops pending renames without marking them missing
+func (w *Watcher) stopRenames() {
+	w.vanishedMu.Lock()
+	defer w.vanishedMu.Unlock()
+	for id, v := range w.vanished {
+		v.timer.Stop()
+		delete(w.vanished, id)
+	}
+}
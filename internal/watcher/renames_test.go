@@ -0,0 +1,58 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kalycs/internal/classifier"
+	"kalycs/internal/store"
+	"kalycs/internal/testutils"
+)
+
+func TestExpireRename_SkipsRelocatedRow(t *testing.T) {
+	testutils.PrepareTestEnv(t)
+	s := store.NewStore(testutils.SetupTestDB(t))
+	c := classifier.NewClassifier(s)
+	ctx := context.Background()
+	if err := c.LoadIncomingProject(ctx); err != nil {
+		t.Fatalf("failed to load incoming project: %v", err)
+	}
+
+	root := t.TempDir()
+	w, err := NewWatcherWithOptions(ctx, root, c, Options{QuietPeriod: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	oldPath := filepath.Join(root, "invoice.pdf")
+	if err := os.WriteFile(oldPath, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(oldPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Classify(ctx, oldPath, info); err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	f, err := s.File.GetByPath(ctx, oldPath)
+	if err != nil || f == nil {
+		t.Fatalf("file was not tracked: %v", err)
+	}
+
+	// The classifier moves the file out of the watched root: the rename away
+	// is seen, but its new path never is
+	w.renamedAway(oldPath)
+	if err := c.Relocate(ctx, f.ID, filepath.Join(t.TempDir(), "invoice.pdf")); err != nil {
+		t.Fatalf("Relocate() error = %v", err)
+	}
+	w.expireRename(f.ID)
+
+	if missing, _ := s.File.ListMissing(ctx); len(missing) != 0 {
+		t.Errorf("relocated file was marked missing: %+v", missing)
+	}
+}
@@ -0,0 +1,163 @@
+package watcher
+
+import (
+	"context"
+	"kalycs/internal/fileops"
+	"kalycs/internal/logging"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultQuietPeriod is how long a file's size and mtime must stay unchanged before it is classified
+	DefaultQuietPeriod = 500 * time.Millisecond
+	// DefaultMaxWait is how long an empty file is given to receive data before it is classified anyway
+	DefaultMaxWait = 30 * time.Second
+)
+
+// tempSuffixes are the extensions browsers and download managers use while a download is in progress
+var tempSuffixes = []string{
+	".crdownload", // Chrome, Edge, Brave
+	".part",       // Firefox, wget
+	".partial",    // Internet Explorer
+	".download",   // Safari
+	".opdownload", // Opera
+}
+
+// IsTempFile reports whether name is an in-progress download or an in-progress
+// copy made by Kalycs itself. Such files are renamed when complete and are
+// classified under their final name.
+func IsTempFile(name string) bool {
+	base := filepath.Base(name)
+	if strings.HasPrefix(base, fileops.TempPrefix) {
+		return true
+	}
+	lower := strings.ToLower(base)
+	for _, suffix := range tempSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// stabilizer sits between fsnotify and the classifier. It coalesces bursts of
+// events for the same path and only hands a file on once its size and mtime
+// have stopped changing for the quiet period.
+type stabilizer struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	quiet   time.Duration
+	maxWait time.Duration
+	pending map[string]*pendingFile
+	ready   func(path string, info os.FileInfo)
+}
+
+// pendingFile is the last observed state of a file waiting to settle
+type pendingFile struct {
+	timer *time.Timer
+	size  int64
+	mtime time.Time
+	since time.Time
+}
+
+func newStabilizer(ctx context.Context, quiet, maxWait time.Duration, ready func(string, os.FileInfo)) *stabilizer {
+	if quiet <= 0 {
+		quiet = DefaultQuietPeriod
+	}
+	if maxWait <= 0 {
+		maxWait = DefaultMaxWait
+	}
+	return &stabilizer{
+		ctx:     ctx,
+		quiet:   quiet,
+		maxWait: maxWait,
+		pending: make(map[string]*pendingFile),
+		ready:   ready,
+	}
+}
+
+// Observe records activity on path and restarts its quiet period
+func (s *stabilizer) Observe(path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pending[path]
+	if !ok {
+		p = &pendingFile{since: time.Now()}
+		p.timer = time.AfterFunc(s.quiet, func() { s.check(path) })
+		s.pending[path] = p
+	} else {
+		p.timer.Reset(s.quiet)
+	}
+	p.size = info.Size()
+	p.mtime = info.ModTime()
+}
+
+// Cancel forgets a pending path, e.g. after it was removed
+func (s *stabilizer) Cancel(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.pending[path]; ok {
+		p.timer.Stop()
+		delete(s.pending, path)
+	}
+}
+
+// Stop cancels every pending path
+func (s *stabilizer) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for path, p := range s.pending {
+		p.timer.Stop()
+		delete(s.pending, path)
+	}
+}
+
+// check runs when a path's quiet period ends. The file is handed on if it is
+// unchanged since the last observation and has content (or has waited long
+// enough); otherwise the new state is recorded and the quiet period restarts.
+func (s *stabilizer) check(path string) {
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	info, statErr := os.Stat(path)
+
+	s.mu.Lock()
+	p, ok := s.pending[path]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	if statErr != nil || info.IsDir() {
+		delete(s.pending, path)
+		s.mu.Unlock()
+		return
+	}
+
+	settled := info.Size() == p.size && info.ModTime().Equal(p.mtime)
+	waitedOut := time.Since(p.since) >= s.maxWait
+	if !settled || (info.Size() == 0 && !waitedOut) {
+		p.size = info.Size()
+		p.mtime = info.ModTime()
+		p.timer.Reset(s.quiet)
+		s.mu.Unlock()
+		return
+	}
+	delete(s.pending, path)
+	s.mu.Unlock()
+
+	logging.L().Debugw("file settled", "path", path, "size", info.Size(), "waited", time.Since(p.since))
+	s.ready(path, info)
+}
@@ -5,19 +5,51 @@ import (
 	"kalycs/internal/classifier"
 	"kalycs/internal/logging"
 	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
+// Options configures how a watched directory is handled
+type Options struct {
+	// IgnorePatterns are glob patterns matched against the name and the root-relative
+	// path of files and directories; matching files are skipped and, in recursive
+	// mode, matching directories are not descended into
+	IgnorePatterns []string
+	// Recursive also watches subdirectories, including ones created while running
+	Recursive bool
+	// MaxDepth limits how many directory levels below the root are watched in
+	// recursive mode; 0 means unlimited
+	MaxDepth int
+	// QuietPeriod is how long a file must stay unchanged before it is classified;
+	// 0 uses DefaultQuietPeriod
+	QuietPeriod time.Duration
+	// MaxWait bounds how long an empty file is waited on; 0 uses DefaultMaxWait
+	MaxWait time.Duration
+}
+
 type Watcher struct {
 	watcher    *fsnotify.Watcher
 	ctx        context.Context
 	cancel     context.CancelFunc
 	classifier *classifier.Classifier
+	root       string
+	opts       Options
+	dirs       map[string]struct{} // directories currently subscribed
+	stable     *stabilizer
+	vanishedMu sync.Mutex
+	vanished   map[string]*vanishedFile // renamed-away files by file ID
 }
 
 func NewWatcher(ctx_main context.Context, watchPath string, c *classifier.Classifier) (*Watcher, error) {
-	ctx, cancel := context.WithCancel(ctx_main)
+	return NewWatcherWithOptions(ctx_main, watchPath, c, Options{})
+}
+
+func NewWatcherWithOptions(ctx_main context.Context, watchPath string, c *classifier.Classifier, opts Options) (*Watcher, error) {
+	ctx, cancel := context.WithCancel(classifier.WithRoot(ctx_main, watchPath))
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		cancel()
@@ -30,12 +62,21 @@ func NewWatcher(ctx_main context.Context, watchPath string, c *classifier.Classi
 		return nil, err
 	}
 
-	return &Watcher{
+	w := &Watcher{
 		watcher:    watcher,
 		ctx:        ctx,
 		cancel:     cancel,
 		classifier: c,
-	}, nil
+		root:       filepath.Clean(watchPath),
+		opts:       opts,
+		dirs:       map[string]struct{}{filepath.Clean(watchPath): {}},
+		vanished:   make(map[string]*vanishedFile),
+	}
+	w.stable = newStabilizer(ctx, opts.QuietPeriod, opts.MaxWait, w.classify)
+	if opts.Recursive {
+		w.addTree(w.root)
+	}
+	return w, nil
 }
 
 func (w *Watcher) Start() {
@@ -53,7 +94,26 @@ func (w *Watcher) Start() {
 				}
 				logging.L().Infow("fsnotify event", "event", event, "name", event.Name, "op", event.Op)
 
-				if event.Op&fsnotify.Create == fsnotify.Create || event.Op&fsnotify.Rename == fsnotify.Rename {
+				if w.ignored(event.Name) {
+					logging.L().Debugw("ignoring file matching ignore pattern", "path", event.Name)
+					continue
+				}
+				if IsTempFile(event.Name) {
+					logging.L().Debugw("ignoring in-progress download", "path", event.Name)
+					continue
+				}
+
+				if event.Op&fsnotify.Remove == fsnotify.Remove || event.Op&fsnotify.Rename == fsnotify.Rename {
+					w.forgetTree(event.Name)
+					w.stable.Cancel(event.Name)
+					if event.Op&fsnotify.Remove == fsnotify.Remove {
+						w.removed(event.Name)
+					} else if _, err := os.Lstat(event.Name); os.IsNotExist(err) {
+						w.renamedAway(event.Name)
+					}
+				}
+
+				if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) != 0 {
 					info, err := os.Stat(event.Name)
 					if err != nil {
 						if !os.IsNotExist(err) {
@@ -61,11 +121,14 @@ func (w *Watcher) Start() {
 						}
 						continue
 					}
+					if info.IsDir() && w.opts.Recursive && event.Op&fsnotify.Create == fsnotify.Create {
+						// Files may land in the new directory before it is subscribed,
+						// so pick up whatever is already there.
+						w.addTree(event.Name)
+						w.observeTree(event.Name)
+					}
 					if !info.IsDir() {
-						logging.L().Infow("classifying new file", "path", event.Name)
-						if err := w.classifier.Classify(w.ctx, event.Name, info); err != nil {
-							logging.L().Errorw("failed to classify file", "file", event.Name, "error", err)
-						}
+						w.stable.Observe(event.Name)
 					}
 				}
 			case err, ok := <-w.watcher.Errors:
@@ -76,12 +139,53 @@ func (w *Watcher) Start() {
 				logging.L().Errorw("fsnotify error", "error", err)
 			case <-w.ctx.Done():
 				logging.L().Info("Watcher context done")
+				w.stable.Stop()
+				w.stopRenames()
 				return
 			}
 		}
 	}()
 }
 
+// classify hands a settled file to the classifier. A file that is the new
+// path of a renamed-away file takes over its row first.
+func (w *Watcher) classify(path string, info os.FileInfo) {
+	if f, ok := w.claimRename(path, info); ok {
+		logging.L().Infow("file renamed", "file_id", f.ID, "old_path", f.Path, "new_path", path)
+		if err := w.classifier.Relocate(w.ctx, f.ID, path); err != nil {
+			logging.L().Errorw("failed to update path of renamed file", "file_id", f.ID, "path", path, "error", err)
+		}
+	}
+	logging.L().Infow("classifying new file", "path", path)
+	if err := w.classifier.Classify(w.ctx, path, info); err != nil {
+		logging.L().Errorw("failed to classify file", "file", path, "error", err)
+	}
+}
+
+// ignored reports whether the name or root-relative path matches one of the ignore patterns
+func (w *Watcher) ignored(p string) bool {
+	return matchesIgnore(w.root, w.opts.IgnorePatterns, p)
+}
+
+func matchesIgnore(root string, patterns []string, p string) bool {
+	name := filepath.Base(p)
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		rel = name
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (w *Watcher) Stop() {
 	logging.L().Info("Stopping watcher")
 	w.cancel()
@@ -374,3 +374,500 @@ func TestWatcher_DirectoryCreationIsIgnored(t *testing.T) {
 success:
 	// If we reach here, it means the timeout occurred without the file ever appearing, which is correct.
 }
+
+func TestWatcher_FileMovedToProjectDestination(t *testing.T) {
+	// 1. Setup
+	ctx := context.Background()
+	c, s := setupTestClassifier(t)
+
+	destDir := t.TempDir()
+	project := &db.Project{Name: "Invoices", IsActive: true, Action: "move", DestinationDir: destDir}
+	if err := s.Project.Create(ctx, project); err != nil {
+		t.Fatalf("failed to create test project: %v", err)
+	}
+	ruleTexts, _ := json.Marshal([]string{"invoice"})
+	rule := &db.Rule{Name: "Invoices", ProjectID: project.ID, Rule: "starts_with", Texts: string(ruleTexts)}
+	if err := s.Rule.Create(ctx, rule); err != nil {
+		t.Fatalf("failed to create test rule: %v", err)
+	}
+	if err := c.Reload(ctx); err != nil {
+		t.Fatalf("failed to reload classifier: %v", err)
+	}
+
+	watchDir := t.TempDir()
+
+	// 2. Start watcher
+	w, err := watcher.NewWatcher(ctx, watchDir, c)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	w.Start()
+	defer w.Stop()
+	time.Sleep(20 * time.Millisecond) // give watcher time to start
+
+	// 3. Create a file that matches the rule
+	if err := os.WriteFile(filepath.Join(watchDir, "invoice-42.pdf"), []byte("total"), 0600); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	// 4. Assert the file was moved and tracked at its new location
+	newPath := filepath.Join(destDir, "invoice-42.pdf")
+	var file *db.File
+	var getErr error
+	timeout := time.After(2 * time.Second)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-timeout:
+			t.Fatalf("timed out waiting for file to be moved. last error: %v", getErr)
+		case <-ticker.C:
+			file, getErr = s.File.GetByPath(ctx, newPath)
+			if getErr == nil && file != nil {
+				goto found
+			}
+		}
+	}
+
+found:
+	if file.ProjectID.String != project.ID {
+		t.Errorf("moved file has ProjectID %v, want %s", file.ProjectID, project.ID)
+	}
+	if _, err := os.Stat(filepath.Join(watchDir, "invoice-42.pdf")); !os.IsNotExist(err) {
+		t.Errorf("original file should have been moved, stat error = %v", err)
+	}
+}
+
+// waitForFile polls the store until path is tracked or the timeout expires
+func waitForFile(t *testing.T, s *store.Store, path string, timeout time.Duration) *db.File {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		file, err := s.File.GetByPath(context.Background(), path)
+		if err == nil && file != nil {
+			return file
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return nil
+}
+
+func TestManager_SyncStartsAndStopsRoots(t *testing.T) {
+	ctx := context.Background()
+	c, s := setupTestClassifier(t)
+
+	dirA := t.TempDir()
+	dirB := t.TempDir()
+	rootA := &db.WatchRoot{Path: dirA, Enabled: true}
+	rootB := &db.WatchRoot{Path: dirB, Enabled: true}
+	for _, r := range []*db.WatchRoot{rootA, rootB} {
+		if err := s.Watch.Create(ctx, r); err != nil {
+			t.Fatalf("failed to create watch root: %v", err)
+		}
+	}
+
+	m := watcher.NewManager(ctx, c)
+	defer m.StopAll()
+
+	roots, _ := s.Watch.GetAll(ctx)
+	if err := m.Sync(roots); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if got := len(m.Running()); got != 2 {
+		t.Fatalf("Running() = %d watchers, want 2", got)
+	}
+	time.Sleep(20 * time.Millisecond) // give watchers time to start
+
+	for _, dir := range []string{dirA, dirB} {
+		path := filepath.Join(dir, "scan.pdf")
+		if err := os.WriteFile(path, []byte("scan"), 0600); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+		if waitForFile(t, s, path, 2*time.Second) == nil {
+			t.Errorf("file in %s was not classified", dir)
+		}
+	}
+
+	// Disable root B; only root A should keep running
+	rootB.Enabled = false
+	if err := s.Watch.Update(ctx, rootB); err != nil {
+		t.Fatalf("failed to update watch root: %v", err)
+	}
+	roots, _ = s.Watch.GetAll(ctx)
+	if err := m.Sync(roots); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	running := m.Running()
+	if len(running) != 1 || running[0] != rootA.ID {
+		t.Fatalf("Running() = %v, want only %s", running, rootA.ID)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	path := filepath.Join(dirB, "later.pdf")
+	if err := os.WriteFile(path, []byte("later"), 0600); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	if waitForFile(t, s, path, 300*time.Millisecond) != nil {
+		t.Error("file in disabled root was classified")
+	}
+}
+
+func TestWatcher_IgnorePatterns(t *testing.T) {
+	ctx := context.Background()
+	c, s := setupTestClassifier(t)
+	tempDir := t.TempDir()
+
+	w, err := watcher.NewWatcherWithOptions(ctx, tempDir, c, watcher.Options{IgnorePatterns: []string{"*.ini", "~$*"}})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	w.Start()
+	defer w.Stop()
+	time.Sleep(20 * time.Millisecond) // give watcher time to start
+
+	ignored := filepath.Join(tempDir, "desktop.ini")
+	kept := filepath.Join(tempDir, "notes.txt")
+	for _, p := range []string{ignored, kept} {
+		if err := os.WriteFile(p, []byte("x"), 0600); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+	}
+
+	if waitForFile(t, s, kept, 2*time.Second) == nil {
+		t.Fatal("non-ignored file was not classified")
+	}
+	if f, _ := s.File.GetByPath(ctx, ignored); f != nil {
+		t.Error("ignored file was classified")
+	}
+}
+
+func TestWatcher_Recursive(t *testing.T) {
+	ctx := context.Background()
+	c, s := setupTestClassifier(t)
+	root := t.TempDir()
+
+	existing := filepath.Join(root, "existing")
+	excluded := filepath.Join(root, "node_modules")
+	deep := filepath.Join(root, "a", "b")
+	for _, d := range []string{existing, excluded, deep} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", d, err)
+		}
+	}
+
+	opts := watcher.Options{Recursive: true, MaxDepth: 1, IgnorePatterns: []string{"node_modules"}}
+	w, err := watcher.NewWatcherWithOptions(ctx, root, c, opts)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	w.Start()
+	defer w.Stop()
+	time.Sleep(20 * time.Millisecond) // give watcher time to start
+
+	// A file in a subdirectory that existed at start
+	inExisting := filepath.Join(existing, "report.pdf")
+	if err := os.WriteFile(inExisting, []byte("x"), 0600); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	if waitForFile(t, s, inExisting, 2*time.Second) == nil {
+		t.Error("file in existing subdirectory was not classified")
+	}
+
+	// A directory created at runtime, e.g. an extracted archive
+	extracted := filepath.Join(root, "archive")
+	if err := os.Mkdir(extracted, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond) // let the new directory be subscribed
+	inExtracted := filepath.Join(extracted, "photo.jpg")
+	if err := os.WriteFile(inExtracted, []byte("x"), 0600); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	if waitForFile(t, s, inExtracted, 2*time.Second) == nil {
+		t.Error("file in new subdirectory was not classified")
+	}
+
+	// Excluded and too-deep directories are not watched
+	for _, p := range []string{filepath.Join(excluded, "lib.js"), filepath.Join(deep, "deep.txt")} {
+		if err := os.WriteFile(p, []byte("x"), 0600); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+	}
+	time.Sleep(300 * time.Millisecond)
+	for _, p := range []string{filepath.Join(excluded, "lib.js"), filepath.Join(deep, "deep.txt")} {
+		if f, _ := s.File.GetByPath(ctx, p); f != nil {
+			t.Errorf("file %s should not have been classified", p)
+		}
+	}
+}
+
+func TestIsTempFile(t *testing.T) {
+	tests := map[string]bool{
+		"report.pdf":                   false,
+		"report.pdf.crdownload":        true,
+		"archive.zip.part":             true,
+		"Setup.dmg.download":           true,
+		"movie.MKV.PART":               true,
+		".kalycs-123456":               true,
+		"partial.txt":                  false,
+		"download.zip":                 false,
+		"notes.partial.md":             false,
+		"Unconfirmed 12345.opdownload": true,
+	}
+	for name, want := range tests {
+		if got := watcher.IsTempFile(name); got != want {
+			t.Errorf("IsTempFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestWatcher_DownloadRenamedFromTempFile(t *testing.T) {
+	ctx := context.Background()
+	c, s := setupTestClassifier(t)
+	tempDir := t.TempDir()
+
+	w, err := watcher.NewWatcherWithOptions(ctx, tempDir, c, watcher.Options{QuietPeriod: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	w.Start()
+	defer w.Stop()
+	time.Sleep(20 * time.Millisecond) // give watcher time to start
+
+	// Simulate a browser download: chunks written to a temp file, then renamed
+	partial := filepath.Join(tempDir, "installer.zip.crdownload")
+	f, err := os.Create(partial)
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := f.Write([]byte("chunk")); err != nil {
+			t.Fatalf("failed to write chunk: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	f.Close()
+
+	final := filepath.Join(tempDir, "installer.zip")
+	if err := os.Rename(partial, final); err != nil {
+		t.Fatalf("failed to rename download: %v", err)
+	}
+
+	file := waitForFile(t, s, final, 2*time.Second)
+	if file == nil {
+		t.Fatal("completed download was not classified")
+	}
+	if file.Size != 25 {
+		t.Errorf("classified size = %d, want 25", file.Size)
+	}
+	if f, _ := s.File.GetByPath(ctx, partial); f != nil {
+		t.Error("temp download file was classified")
+	}
+}
+
+func TestWatcher_WaitsForWritesToSettle(t *testing.T) {
+	ctx := context.Background()
+	c, s := setupTestClassifier(t)
+	tempDir := t.TempDir()
+
+	quiet := 150 * time.Millisecond
+	w, err := watcher.NewWatcherWithOptions(ctx, tempDir, c, watcher.Options{QuietPeriod: quiet})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	w.Start()
+	defer w.Stop()
+	time.Sleep(20 * time.Millisecond) // give watcher time to start
+
+	// An empty file is not classified while it stays empty
+	path := filepath.Join(tempDir, "video.mp4")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	time.Sleep(2 * quiet)
+	if got, _ := s.File.GetByPath(ctx, path); got != nil {
+		t.Fatal("empty file was classified before receiving data")
+	}
+
+	// Writes arriving faster than the quiet period keep postponing classification
+	for i := 0; i < 6; i++ {
+		if _, err := f.Write([]byte("data")); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+		time.Sleep(quiet / 3)
+		if got, _ := s.File.GetByPath(ctx, path); got != nil {
+			t.Fatalf("file was classified while still being written (after %d writes)", i+1)
+		}
+	}
+	f.Close()
+
+	file := waitForFile(t, s, path, 2*time.Second)
+	if file == nil {
+		t.Fatal("file was not classified once writes stopped")
+	}
+	if file.Size != 24 {
+		t.Errorf("classified size = %d, want 24", file.Size)
+	}
+}
+
+func TestWatcher_RemoveMarksFileMissing(t *testing.T) {
+	ctx := context.Background()
+	c, s := setupTestClassifier(t)
+	tempDir := t.TempDir()
+
+	w, err := watcher.NewWatcherWithOptions(ctx, tempDir, c, watcher.Options{QuietPeriod: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	w.Start()
+	defer w.Stop()
+	time.Sleep(20 * time.Millisecond) // give watcher time to start
+
+	path := filepath.Join(tempDir, "old.zip")
+	if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	if waitForFile(t, s, path, 2*time.Second) == nil {
+		t.Fatal("file was not classified")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if missing, _ := s.File.ListMissing(ctx); len(missing) == 1 && missing[0].Path == path {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Error("removed file was not marked missing")
+}
+
+func TestWatcher_RenameKeepsFileRow(t *testing.T) {
+	ctx := context.Background()
+	c, s := setupTestClassifier(t)
+	tempDir := t.TempDir()
+
+	w, err := watcher.NewWatcherWithOptions(ctx, tempDir, c, watcher.Options{QuietPeriod: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	w.Start()
+	defer w.Stop()
+	time.Sleep(20 * time.Millisecond) // give watcher time to start
+
+	oldPath := filepath.Join(tempDir, "IMG_0001.jpg")
+	if err := os.WriteFile(oldPath, []byte("image"), 0600); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	original := waitForFile(t, s, oldPath, 2*time.Second)
+	if original == nil {
+		t.Fatal("file was not classified")
+	}
+
+	newPath := filepath.Join(tempDir, "holiday.jpg")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatalf("failed to rename file: %v", err)
+	}
+	renamed := waitForFile(t, s, newPath, 2*time.Second)
+	if renamed == nil {
+		t.Fatal("renamed file was not tracked at its new path")
+	}
+	if renamed.ID != original.ID {
+		t.Errorf("renamed file got a new row %s, want %s", renamed.ID, original.ID)
+	}
+	if f, _ := s.File.GetByPath(ctx, oldPath); f != nil {
+		t.Error("old path is still tracked after rename")
+	}
+	if missing, _ := s.File.ListMissing(ctx); len(missing) != 0 {
+		t.Errorf("renamed file was marked missing: %+v", missing)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	c, s := setupTestClassifier(t)
+	root := t.TempDir()
+
+	write := func(name, content string) (string, os.FileInfo) {
+		t.Helper()
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", name, err)
+		}
+		return p, info
+	}
+
+	// Files classified in an earlier session
+	unchanged, info := write("unchanged.txt", "same")
+	if err := c.Classify(ctx, unchanged, info); err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	changed, info := write("changed.txt", "old")
+	if err := c.Classify(ctx, changed, info); err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	vanished, info := write("vanished.txt", "gone")
+	if err := c.Classify(ctx, vanished, info); err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	before, _ := s.File.GetByPath(ctx, unchanged)
+
+	// Changes made while Kalycs was closed
+	write("changed.txt", "grown while away")
+	if err := os.Remove(vanished); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	downloaded, _ := write("downloaded.pdf", "new")
+	partial, _ := write("movie.mkv.part", "partial")
+	ignored, _ := write("Thumbs.db", "cache")
+	nested, _ := write(filepath.Join("sub", "nested.txt"), "nested")
+
+	roots := []db.WatchRoot{
+		{ID: "root", Path: root, Enabled: true, IgnorePatterns: `["Thumbs.db"]`},
+		{ID: "disabled", Path: t.TempDir(), Enabled: false},
+	}
+	var reports []watcher.ReconcileProgress
+	if err := watcher.Reconcile(ctx, c, roots, func(p watcher.ReconcileProgress) { reports = append(reports, p) }); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	if f, _ := s.File.GetByPath(ctx, downloaded); f == nil {
+		t.Error("file downloaded while closed was not classified")
+	}
+	if f, _ := s.File.GetByPath(ctx, changed); f == nil || f.Size != int64(len("grown while away")) {
+		t.Errorf("changed file was not re-checked: %+v", f)
+	}
+	if f, _ := s.File.GetByPath(ctx, unchanged); f == nil || !f.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Errorf("unchanged file was rewritten: %+v", f)
+	}
+	if f, _ := s.File.GetByPath(ctx, vanished); f == nil || !f.DeletedAt.Valid {
+		t.Errorf("vanished file was not marked missing: %+v", f)
+	}
+	for _, p := range []string{partial, ignored, nested} {
+		if f, _ := s.File.GetByPath(ctx, p); f != nil {
+			t.Errorf("file %s should not have been classified", p)
+		}
+	}
+
+	if len(reports) != 2 {
+		t.Fatalf("got %d progress reports, want 2: %+v", len(reports), reports)
+	}
+	want := watcher.ReconcileProgress{Root: root, RootIndex: 1, RootCount: 1, Scanned: 3, Classified: 2, Missing: 1}
+	if reports[0] != want {
+		t.Errorf("root report = %+v, want %+v", reports[0], want)
+	}
+	if !reports[1].Done {
+		t.Errorf("final report = %+v, want done", reports[1])
+	}
+}